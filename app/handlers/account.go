@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/mailer"
+	"knowledge-capsule/pkg/utils"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+	minPasswordLen       = 8
+)
+
+var (
+	Mailer     mailer.Mailer
+	appBaseURL string
+)
+
+// InitMailer sets the mailer and the base URL used to build links in emails.
+func InitMailer(m mailer.Mailer, baseURL string) {
+	Mailer = m
+	appBaseURL = strings.TrimRight(baseURL, "/")
+}
+
+// ForgotPasswordHandler godoc
+// @Summary Request password reset
+// @Description Email a single-use password reset link. Always succeeds so registered emails cannot be discovered. Requests are limited per address and per IP.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param input body object{email=string} true "Account email"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Router /api/auth/forgot-password [post]
+func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if !utils.ParseAndValidateBody(w, r, &req) {
+		return
+	}
+	if throttleMail(w, r, "forgot_password", req.Email) {
+		return
+	}
+
+	if user, err := UserStore.FindByEmail(strings.TrimSpace(req.Email)); err == nil {
+		token, err := TokenStore.CreateToken(user.ID, models.TokenPurposePasswordReset, passwordResetTTL)
+		if err != nil {
+			utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		body := fmt.Sprintf("Hi %s,\n\nUse the link below to reset your Knowledge Capsule password. It expires in %s and can be used once.\n\n%s\n\nIf you did not request this, you can ignore this email.\n",
+			user.Name, passwordResetTTL, appBaseURL+"/reset-password?token="+url.QueryEscape(token))
+		if err := Mailer.Send(user.Email, "Reset your password", body); err != nil {
+			logger.ErrorRequest(r, logger.EventMail, err, slog.String("action", "password_reset"), slog.String("user_id", user.ID))
+		}
+		logger.LogEvent(logger.EventAuth, r, slog.String("action", "forgot_password"), slog.String("user_id", user.ID))
+	}
+
+	utils.JSONResponse(w, http.StatusOK, true, "If the email is registered, a reset link has been sent", nil)
+}
+
+// ResetPasswordHandler godoc
+// @Summary Reset password
+// @Description Set a new password using a token from the reset email. All existing sessions and personal access tokens are revoked.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param input body object{token=string,password=string} true "Reset token and new password"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/auth/reset-password [post]
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if !utils.ParseAndValidateBody(w, r, &req) {
+		return
+	}
+	if len(req.Password) < minPasswordLen {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "password", Message: fmt.Sprintf("must be at least %d characters", minPasswordLen)})
+		return
+	}
+
+	userID, err := TokenStore.ConsumeToken(req.Token, models.TokenPurposePasswordReset)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	hash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if err := UserStore.UpdatePasswordHash(userID, hash); err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	// Receiving the reset email proves ownership of the address.
+	if err := UserStore.MarkEmailVerified(userID); err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	revoked, err := SessionStore.RevokeUserSessions(userID, "")
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	tokensRevoked, err := APITokenStore.DeleteUserTokens(userID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "reset_password"), slog.String("user_id", userID),
+		slog.Int("sessions_revoked", revoked), slog.Int("tokens_revoked", tokensRevoked))
+
+	utils.JSONResponse(w, http.StatusOK, true, "Password reset", nil)
+}
+
+// VerifyEmailHandler godoc
+// @Summary Verify email
+// @Description Verify an email address with the token from the verification email. Send only "email" to resend the verification email; resends are limited per address and per IP.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param input body object{token=string,email=string} true "Verification token, or email to resend"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Router /api/auth/verify-email [post]
+func VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req struct {
+		Token string `json:"token"`
+		Email string `json:"email"`
+	}
+	if r.Body == nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("empty request body"))
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Token == "" {
+		if strings.TrimSpace(req.Email) == "" {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "token", Message: "cannot be empty"})
+			return
+		}
+		if throttleMail(w, r, "resend_verification", req.Email) {
+			return
+		}
+		if user, err := UserStore.FindByEmail(strings.TrimSpace(req.Email)); err == nil && user.EmailVerifiedAt == nil {
+			sendVerificationEmail(r, user)
+		}
+		utils.JSONResponse(w, http.StatusOK, true, "If the email is registered and unverified, a verification link has been sent", nil)
+		return
+	}
+
+	userID, err := TokenStore.ConsumeToken(req.Token, models.TokenPurposeEmailVerification)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err := UserStore.MarkEmailVerified(userID); err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "verify_email"), slog.String("user_id", userID))
+	utils.JSONResponse(w, http.StatusOK, true, "Email verified", nil)
+}
+
+// sendVerificationEmail issues a verification token and emails it. Failures are logged only.
+func sendVerificationEmail(r *http.Request, user *models.User) {
+	token, err := TokenStore.CreateToken(user.ID, models.TokenPurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		logger.ErrorRequest(r, logger.EventAuth, err, slog.String("action", "create_verification_token"), slog.String("user_id", user.ID))
+		return
+	}
+	body := fmt.Sprintf("Hi %s,\n\nConfirm your email address for Knowledge Capsule by opening the link below. It expires in %s.\n\n%s\n",
+		user.Name, emailVerificationTTL, appBaseURL+"/verify-email?token="+url.QueryEscape(token))
+	if err := Mailer.Send(user.Email, "Verify your email", body); err != nil {
+		logger.ErrorRequest(r, logger.EventMail, err, slog.String("action", "email_verification"), slog.String("user_id", user.ID))
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -77,6 +78,78 @@ func SetUserRole(w http.ResponseWriter, r *http.Request) {
 	utils.JSONResponse(w, http.StatusOK, true, "Role updated", map[string]string{"user_id": id, "role": role})
 }
 
+// GetSecuritySettings godoc
+// @Summary Get security settings (superadmin only)
+// @Description Get global security policy, e.g. whether admins must use two-factor authentication
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} models.SecuritySettings
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/admin/security [get]
+func GetSecuritySettings(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	required, err := SettingsStore.GetBool(models.SettingRequireAdmin2FA)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Security settings fetched", models.SecuritySettings{RequireAdmin2FA: required})
+}
+
+// UpdateSecuritySettings godoc
+// @Summary Update security settings (superadmin only)
+// @Description Require two-factor authentication for all admin and superadmin accounts. Enabling it requires the caller to be signed in with 2FA.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param input body models.SecuritySettings true "Security settings"
+// @Success 200 {object} models.SecuritySettings
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/admin/security [put]
+func UpdateSecuritySettings(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPut) {
+		return
+	}
+	var req models.SecuritySettings
+	if r.Body == nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, nil)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	// Prevent locking yourself out of admin endpoints.
+	if mfa, _ := r.Context().Value(middleware.MFAContextKey).(bool); req.RequireAdmin2FA && !mfa {
+		utils.ErrorResponse(w, r, http.StatusForbidden, errors.New("sign in with two-factor authentication before requiring it for admins"))
+		return
+	}
+
+	if err := SettingsStore.Set(models.SettingRequireAdmin2FA, strconv.FormatBool(req.RequireAdmin2FA)); err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	logger.LogEvent(logger.EventAdmin, r, slog.String("action", "update_security_settings"), slog.Bool("require_admin_2fa", req.RequireAdmin2FA))
+	utils.JSONResponse(w, http.StatusOK, true, "Security settings updated", req)
+}
+
+// SecuritySettingsHandler routes GET/PUT /api/admin/security.
+func SecuritySettingsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		GetSecuritySettings(w, r)
+	case http.MethodPut:
+		UpdateSecuritySettings(w, r)
+	default:
+		utils.ErrorResponse(w, r, http.StatusMethodNotAllowed, nil)
+	}
+}
+
 // ListAdmins godoc
 // @Summary List admins (superadmin only)
 // @Description List users with role admin or superadmin
@@ -105,14 +178,14 @@ func ListAdmins(w http.ResponseWriter, r *http.Request) {
 
 // GlobalSearchResult is the response for admin global search.
 type GlobalSearchResult struct {
-	Users    []models.User    `json:"users"`
-	Topics   []models.Topic   `json:"topics"`
-	Capsules []models.Capsule `json:"capsules"`
+	Users    []models.User                `json:"users"`
+	Topics   []models.Topic               `json:"topics"`
+	Capsules []models.CapsuleSearchResult `json:"capsules"`
 }
 
 // GlobalSearch godoc
 // @Summary Global search (admin only)
-// @Description Search across users, topics, and capsules
+// @Description Search across users, topics, and capsules, tolerating typos in names and titles. Capsules are also matched with full-text search. Each list is ordered by relevance.
 // @Tags admin
 // @Accept  json
 // @Produce  json
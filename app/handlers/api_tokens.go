@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+const maxTokenNameLen = 100
+
+// CreatedAPIToken is returned once when a token is created; Token is never shown again.
+type CreatedAPIToken struct {
+	models.APIToken
+	Token string `json:"token"`
+}
+
+// ListAPITokens godoc
+// @Summary List my API tokens
+// @Description List the authenticated user's personal access tokens (token values are never returned)
+// @Tags tokens
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} models.APIToken
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/users/me/tokens [get]
+func ListAPITokens(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	tokens, err := APITokenStore.ListTokens(userID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Tokens fetched", tokens)
+}
+
+// CreateAPIToken godoc
+// @Summary Create API token
+// @Description Create a named, scoped personal access token. The token value is only returned in this response.
+// @Tags tokens
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param input body models.APITokenInput true "Token name, scopes (capsules:read, capsules:write, topics:read, topics:write, chat, admin) and optional expiry"
+// @Success 201 {object} CreatedAPIToken
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/users/me/tokens [post]
+func CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	req, ok := parseAPITokenInput(w, r)
+	if !ok {
+		return
+	}
+	if req.ExpiresInDays < 0 {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "expires_in_days", Message: "cannot be negative"})
+		return
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	token, raw, err := APITokenStore.CreateToken(userID, req.Name, req.Scopes, expiresAt)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "create_api_token"), slog.String("token_id", token.ID), slog.Any("scopes", []string(token.Scopes)))
+	utils.JSONResponse(w, http.StatusCreated, true, "Token created; copy it now, it will not be shown again", CreatedAPIToken{APIToken: *token, Token: raw})
+}
+
+// GetAPIToken godoc
+// @Summary Get API token
+// @Description Get one of the authenticated user's personal access tokens
+// @Tags tokens
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Token ID"
+// @Success 200 {object} models.APIToken
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/users/me/tokens/{id} [get]
+func GetAPIToken(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	id := strings.TrimPrefix(r.URL.Path, "/api/users/me/tokens/")
+	token, err := APITokenStore.FindByID(id, userID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Token fetched", token)
+}
+
+// UpdateAPIToken godoc
+// @Summary Update API token
+// @Description Rename a personal access token and replace its scopes
+// @Tags tokens
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Token ID"
+// @Param input body models.APITokenInput true "New name and scopes (expiry cannot be changed)"
+// @Success 200 {object} models.APIToken
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/users/me/tokens/{id} [patch]
+func UpdateAPIToken(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPatch) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	id := strings.TrimPrefix(r.URL.Path, "/api/users/me/tokens/")
+	req, ok := parseAPITokenInput(w, r)
+	if !ok {
+		return
+	}
+
+	token, err := APITokenStore.UpdateToken(id, userID, req.Name, req.Scopes)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "update_api_token"), slog.String("token_id", id), slog.Any("scopes", []string(token.Scopes)))
+	utils.JSONResponse(w, http.StatusOK, true, "Token updated", token)
+}
+
+// DeleteAPIToken godoc
+// @Summary Revoke API token
+// @Description Delete a personal access token; it stops working immediately
+// @Tags tokens
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Token ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/users/me/tokens/{id} [delete]
+func DeleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodDelete) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	id := strings.TrimPrefix(r.URL.Path, "/api/users/me/tokens/")
+	if err := APITokenStore.DeleteToken(id, userID); err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "delete_api_token"), slog.String("token_id", id))
+	utils.JSONResponse(w, http.StatusOK, true, "Token revoked", nil)
+}
+
+// APITokensHandler routes /api/users/me/tokens (GET, POST) and /api/users/me/tokens/:id (GET, PATCH, DELETE).
+func APITokensHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/users/me/tokens")
+	path = strings.TrimPrefix(path, "/")
+
+	if path == "" {
+		switch r.Method {
+		case http.MethodGet:
+			ListAPITokens(w, r)
+		case http.MethodPost:
+			CreateAPIToken(w, r)
+		default:
+			utils.ErrorResponse(w, r, http.StatusMethodNotAllowed, nil)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		GetAPIToken(w, r)
+	case http.MethodPatch:
+		UpdateAPIToken(w, r)
+	case http.MethodDelete:
+		DeleteAPIToken(w, r)
+	default:
+		utils.ErrorResponse(w, r, http.StatusMethodNotAllowed, nil)
+	}
+}
+
+// parseAPITokenInput decodes and validates name and scopes. Only admins may grant the admin scope.
+func parseAPITokenInput(w http.ResponseWriter, r *http.Request) (models.APITokenInput, bool) {
+	var req models.APITokenInput
+	if r.Body == nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("empty request body"))
+		return req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return req, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "name", Message: "cannot be empty"})
+		return req, false
+	}
+	if len(req.Name) > maxTokenNameLen {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "name", Message: "exceeds maximum length"})
+		return req, false
+	}
+	if len(req.Scopes) == 0 {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "scopes", Message: "cannot be empty"})
+		return req, false
+	}
+
+	role, _ := r.Context().Value(middleware.RoleContextKey).(string)
+	seen := make(map[string]bool)
+	scopes := make(models.Scopes, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !isValidScope(scope) {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "scopes", Message: "unknown scope " + scope})
+			return req, false
+		}
+		if scope == models.ScopeAdmin && role != models.RoleAdmin && role != models.RoleSuperAdmin {
+			utils.ErrorResponse(w, r, http.StatusForbidden, errors.New("admin scope requires an admin account"))
+			return req, false
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	req.Scopes = scopes
+	return req, true
+}
+
+func isValidScope(scope string) bool {
+	for _, s := range models.ValidScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -6,19 +6,42 @@ import (
 	"net/http"
 	"time"
 
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
 	"knowledge-capsule/pkg/logger"
 	"knowledge-capsule/pkg/utils"
 )
 
+// AuthSettings configures login behaviour.
+type AuthSettings struct {
+	AccessTokenTTL           time.Duration
+	RefreshTokenTTL          time.Duration
+	RequireEmailVerification bool
+}
+
+const loginChallengeTTL = 5 * time.Minute
+
+var authSettings = AuthSettings{
+	AccessTokenTTL:  15 * time.Minute,
+	RefreshTokenTTL: 30 * 24 * time.Hour,
+}
+
+// InitAuth sets token lifetimes and login policy from config.
+func InitAuth(settings AuthSettings) {
+	authSettings = settings
+}
+
 // RegisterHandler godoc
 // @Summary Register a new user
-// @Description Register a new user with name, email and password
+// @Description Register a new user with name, email and password. A verification email is sent to the address.
 // @Tags auth
 // @Accept  json
 // @Produce  json
 // @Param input body object{name=string,email=string,password=string} true "User registration info"
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{} "Too many attempts; see Retry-After"
 // @Router /api/auth/register [post]
 func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	if !utils.AllowMethod(w, r, http.MethodPost) {
@@ -35,12 +58,21 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Every registration attempt counts, so one IP cannot mass-create accounts.
+	ip := utils.ClientIP(r)
+	if rejectIfLocked(w, r, "register", models.ThrottleKey(models.ThrottleScopeRegister, ip)) {
+		return
+	}
+	recordFailure(r, models.ThrottleScopeRegister, ip, throttleSettings.Register)
+
 	user, err := UserStore.AddUser(req.Name, req.Email, req.Password)
 	if err != nil {
+		logger.LogEvent(logger.EventAuth, r, slog.String("action", "register_failed"), slog.String("email", req.Email), slog.String("reason", err.Error()))
 		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
 		return
 	}
 	logger.LogEvent(logger.EventAuth, r, slog.String("action", "register"), slog.String("user_id", user.ID), slog.String("email", req.Email))
+	sendVerificationEmail(r, user)
 
 	utils.JSONResponse(w, http.StatusCreated, true, "User registered", map[string]string{
 		"user_id": user.ID,
@@ -49,13 +81,14 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 
 // LoginHandler godoc
 // @Summary Login user
-// @Description Login with email and password to get JWT token
+// @Description Login with email and password to get a short-lived JWT access token and a refresh token
 // @Tags auth
 // @Accept  json
 // @Produce  json
 // @Param input body object{email=string,password=string} true "User login info"
 // @Success 200 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{} "Locked out; see Retry-After"
 // @Router /api/auth/login [post]
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	if !utils.AllowMethod(w, r, http.MethodPost) {
@@ -70,29 +103,242 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if rejectIfLocked(w, r, "login",
+		models.ThrottleKey(models.ThrottleScopeIP, utils.ClientIP(r)),
+		models.ThrottleKey(models.ThrottleScopeAccount, accountSubject(req.Email))) {
+		return
+	}
+
 	user, err := UserStore.FindByEmail(req.Email)
 	if err != nil || user == nil {
+		recordLoginFailure(r, req.Email, "unknown_email")
 		utils.ErrorResponse(w, r, http.StatusUnauthorized, errors.New("invalid credentials"))
 		return
 	}
 
 	if !utils.CheckPassword(req.Password, user.PasswordHash) {
+		recordLoginFailure(r, req.Email, "invalid_password")
 		utils.ErrorResponse(w, r, http.StatusUnauthorized, errors.New("invalid credentials"))
 		return
 	}
+	if utils.NeedsRehash(user.PasswordHash) {
+		rehashPassword(r, user.ID, req.Password)
+	}
+	if authSettings.RequireEmailVerification && user.EmailVerifiedAt == nil {
+		logger.LogEvent(logger.EventAuth, r, slog.String("action", "login_blocked"), slog.String("user_id", user.ID), slog.String("reason", "email_not_verified"))
+		utils.ErrorResponse(w, r, http.StatusForbidden, errors.New("email not verified"))
+		return
+	}
 
-	role := user.Role
-	if role == "" {
-		role = "user"
+	message, data, err := completeLogin(r, user)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, message, data)
+}
+
+// LoginTwoFactorHandler godoc
+// @Summary Complete two-factor login
+// @Description Exchange the challenge token from /api/auth/login and a TOTP or recovery code for tokens. The challenge is single-use: a wrong code requires signing in again.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param input body object{challenge_token=string,code=string} true "Challenge token and TOTP or recovery code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{} "Locked out; see Retry-After"
+// @Router /api/auth/login/2fa [post]
+func LoginTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+	if !utils.ParseAndValidateBody(w, r, &req) {
+		return
+	}
+
+	if rejectIfLocked(w, r, "login_2fa", models.ThrottleKey(models.ThrottleScopeIP, utils.ClientIP(r))) {
+		return
+	}
+
+	userID, err := TokenStore.ConsumeToken(req.ChallengeToken, models.TokenPurposeLoginChallenge)
+	if err != nil {
+		recordLoginFailure(r, "", "invalid_challenge")
+		utils.ErrorResponse(w, r, http.StatusUnauthorized, err)
+		return
 	}
-	token, err := utils.GenerateJWT(user.ID, user.Email, role, time.Hour*24)
+	user, err := UserStore.FindByID(userID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusUnauthorized, errors.New("invalid challenge"))
+		return
+	}
+	// Wrong codes count against the account, so a known password cannot buy unlimited
+	// guesses at the second factor.
+	if rejectIfLocked(w, r, "login_2fa", models.ThrottleKey(models.ThrottleScopeAccount, accountSubject(user.Email))) {
+		return
+	}
+	method, ok := verifySecondFactor(user, req.Code)
+	if !ok {
+		recordLoginFailure(r, user.Email, "invalid_2fa_code")
+		utils.ErrorResponse(w, r, http.StatusUnauthorized, errors.New("invalid two-factor code; sign in again"))
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "login_2fa"), slog.String("user_id", user.ID), slog.String("method", method))
+
+	data, err := startSession(r, user, true)
 	if err != nil {
 		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
 		return
 	}
-	logger.LogEvent(logger.EventAuth, r, slog.String("action", "login"), slog.String("user_id", user.ID), slog.String("email", req.Email))
+	utils.JSONResponse(w, http.StatusOK, true, "Login successful", data)
+}
 
-	utils.JSONResponse(w, http.StatusOK, true, "Login successful", map[string]string{
-		"token": token,
-	})
+// completeLogin finishes a first-factor login (password or SSO): users with 2FA get a
+// single-use challenge token, everyone else gets a session.
+func completeLogin(r *http.Request, user *models.User) (string, map[string]interface{}, error) {
+	if user.TwoFactorEnabled {
+		challenge, err := TokenStore.CreateToken(user.ID, models.TokenPurposeLoginChallenge, loginChallengeTTL)
+		if err != nil {
+			return "", nil, err
+		}
+		logger.LogEvent(logger.EventAuth, r, slog.String("action", "login_challenge"), slog.String("user_id", user.ID), slog.String("email", user.Email))
+		return "Two-factor authentication required", map[string]interface{}{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+			"expires_in":          int(loginChallengeTTL.Seconds()),
+		}, nil
+	}
+
+	data, err := startSession(r, user, false)
+	return "Login successful", data, err
+}
+
+// startSession creates a session for an authenticated user and returns its tokens. The
+// account's failed logins are cleared only here, once every factor has passed.
+func startSession(r *http.Request, user *models.User, mfa bool) (map[string]interface{}, error) {
+	session, refreshToken, err := SessionStore.CreateSession(user.ID, r.UserAgent(), utils.ClientIP(r), mfa, authSettings.RefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	resetAccountThrottle(r, user.Email)
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "login"), slog.String("user_id", user.ID), slog.String("email", user.Email), slog.String("session_id", session.FamilyID), slog.Bool("mfa", mfa))
+
+	return tokenResponse(user, session, refreshToken)
+}
+
+// RefreshHandler godoc
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token and a rotated refresh token. Reusing an old refresh token revokes the whole session.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param input body object{refresh_token=string} true "Refresh token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/auth/refresh [post]
+func RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if !utils.ParseAndValidateBody(w, r, &req) {
+		return
+	}
+
+	session, refreshToken, err := SessionStore.RotateSession(req.RefreshToken, r.UserAgent(), utils.ClientIP(r), authSettings.RefreshTokenTTL)
+	if err != nil {
+		if errors.Is(err, store.ErrRefreshTokenReused) {
+			logger.LogEvent(logger.EventAuth, r, slog.String("action", "refresh_reuse"), slog.String("reason", err.Error()))
+		}
+		utils.ErrorResponse(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	// Re-read the user so role changes take effect on the next access token.
+	user, err := UserStore.FindByID(session.UserID)
+	if err != nil {
+		SessionStore.RevokeSession(session.FamilyID, session.UserID)
+		utils.ErrorResponse(w, r, http.StatusUnauthorized, errors.New("invalid refresh token"))
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "refresh"), slog.String("user_id", user.ID), slog.String("session_id", session.FamilyID))
+
+	data, err := tokenResponse(user, session, refreshToken)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Token refreshed", data)
+}
+
+// LogoutHandler godoc
+// @Summary Logout
+// @Description Revoke the session behind the current access token and its refresh token
+// @Tags auth
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/auth/logout [post]
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	sessionID, _ := r.Context().Value(middleware.SessionContextKey).(string)
+
+	if err := SessionStore.RevokeSession(sessionID, userID); err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "logout"), slog.String("session_id", sessionID))
+	utils.JSONResponse(w, http.StatusOK, true, "Logged out", nil)
+}
+
+// rehashPassword upgrades a legacy or outdated password hash after a successful login.
+// Failures are logged and never block the login.
+func rehashPassword(r *http.Request, userID, password string) {
+	hash, err := utils.HashPassword(password)
+	if err == nil {
+		err = UserStore.UpdatePasswordHash(userID, hash)
+	}
+	if err != nil {
+		logger.ErrorRequest(r, logger.EventAuth, err, slog.String("action", "rehash_password"), slog.String("user_id", userID))
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "rehash_password"), slog.String("user_id", userID))
+}
+
+// tokenResponse mints an access token for the session and returns both tokens.
+func tokenResponse(user *models.User, session *models.Session, refreshToken string) (map[string]interface{}, error) {
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+	token, err := utils.GenerateJWT(models.TokenClaims{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      role,
+		SessionID: session.FamilyID,
+		MFA:       session.MFA,
+	}, authSettings.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"token":              token,
+		"token_type":         "Bearer",
+		"expires_in":         int(authSettings.AccessTokenTTL.Seconds()),
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": session.ExpiresAt,
+	}, nil
 }
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
+	"knowledge-capsule/pkg/utils"
+)
+
+// noRecoveryCodes rejects every recovery code and accepts every TOTP step.
+type noRecoveryCodes struct {
+	store.TwoFactorStore
+}
+
+func (noRecoveryCodes) UseRecoveryCode(string, string) (bool, error) { return false, nil }
+func (noRecoveryCodes) MarkStepUsed(string, int64) (bool, error)     { return true, nil }
+
+const testPassword = "correct horse battery"
+
+// setupTwoFactorLogin wires stores for a user with 2FA who may fail three times per account.
+func setupTwoFactorLogin(t *testing.T) *models.User {
+	t.Helper()
+	utils.InitPasswordParams(utils.PasswordParams{Memory: 64, Iterations: 1, Parallelism: 1})
+	if err := utils.InitJWT(utils.JWTSettings{Secret: "test-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := utils.HashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{ID: "u1", Email: "ada@example.com", PasswordHash: hash, TOTPSecret: secret, TwoFactorEnabled: true}
+
+	db := newTestDB(t, &models.OneTimeToken{}, &models.AuthThrottle{}, &models.Session{})
+	users, tokens, throttles, sessions, twoFactor, settings := UserStore, TokenStore, ThrottleStore, SessionStore, TwoFactorStore, throttleSettings
+	t.Cleanup(func() {
+		UserStore, TokenStore, ThrottleStore, SessionStore, TwoFactorStore, throttleSettings = users, tokens, throttles, sessions, twoFactor, settings
+		utils.InitPasswordParams(utils.DefaultPasswordParams)
+	})
+	UserStore = fakeUsers{users: map[string]*models.User{user.ID: user}}
+	TokenStore = store.NewOneTimeTokenStore(db)
+	ThrottleStore = store.NewThrottleStore(db)
+	SessionStore = store.NewSessionStore(db)
+	TwoFactorStore = noRecoveryCodes{}
+	throttleSettings = ThrottleSettings{
+		Account: models.ThrottlePolicy{MaxFailures: 3, BaseLockout: time.Minute, MaxLockout: time.Hour, Window: time.Hour},
+		IP:      models.ThrottlePolicy{MaxFailures: 100, BaseLockout: time.Minute, MaxLockout: time.Hour, Window: time.Hour},
+	}
+	return user
+}
+
+// loginChallenge signs in with a password and returns the 2FA challenge token.
+func loginChallenge(t *testing.T, email, password string) (int, string) {
+	t.Helper()
+	w := serveJSON(t, LoginHandler, http.MethodPost, "/api/auth/login", map[string]string{"email": email, "password": password})
+	var body struct {
+		Data struct {
+			ChallengeToken string `json:"challenge_token"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body.Data.ChallengeToken
+}
+
+func submitCode(t *testing.T, challenge, code string) int {
+	t.Helper()
+	return serveJSON(t, LoginTwoFactorHandler, http.MethodPost, "/api/auth/login/2fa",
+		map[string]string{"challenge_token": challenge, "code": code}).Code
+}
+
+func TestLoginTwoFactorCountsAgainstAccount(t *testing.T) {
+	user := setupTwoFactorLogin(t)
+
+	// A correct password must not clear the failures of wrong second factors.
+	for i := 0; i < 3; i++ {
+		status, challenge := loginChallenge(t, user.Email, testPassword)
+		if status != http.StatusOK || challenge == "" {
+			t.Fatalf("login %d: status = %d, challenge %q", i, status, challenge)
+		}
+		if status := submitCode(t, challenge, "wrong"); status != http.StatusUnauthorized {
+			t.Fatalf("2fa %d: status = %d, want 401", i, status)
+		}
+	}
+	if status, _ := loginChallenge(t, user.Email, testPassword); status != http.StatusTooManyRequests {
+		t.Errorf("login after repeated wrong codes: status = %d, want 429", status)
+	}
+}
+
+func TestLoginTwoFactorRejectsLockedAccount(t *testing.T) {
+	user := setupTwoFactorLogin(t)
+	_, challenge := loginChallenge(t, user.Email, testPassword)
+	for i := 0; i < 3; i++ {
+		if status, _ := loginChallenge(t, user.Email, "wrong password"); status != http.StatusUnauthorized {
+			t.Fatalf("login %d: status = %d, want 401", i, status)
+		}
+	}
+
+	code, err := utils.TOTPCode(user.TOTPSecret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := submitCode(t, challenge, code); status != http.StatusTooManyRequests {
+		t.Errorf("2fa on a locked account: status = %d, want 429", status)
+	}
+}
+
+func TestLoginTwoFactorResetsAccountOnSuccess(t *testing.T) {
+	user := setupTwoFactorLogin(t)
+	for i := 0; i < 2; i++ {
+		_, challenge := loginChallenge(t, user.Email, testPassword)
+		submitCode(t, challenge, "wrong")
+	}
+
+	_, challenge := loginChallenge(t, user.Email, testPassword)
+	code, err := utils.TOTPCode(user.TOTPSecret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := submitCode(t, challenge, code); status != http.StatusOK {
+		t.Fatalf("2fa with a valid code: status = %d, want 200", status)
+	}
+	key := models.ThrottleKey(models.ThrottleScopeAccount, accountSubject(user.Email))
+	if cleared, err := ThrottleStore.Reset(key); err != nil || cleared {
+		t.Errorf("account failures after a full login: cleared = %v, %v; want none left", cleared, err)
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+// BulkCapsules godoc
+// @Summary Bulk capsule operations
+// @Description Apply one action to many capsules in a single transaction: delete (move to the trash), add_tags, remove_tags, set_topic (by topic_id or name, as on update) or set_private (is_private, or toggle each capsule when omitted). Capsules are given by ids, or by filter, a query string with the filters of GET /api/capsules that matches the caller's own capsules (at most 500 either way). Filters are strict: unknown or repeated params, empty or invalid values and filters without a condition are rejected (400). Permissions are those of single updates and deletes, and each changed capsule gets a new revision. Each capsule is reported as updated, deleted, unchanged or failed; a failure only undoes that capsule, unless atomic is set, in which case any failure rolls back every change (409, with the others reported as rolled_back).
+// @Tags capsules
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param input body models.BulkInput true "Action, capsules and action arguments"
+// @Success 200 {object} models.BulkResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} models.BulkResult
+// @Router /api/capsules/bulk [post]
+func BulkCapsules(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	var req models.BulkInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	switch req.Action {
+	case models.BulkAddTags, models.BulkRemoveTags:
+		if len(models.NormalizeTags(req.Tags)) == 0 {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "tags", Message: "is required"})
+			return
+		}
+	case models.BulkSetTopic:
+		if strings.TrimSpace(req.Topic) == "" && (req.TopicID == nil || *req.TopicID == "") {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "topic", Message: "topic or topic_id is required"})
+			return
+		}
+	case models.BulkDelete, models.BulkSetPrivate:
+	default:
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "action", Message: "must be delete, add_tags, remove_tags, set_topic or set_private"})
+		return
+	}
+
+	var filters *models.CapsuleFilters
+	switch filter := strings.TrimSpace(req.Filter); {
+	case filter != "" && len(req.IDs) > 0:
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "filter", Message: "cannot be combined with ids"})
+		return
+	case filter != "":
+		var err error
+		if filters, err = bulkFilters(filter); err != nil {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		ids := make([]string, 0, len(req.IDs))
+		seen := make(map[string]bool, len(req.IDs))
+		for _, id := range req.IDs {
+			if id = strings.TrimSpace(id); id != "" && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "ids", Message: "ids or filter is required"})
+			return
+		}
+		if len(ids) > models.MaxBulkCapsules {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "ids", Message: fmt.Sprintf("at most %d capsules", models.MaxBulkCapsules)})
+			return
+		}
+		req.IDs = ids
+	}
+
+	result, err := CapsuleStore.BulkUpdateCapsules(userID, req, filters)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrBulkTooLarge) {
+			status = http.StatusBadRequest
+		}
+		utils.ErrorResponse(w, r, status, err)
+		return
+	}
+	logger.LogEvent(logger.EventCapsule, r, slog.String("action", "bulk_"+req.Action), slog.Bool("applied", result.Applied),
+		slog.Int("succeeded", result.Succeeded), slog.Int("failed", result.Failed))
+	if !result.Applied {
+		utils.JSONResponse(w, http.StatusConflict, false, "Bulk operation rolled back", result)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Bulk operation applied", result)
+}
+
+// bulkFilterKeys are the query params a bulk filter may use, with whether each narrows the
+// match on its own.
+var bulkFilterKeys = map[string]bool{
+	"topic":             true,
+	"topic_id":          true,
+	"include_subtopics": false,
+	"tags":              true,
+	"q":                 true,
+	"is_private":        true,
+	"created_from":      true,
+	"created_before":    true,
+}
+
+// bulkFilters reads a bulk filter query string. Unlike list filters it is strict: unknown or
+// repeated params, empty or invalid values and filters without any condition are rejected, as
+// a filter that silently matched nothing less would apply the action to every capsule.
+func bulkFilters(filter string) (*models.CapsuleFilters, error) {
+	values, err := url.ParseQuery(strings.TrimPrefix(filter, "?"))
+	if err != nil {
+		return nil, &utils.ValidationError{Field: "filter", Message: "must be a query string"}
+	}
+	narrows := false
+	for key, vals := range values {
+		condition, known := bulkFilterKeys[key]
+		switch {
+		case !known:
+			return nil, &utils.ValidationError{Field: "filter", Message: "unknown filter " + key}
+		case len(vals) > 1:
+			return nil, &utils.ValidationError{Field: "filter", Message: key + " is given more than once"}
+		case strings.TrimSpace(vals[0]) == "":
+			return nil, &utils.ValidationError{Field: "filter", Message: key + " cannot be empty"}
+		}
+		narrows = narrows || condition
+	}
+	for _, key := range []string{"is_private", "include_subtopics"} {
+		if v := values.Get(key); v != "" && v != "true" && v != "false" {
+			return nil, &utils.ValidationError{Field: "filter", Message: key + " must be true or false"}
+		}
+	}
+	if !narrows {
+		return nil, &utils.ValidationError{Field: "filter", Message: "must have at least one capsule filter"}
+	}
+
+	filters, err := capsuleFilters(values)
+	if err != nil {
+		return nil, err
+	}
+	if values.Has("tags") && len(filters.Tags) == 0 {
+		return nil, &utils.ValidationError{Field: "filter", Message: "tags must name at least one tag"}
+	}
+	return filters, nil
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"testing"
+)
+
+func TestBulkFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		wantErr bool
+	}{
+		{"topic and tags", "topic=Golang&tags=draft", false},
+		{"leading question mark", "?is_private=true", false},
+		{"date range", "created_from=2026-01-01&created_before=2026-02-01T00:00:00Z", false},
+		{"subtopics with topic", "topic_id=t1&include_subtopics=true", false},
+		{"unknown key", "topic=Golang&owner=me", true},
+		{"invalid is_private", "is_private=maybe", true},
+		{"invalid include_subtopics", "topic=Golang&include_subtopics=yes", true},
+		{"invalid date", "created_from=garbage", true},
+		{"no tags", "tags=,", true},
+		{"empty value", "topic=", true},
+		{"blank value", "q=%20", true},
+		{"repeated key", "tags=a&tags=b", true},
+		{"no condition", "include_subtopics=true", true},
+		{"malformed", "topic=%zz", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters, err := bulkFilters(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bulkFilters(%q) error = %v, wantErr %v", tt.filter, err, tt.wantErr)
+			}
+			if !tt.wantErr && filters == nil {
+				t.Errorf("bulkFilters(%q) = nil, want filters", tt.filter)
+			}
+		})
+	}
+}
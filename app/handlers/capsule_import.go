@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/markdown"
+	"knowledge-capsule/pkg/utils"
+)
+
+// Import size bounds
+const (
+	maxImportBytes = 50 << 20
+	maxImportItems = 1000
+)
+
+// ExportCapsules godoc
+// @Summary Export capsules
+// @Description Download the caller's capsules (outside the trash) as one JSON file, or as a zip of Markdown files with YAML front matter (title, content_format, topic, tags, is_private, created_at, updated_at) in one folder per topic. Both can be imported again.
+// @Tags capsules
+// @Produce  json
+// @Produce  application/zip
+// @Security BearerAuth
+// @Param format query string false "json (default) or markdown-zip" Enums(json, markdown-zip)
+// @Param topic query string false "Only capsules in this topic"
+// @Param tags query string false "Only capsules with these tags (comma-separated)"
+// @Param is_private query bool false "Only private or only non-private capsules"
+// @Success 200 {object} models.CapsuleExportFile
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/capsules/export [get]
+func ExportCapsules(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = models.ExportFormatJSON
+	case models.ExportFormatJSON, models.ExportFormatMarkdownZip:
+	default:
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "format", Message: "must be json or markdown-zip"})
+		return
+	}
+
+	filters, err := parseCapsuleFilters(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	capsules, err := CapsuleStore.ExportCapsules(userID, filters)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	exports := make([]models.CapsuleExport, len(capsules))
+	for i, c := range capsules {
+		exports[i] = models.CapsuleExport{
+			Title:         c.Title,
+			Content:       c.Content,
+			ContentFormat: c.ContentFormat,
+			Topic:         c.Topic,
+			Tags:          c.Tags,
+			IsPrivate:     c.IsPrivate,
+			CreatedAt:     &capsules[i].CreatedAt,
+			UpdatedAt:     &capsules[i].UpdatedAt,
+		}
+	}
+
+	name := "capsules-" + time.Now().UTC().Format("2006-01-02")
+	if format == models.ExportFormatMarkdownZip {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.zip"`)
+		err = markdown.WriteArchive(w, exports)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.json"`)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(models.CapsuleExportFile{
+			Version:    models.CapsuleExportVersion,
+			ExportedAt: time.Now().UTC(),
+			Capsules:   exports,
+		})
+	}
+	if err != nil {
+		logger.ErrorRequest(r, logger.EventCapsule, err, slog.String("action", "export"), slog.String("format", format))
+		return
+	}
+	logger.LogEvent(logger.EventCapsule, r, slog.String("action", "export"), slog.String("format", format), slog.Int("count", len(exports)))
+}
+
+// ImportCapsules godoc
+// @Summary Import capsules
+// @Description Import capsules from a JSON export, a zip of Markdown files with YAML front matter, or an Obsidian vault (zipped, or single notes). Upload as multipart file fields, or send a JSON export or zip archive as the raw request body. Markdown notes without front matter take their title from the file name and their topic from their folder; Obsidian notes also get their inline #tags, and links to headings or paths are reduced to [[Note]]. An item whose title matches one of the caller's capsules (or an earlier item) is a duplicate: skipped, imported as an update (a new revision, unless the content is the same) or created anyway, by on_duplicate. dry_run reports what the import would do without saving anything. The report lists each item with its status: created, updated, skipped or failed.
+// @Tags capsules
+// @Accept  mpfd
+// @Accept  json
+// @Accept  application/zip
+// @Produce  json
+// @Security BearerAuth
+// @Param format query string false "json, markdown-zip or obsidian; by default json, or markdown-zip for zip archives and .md files" Enums(json, markdown-zip, obsidian)
+// @Param on_duplicate query string false "skip (default), update or create" Enums(skip, update, create)
+// @Param dry_run query bool false "Report without importing"
+// @Param file formData file false "Export file, zip archive or Markdown note (repeatable)"
+// @Success 200 {object} models.ImportReport
+// @Failure 400 {object} map[string]interface{}
+// @Failure 413 {object} map[string]interface{}
+// @Router /api/capsules/import [post]
+func ImportCapsules(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	query := r.URL.Query()
+	format := query.Get("format")
+	switch format {
+	case "", models.ExportFormatJSON, models.ExportFormatMarkdownZip, models.ImportFormatObsidian:
+	default:
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "format", Message: "must be json, markdown-zip or obsidian"})
+		return
+	}
+	onDuplicate := query.Get("on_duplicate")
+	switch onDuplicate {
+	case "":
+		onDuplicate = models.DuplicateSkip
+	case models.DuplicateSkip, models.DuplicateUpdate, models.DuplicateCreate:
+	default:
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "on_duplicate", Message: "must be skip, update or create"})
+		return
+	}
+	dryRun := query.Get("dry_run") == "true"
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	items, err := readImport(r, format)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || errors.Is(err, markdown.ErrArchiveTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		utils.ErrorResponse(w, r, status, err)
+		return
+	}
+	if len(items) == 0 {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "file", Message: "contains no capsules"})
+		return
+	}
+	if len(items) > maxImportItems {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "file", Message: fmt.Sprintf("contains more than %d capsules", maxImportItems)})
+		return
+	}
+
+	report, err := importItems(userID, items, onDuplicate, dryRun)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	logger.LogEvent(logger.EventCapsule, r, slog.String("action", "import"), slog.Bool("dry_run", dryRun),
+		slog.Int("created", report.Created), slog.Int("updated", report.Updated),
+		slog.Int("skipped", report.Skipped), slog.Int("failed", report.Failed))
+	message := "Capsules imported"
+	if dryRun {
+		message = "Import checked"
+	}
+	utils.JSONResponse(w, http.StatusOK, true, message, report)
+}
+
+// readImport reads the items of an import from the file fields of a multipart form, or
+// from the request body. All archives of the import share one unpacked size budget.
+func readImport(r *http.Request, format string) ([]models.ImportItem, error) {
+	budget := int64(markdown.MaxArchiveBytes)
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		return readImportFile("", data, format, &budget)
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		return nil, err
+	}
+	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		return nil, &utils.ValidationError{Field: "file", Message: "is required"}
+	}
+	var items []models.ImportItem
+	for _, fh := range files {
+		f, err := fh.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		fileItems, err := readImportFile(fh.Filename, data, format, &budget)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fh.Filename, err)
+		}
+		items = append(items, fileItems...)
+	}
+	return items, nil
+}
+
+// readImportFile reads one uploaded file: a JSON export, a zip archive or a Markdown note.
+// Without a format, zip archives and .md files are read as Markdown, anything else as JSON.
+// Archives are unpacked within budget.
+func readImportFile(name string, data []byte, format string, budget *int64) ([]models.ImportItem, error) {
+	isZip := bytes.HasPrefix(data, []byte("PK\x03\x04"))
+	ext := strings.ToLower(path.Ext(name))
+	isNote := ext == ".md" || ext == ".markdown"
+	switch {
+	case format == models.ExportFormatJSON || (format == "" && !isZip && !isNote):
+		return readImportJSON(data)
+	case isZip:
+		return markdown.ReadArchive(data, format == models.ImportFormatObsidian, budget)
+	case len(data) > markdown.MaxNoteBytes:
+		return nil, errors.New("note is larger than 1 MB")
+	default:
+		return []models.ImportItem{markdown.ReadNote(path.Base(name), data, format == models.ImportFormatObsidian)}, nil
+	}
+}
+
+// readImportJSON reads a JSON export, or a bare array of capsules.
+func readImportJSON(data []byte) ([]models.ImportItem, error) {
+	var file models.CapsuleExportFile
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trimmed, &file.Capsules); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	} else {
+		if err := json.Unmarshal(trimmed, &file); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if file.Version > models.CapsuleExportVersion {
+			return nil, fmt.Errorf("unsupported export version %d", file.Version)
+		}
+	}
+	items := make([]models.ImportItem, len(file.Capsules))
+	for i, c := range file.Capsules {
+		items[i] = models.ImportItem{Source: fmt.Sprintf("capsules[%d]", i), Capsule: c}
+	}
+	return items, nil
+}
+
+// importTarget is a capsule an import item can duplicate: one of the user's capsules, or an
+// earlier item of the same import.
+type importTarget struct {
+	id      string // empty for items not saved (dry runs)
+	ref     string // reported as duplicate_of
+	content string
+	format  string
+}
+
+// importItems validates each item and creates, updates or skips it, reporting what happened.
+// With dryRun nothing is saved. A failed item does not stop the others.
+func importItems(userID string, items []models.ImportItem, onDuplicate string, dryRun bool) (*models.ImportReport, error) {
+	titles := make([]string, 0, len(items))
+	for _, item := range items {
+		if t := strings.TrimSpace(item.Capsule.Title); item.Err == nil && t != "" {
+			titles = append(titles, t)
+		}
+	}
+	existing, err := CapsuleStore.FindByTitles(userID, titles)
+	if err != nil {
+		return nil, err
+	}
+	targets := make(map[string]importTarget, len(existing)+len(items))
+	for _, c := range existing {
+		if key := strings.ToLower(c.Title); targets[key].ref == "" {
+			targets[key] = importTarget{id: c.ID, ref: c.ID, content: c.Content, format: c.ContentFormat}
+		}
+	}
+
+	report := &models.ImportReport{DryRun: dryRun, Total: len(items), Items: make([]models.ImportItemResult, 0, len(items))}
+	for _, item := range items {
+		c := item.Capsule
+		c.Title = strings.TrimSpace(c.Title)
+		if c.ContentFormat == "" {
+			c.ContentFormat = models.ContentPlain
+		}
+		result := models.ImportItemResult{Source: item.Source, Title: c.Title}
+		fail := func(err error) {
+			result.Status = models.ImportFailed
+			result.Error = err.Error()
+			report.Failed++
+		}
+
+		switch {
+		case item.Err != nil:
+			fail(item.Err)
+		case c.Title == "":
+			fail(&utils.ValidationError{Field: "title", Message: "cannot be empty"})
+		case len(c.Title) > maxTitleLen:
+			fail(&utils.ValidationError{Field: "title", Message: "exceeds maximum length"})
+		case len(c.Content) > maxContentLen:
+			fail(&utils.ValidationError{Field: "content", Message: "exceeds maximum length of 1 MB"})
+		case !models.ValidContentFormat(c.ContentFormat):
+			fail(&utils.ValidationError{Field: "content_format", Message: "must be plain or markdown"})
+		}
+		if result.Status != "" {
+			report.Items = append(report.Items, result)
+			continue
+		}
+
+		key := strings.ToLower(c.Title)
+		input := models.CapsuleInput{
+			Title:         c.Title,
+			Content:       c.Content,
+			ContentFormat: c.ContentFormat,
+			Topic:         strings.TrimSpace(c.Topic),
+			Tags:          c.Tags,
+			IsPrivate:     c.IsPrivate,
+		}
+		target, duplicate := targets[key]
+		if duplicate {
+			result.DuplicateOf = target.ref
+		}
+		switch {
+		case duplicate && onDuplicate != models.DuplicateCreate:
+			if onDuplicate == models.DuplicateSkip || target.content == c.Content && target.format == c.ContentFormat {
+				result.Status = models.ImportSkipped
+				report.Skipped++
+				break
+			}
+			result.CapsuleID = target.id
+			if !dryRun {
+				if _, err := CapsuleStore.UpdateCapsule(target.id, userID, models.Capsule{CapsuleInput: input}); err != nil {
+					fail(err)
+					break
+				}
+			}
+			target.content, target.format = c.Content, c.ContentFormat
+			targets[key] = target
+			result.Status = models.ImportUpdated
+			report.Updated++
+		default:
+			ref := item.Source
+			if !dryRun {
+				capsule, err := CapsuleStore.ImportCapsule(userID, input, c.CreatedAt, c.UpdatedAt)
+				if err != nil {
+					fail(err)
+					break
+				}
+				result.CapsuleID = capsule.ID
+				ref = capsule.ID
+			}
+			if !duplicate {
+				targets[key] = importTarget{id: result.CapsuleID, ref: ref, content: c.Content, format: c.ContentFormat}
+			}
+			result.Status = models.ImportCreated
+			report.Created++
+		}
+		report.Items = append(report.Items, result)
+	}
+	return report, nil
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+const (
+	linkSlugBytes      = 12 // random slugs are 16 URL-safe characters
+	minLinkPassword    = 8
+	maxLinkPasswordLen = 128
+)
+
+// linkSlugPattern allows lowercase words joined by hyphens, 3 to 64 characters.
+var linkSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}[a-z0-9]$`)
+
+// ListCapsuleLinks godoc
+// @Summary List public links
+// @Description List a capsule's public read-only links with their view counts (owner only)
+// @Tags sharing
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Capsule ID"
+// @Success 200 {array} models.CapsuleLink
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/capsules/{id}/links [get]
+func ListCapsuleLinks(w http.ResponseWriter, r *http.Request, capsuleID string) {
+	if _, ok := ownCapsule(w, r, capsuleID); !ok {
+		return
+	}
+	links, err := LinkStore.ListLinks(capsuleID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	for i := range links {
+		links[i].URL = publicLinkURL(links[i].Slug)
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Links fetched", links)
+}
+
+// CreateCapsuleLink godoc
+// @Summary Create public link
+// @Description Create a read-only link at /p/{slug} that anyone can open without an account (owner only). Only public capsules can have links, and links stop working while their capsule is private. Without a slug a random one is generated. Password (8-128 characters) and expiry are optional.
+// @Tags sharing
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Capsule ID"
+// @Param input body models.CapsuleLinkInput false "Optional slug, password and expiry"
+// @Success 201 {object} models.CapsuleLink
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/capsules/{id}/links [post]
+func CreateCapsuleLink(w http.ResponseWriter, r *http.Request, capsuleID string) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	capsule, ok := ownCapsule(w, r, capsuleID)
+	if !ok {
+		return
+	}
+	var req models.CapsuleLinkInput
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	// Links publish the capsule to anyone who finds the URL, so private capsules get none.
+	if capsule.IsPrivate {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, store.ErrCapsulePrivate)
+		return
+	}
+	slug := strings.ToLower(strings.TrimSpace(req.Slug))
+	if slug == "" {
+		var err error
+		if slug, err = utils.GenerateSecureToken(linkSlugBytes); err != nil {
+			utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	} else if !linkSlugPattern.MatchString(slug) {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "slug", Message: "must be 3-64 lowercase letters, digits or hyphens"})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "expires_at", Message: "must be in the future"})
+		return
+	}
+	var passwordHash string
+	if req.Password != "" {
+		if len(req.Password) < minLinkPassword || len(req.Password) > maxLinkPasswordLen {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "password", Message: fmt.Sprintf("must be %d-%d characters", minLinkPassword, maxLinkPasswordLen)})
+			return
+		}
+		var err error
+		if passwordHash, err = utils.HashPassword(req.Password); err != nil {
+			utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	link, err := LinkStore.CreateLink(capsuleID, userID, slug, passwordHash, req.ExpiresAt)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, store.ErrSlugTaken) {
+			status = http.StatusConflict
+		}
+		utils.ErrorResponse(w, r, status, err)
+		return
+	}
+	link.URL = publicLinkURL(link.Slug)
+	logger.LogEvent(logger.EventCapsule, r, slog.String("action", "create_link"), slog.String("capsule_id", capsuleID), slog.String("link_id", link.ID))
+	utils.JSONResponse(w, http.StatusCreated, true, "Link created", link)
+}
+
+// DeleteCapsuleLink godoc
+// @Summary Delete public link
+// @Description Delete a public link; its URL stops working immediately (owner only)
+// @Tags sharing
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Capsule ID"
+// @Param linkId path string true "Link ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/capsules/{id}/links/{linkId} [delete]
+func DeleteCapsuleLink(w http.ResponseWriter, r *http.Request, capsuleID, linkID string) {
+	if !utils.AllowMethod(w, r, http.MethodDelete) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	if err := LinkStore.DeleteLink(capsuleID, userID, linkID); err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	logger.LogEvent(logger.EventCapsule, r, slog.String("action", "delete_link"), slog.String("capsule_id", capsuleID), slog.String("link_id", linkID))
+	utils.JSONResponse(w, http.StatusOK, true, "Link deleted", nil)
+}
+
+// CapsuleLinksHandler routes /api/capsules/{id}/links[/{linkId}].
+func CapsuleLinksHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/capsules/"), "/")
+	id, rest, _ := strings.Cut(path, "/")
+	linkID := strings.TrimPrefix(strings.TrimPrefix(rest, "links"), "/")
+	if linkID != "" {
+		DeleteCapsuleLink(w, r, id, linkID)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		ListCapsuleLinks(w, r, id)
+	case http.MethodPost:
+		CreateCapsuleLink(w, r, id)
+	default:
+		utils.ErrorResponse(w, r, http.StatusMethodNotAllowed, nil)
+	}
+}
+
+func publicLinkURL(slug string) string {
+	return appBaseURL + "/p/" + slug
+}
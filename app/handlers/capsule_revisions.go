@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+const diffContextLines = 3
+
+// ListCapsuleRevisions godoc
+// @Summary List capsule revisions
+// @Description List a capsule's revisions, newest first. Content is omitted; fetch a single revision to get it. Available to the owner and anyone the capsule is shared with.
+// @Tags capsules
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Capsule ID"
+// @Success 200 {array} models.CapsuleRevision
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/capsules/{id}/revisions [get]
+func ListCapsuleRevisions(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	id, _ := parseRevisionPath(r)
+	if _, ok := readableCapsule(w, r, id); !ok {
+		return
+	}
+	revisions, err := CapsuleStore.ListRevisions(id)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Revisions fetched", revisions)
+}
+
+// GetCapsuleRevision godoc
+// @Summary Get capsule revision
+// @Description Get a capsule as it was at a given revision
+// @Tags capsules
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Capsule ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} models.CapsuleRevision
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/capsules/{id}/revisions/{rev} [get]
+func GetCapsuleRevision(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	id, sub := parseRevisionPath(r)
+	rev, err := strconv.Atoi(sub)
+	if err != nil || rev < 1 {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("invalid revision number"))
+		return
+	}
+	if _, ok := readableCapsule(w, r, id); !ok {
+		return
+	}
+	revision, err := CapsuleStore.GetRevision(id, rev)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Revision fetched", revision)
+}
+
+// DiffCapsuleRevisions godoc
+// @Summary Diff capsule revisions
+// @Description Line-based diff of the content between two revisions, plus changed title, topic, tags and privacy. Defaults to the latest revision and the one before it. Revisions whose content differs in more than 20000 lines, or by more than 1000 line edits, are not diffed (422).
+// @Tags capsules
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Capsule ID"
+// @Param from query int false "Older revision (default: to - 1)"
+// @Param to query int false "Newer revision (default: latest)"
+// @Success 200 {object} models.RevisionDiff
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/capsules/{id}/revisions/diff [get]
+func DiffCapsuleRevisions(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	id, _ := parseRevisionPath(r)
+	if _, ok := readableCapsule(w, r, id); !ok {
+		return
+	}
+
+	to, err := revisionParam(r, "to")
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if to == 0 {
+		revisions, err := CapsuleStore.ListRevisions(id)
+		if err != nil {
+			utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		if len(revisions) == 0 {
+			utils.ErrorResponse(w, r, http.StatusNotFound, errors.New("capsule has no revisions"))
+			return
+		}
+		to = revisions[0].Revision
+	}
+	from, err := revisionParam(r, "from")
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if from == 0 {
+		from = max(to-1, 1)
+	}
+
+	older, err := CapsuleStore.GetRevision(id, from)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	newer, err := CapsuleStore.GetRevision(id, to)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	lines, err := utils.DiffLines(older.Content, newer.Content)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+	diff := models.RevisionDiff{
+		From:    from,
+		To:      to,
+		Changes: revisionChanges(older, newer),
+		Lines:   lines,
+		Unified: utils.UnifiedDiff(lines, fmt.Sprintf("revision %d", from), fmt.Sprintf("revision %d", to), diffContextLines),
+	}
+	for _, l := range lines {
+		switch l.Op {
+		case models.DiffInsert:
+			diff.Added++
+		case models.DiffDelete:
+			diff.Removed++
+		}
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Diff computed", diff)
+}
+
+// RestoreCapsuleRevision godoc
+// @Summary Restore capsule revision
+// @Description Restore a capsule to an earlier revision (owner or editor). The restore is saved as a new revision, so no history is lost.
+// @Tags capsules
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Capsule ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} models.Capsule
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/capsules/{id}/revisions/{rev}/restore [post]
+func RestoreCapsuleRevision(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	id, sub := parseRevisionPath(r)
+	rev, err := strconv.Atoi(strings.TrimSuffix(sub, "/restore"))
+	if err != nil || rev < 1 {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("invalid revision number"))
+		return
+	}
+
+	capsule, err := CapsuleStore.RestoreRevision(id, userID, rev)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	logger.LogEvent(logger.EventCapsule, r, slog.String("action", "restore_revision"), slog.String("capsule_id", id), slog.Int("revision", rev))
+	utils.JSONResponse(w, http.StatusOK, true, "Revision restored", capsule)
+}
+
+// CapsuleRevisionsHandler routes /api/capsules/{id}/revisions[/diff|/{rev}|/{rev}/restore].
+func CapsuleRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	_, sub := parseRevisionPath(r)
+	switch {
+	case sub == "":
+		ListCapsuleRevisions(w, r)
+	case sub == "diff":
+		DiffCapsuleRevisions(w, r)
+	case strings.HasSuffix(sub, "/restore"):
+		RestoreCapsuleRevision(w, r)
+	case !strings.Contains(sub, "/"):
+		GetCapsuleRevision(w, r)
+	default:
+		utils.ErrorResponse(w, r, http.StatusNotFound, nil)
+	}
+}
+
+// parseRevisionPath splits /api/capsules/{id}/revisions/{sub} into id and sub.
+func parseRevisionPath(r *http.Request) (string, string) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/capsules/"), "/")
+	id, rest, _ := strings.Cut(path, "/")
+	rest = strings.TrimPrefix(rest, "revisions")
+	return id, strings.TrimPrefix(rest, "/")
+}
+
+// readableCapsule loads a capsule the caller owns or that is shared with them, responding 404 otherwise.
+func readableCapsule(w http.ResponseWriter, r *http.Request, id string) (*models.Capsule, bool) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	capsule, err := CapsuleStore.FindAccessible(id, userID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, errors.New("capsule not found"))
+		return nil, false
+	}
+	return capsule, true
+}
+
+// ownCapsule loads a capsule owned by the caller, responding 404 otherwise.
+func ownCapsule(w http.ResponseWriter, r *http.Request, id string) (*models.Capsule, bool) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	capsule, err := CapsuleStore.FindByID(id)
+	if err != nil || capsule.UserID != userID {
+		utils.ErrorResponse(w, r, http.StatusNotFound, errors.New("capsule not found"))
+		return nil, false
+	}
+	return capsule, true
+}
+
+// revisionParam reads an optional positive revision number from the query string.
+func revisionParam(r *http.Request, name string) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, &utils.ValidationError{Field: name, Message: "must be a revision number"}
+	}
+	return n, nil
+}
+
+// revisionChanges lists metadata fields that differ between two revisions.
+func revisionChanges(a, b *models.CapsuleRevision) []models.FieldChange {
+	changes := []models.FieldChange{}
+	if a.Title != b.Title {
+		changes = append(changes, models.FieldChange{Field: "title", From: a.Title, To: b.Title})
+	}
+	if a.Topic != b.Topic {
+		changes = append(changes, models.FieldChange{Field: "topic", From: a.Topic, To: b.Topic})
+	}
+	if !slices.Equal(a.Tags, b.Tags) {
+		changes = append(changes, models.FieldChange{Field: "tags", From: a.Tags, To: b.Tags})
+	}
+	if a.IsPrivate != b.IsPrivate {
+		changes = append(changes, models.FieldChange{Field: "is_private", From: a.IsPrivate, To: b.IsPrivate})
+	}
+	return changes
+}
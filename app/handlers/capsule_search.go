@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+// SearchCapsules godoc
+// @Summary Search capsules
+// @Description Full-text search over the caller's capsules, best match first. Title matches weigh more than tags, and tags more than content. The query supports "quoted phrases", OR and -exclusions. Each result has its rank and a content snippet with matches wrapped in <mark> tags. Without q, capsules come most recently updated first.
+// @Description The response also carries facet counts per tag, topic, privacy and created week or month over all matches, for drill-down filters. A created bucket narrows with created_from set to its value and created_before set to the next bucket.
+// @Tags capsules
+// @Produce  json
+// @Security BearerAuth
+// @Param q query string false "Search query"
+// @Param topic query string false "Filter by topic name (case-insensitive)"
+// @Param topic_id query string false "Filter by topic ID"
+// @Param include_subtopics query bool false "With topic or topic_id, also match capsules in its subtopics"
+// @Param tags query string false "Filter by tags (comma-separated, any spelling or alias)"
+// @Param is_private query bool false "Filter by is_private"
+// @Param created_from query string false "Created at or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param created_before query string false "Created before this date (YYYY-MM-DD or RFC 3339)"
+// @Param interval query string false "Created facet bucket size (default month)" Enums(week, month)
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {object} models.CapsuleSearchResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/capsules/search [get]
+func SearchCapsules(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	interval := r.URL.Query().Get("interval")
+	switch interval {
+	case "":
+		interval = models.FacetIntervalMonth
+	case models.FacetIntervalWeek, models.FacetIntervalMonth:
+	default:
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "interval", Message: "must be week or month"})
+		return
+	}
+	filters, err := parseCapsuleFilters(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	page, limit := utils.ParsePagination(r)
+	results, total, err := CapsuleStore.SearchCapsules(userID, filters, page, limit)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	refs := make([]*models.Capsule, len(results))
+	for i := range results {
+		refs[i] = &results[i].Capsule
+	}
+	withTopicPaths(r, refs...)
+	facets, err := CapsuleStore.CapsuleFacets(userID, filters, interval)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	q := ""
+	if filters != nil {
+		q = filters.Q
+	}
+	logger.LogEvent(logger.EventSearch, r, slog.String("action", "capsule_search"), slog.String("query", q), slog.Int("total", total))
+	utils.JSONFacetedResponse(w, http.StatusOK, "Search results", results, facets, page, limit, total)
+}
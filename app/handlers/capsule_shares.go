@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+// ListSharedWithMe godoc
+// @Summary Capsules shared with me
+// @Description List capsules other users shared with the caller directly or through a group, with the caller's permission on each
+// @Tags sharing
+// @Produce  json
+// @Security BearerAuth
+// @Param permission query string false "Only capsules with at least this permission" Enums(viewer, commenter, editor)
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Param sort query string false "Sort key (default relevance with q, otherwise updated_at)" Enums(created_at, updated_at, title, relevance)
+// @Param order query string false "asc or desc (default desc, asc for title)" Enums(asc, desc)
+// @Param cursor query string false "next_cursor from the previous page; replaces page"
+// @Param topic query string false "Filter by topic"
+// @Param tags query string false "Filter by tags (comma-separated, any spelling or alias)"
+// @Param q query string false "Full-text search in title, tags and content"
+// @Param render query string false "html to include each capsule's content rendered as sanitized HTML" Enums(html)
+// @Success 200 {object} models.PaginatedResponse "Paginated list: data, page, limit, total, next_cursor"
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/capsules/shared [get]
+func ListSharedWithMe(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	minPermission := r.URL.Query().Get("permission")
+	if minPermission != "" && !models.ValidSharePermission(minPermission) {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "permission", Message: "must be viewer, commenter or editor"})
+		return
+	}
+	opts, err := utils.ParseListOptions(r, models.SortCreatedAt, models.SortUpdatedAt, models.SortTitle, models.SortRelevance)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	render, ok := renderParam(w, r)
+	if !ok {
+		return
+	}
+
+	filters, err := parseCapsuleFilters(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	capsules, info, err := CapsuleStore.ListSharedWithUser(userID, minPermission, filters, opts)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrInvalidCursor) {
+			status = http.StatusBadRequest
+		}
+		utils.ErrorResponse(w, r, status, err)
+		return
+	}
+	if render {
+		refs := make([]*models.Capsule, len(capsules))
+		for i := range capsules {
+			refs[i] = &capsules[i]
+		}
+		withRendered(r, refs...)
+	}
+	utils.JSONListResponse(w, http.StatusOK, "Shared capsules fetched", capsules, opts, info)
+}
+
+// ListCapsuleShares godoc
+// @Summary List capsule shares
+// @Description List who a capsule is shared with (owner only)
+// @Tags sharing
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Capsule ID"
+// @Success 200 {array} models.CapsuleShare
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/capsules/{id}/shares [get]
+func ListCapsuleShares(w http.ResponseWriter, r *http.Request, capsuleID string) {
+	if _, ok := ownCapsule(w, r, capsuleID); !ok {
+		return
+	}
+	shares, err := CapsuleStore.ListShares(capsuleID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Shares fetched", shares)
+}
+
+// ShareCapsule godoc
+// @Summary Share capsule
+// @Description Grant a user (by ID or email) or a group you belong to viewer, commenter or editor access (owner only). Sharing again with the same grantee changes the permission. Sharing by email answers the same whether or not the email is registered, echoing the request instead of the share; unknown emails count toward a lockout of the owner (429).
+// @Tags sharing
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Capsule ID"
+// @Param input body models.CapsuleShareInput true "Grantee and permission"
+// @Success 200 {object} models.CapsuleShare
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Router /api/capsules/{id}/shares [post]
+func ShareCapsule(w http.ResponseWriter, r *http.Request, capsuleID string) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	if _, ok := ownCapsule(w, r, capsuleID); !ok {
+		return
+	}
+	var req models.CapsuleShareInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if !models.ValidSharePermission(req.Permission) {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "permission", Message: "must be viewer, commenter or editor"})
+		return
+	}
+
+	if req.GranteeType == "" {
+		req.GranteeType = models.GranteeUser
+	}
+	switch req.GranteeType {
+	case models.GranteeUser:
+		if email := strings.TrimSpace(req.Email); req.GranteeID == "" && email != "" {
+			shareByEmail(w, r, capsuleID, userID, email, req.Permission)
+			return
+		}
+		if req.GranteeID == "" {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "grantee_id", Message: "user ID or email is required"})
+			return
+		}
+		grantee, err := UserStore.FindByID(req.GranteeID)
+		if err != nil {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("user not found"))
+			return
+		}
+		if grantee.ID == userID {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("you already own this capsule"))
+			return
+		}
+		req.GranteeID = grantee.ID
+	case models.GranteeGroup:
+		// Only groups the owner belongs to, so capsules cannot be pushed into strangers' groups.
+		member, err := GroupStore.IsMember(req.GranteeID, userID)
+		if err != nil {
+			utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		if !member {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("group not found"))
+			return
+		}
+	default:
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "grantee_type", Message: "must be user or group"})
+		return
+	}
+
+	share, err := CapsuleStore.ShareCapsule(capsuleID, userID, req.GranteeType, req.GranteeID, req.Permission)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	logger.LogEvent(logger.EventCapsule, r, slog.String("action", "share"), slog.String("capsule_id", capsuleID),
+		slog.String("grantee_type", share.GranteeType), slog.String("grantee_id", share.GranteeID), slog.String("permission", share.Permission))
+	utils.JSONResponse(w, http.StatusOK, true, "Capsule shared", share)
+}
+
+// shareByEmail shares a capsule with the user registered under email. The response is the same
+// whether or not there is one, so sharing cannot be used to find out who has an account, and
+// unknown addresses count against the owner to limit how many can be tried.
+func shareByEmail(w http.ResponseWriter, r *http.Request, capsuleID, userID, email, permission string) {
+	if rejectIfLocked(w, r, "share_email", models.ThrottleKey(models.ThrottleScopeShareEmail, userID)) {
+		return
+	}
+	grantee, err := UserStore.FindByEmail(email)
+	switch {
+	case err != nil:
+		recordFailure(r, models.ThrottleScopeShareEmail, userID, throttleSettings.IP)
+		logger.LogEvent(logger.EventCapsule, r, slog.String("action", "share"), slog.String("capsule_id", capsuleID), slog.String("reason", "unknown_email"))
+	case grantee.ID == userID:
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("you already own this capsule"))
+		return
+	default:
+		share, err := CapsuleStore.ShareCapsule(capsuleID, userID, models.GranteeUser, grantee.ID, permission)
+		if err != nil {
+			utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		logger.LogEvent(logger.EventCapsule, r, slog.String("action", "share"), slog.String("capsule_id", capsuleID),
+			slog.String("grantee_type", share.GranteeType), slog.String("grantee_id", share.GranteeID), slog.String("permission", share.Permission))
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Capsule shared if the email belongs to a user",
+		models.CapsuleShareInput{GranteeType: models.GranteeUser, Email: email, Permission: permission})
+}
+
+// UnshareCapsule godoc
+// @Summary Remove capsule share
+// @Description Revoke a share (owner only)
+// @Tags sharing
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Capsule ID"
+// @Param shareId path string true "Share ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/capsules/{id}/shares/{shareId} [delete]
+func UnshareCapsule(w http.ResponseWriter, r *http.Request, capsuleID, shareID string) {
+	if !utils.AllowMethod(w, r, http.MethodDelete) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	if err := CapsuleStore.UnshareCapsule(capsuleID, userID, shareID); err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	logger.LogEvent(logger.EventCapsule, r, slog.String("action", "unshare"), slog.String("capsule_id", capsuleID), slog.String("share_id", shareID))
+	utils.JSONResponse(w, http.StatusOK, true, "Share removed", nil)
+}
+
+// CapsuleSharesHandler routes /api/capsules/{id}/shares[/{shareId}].
+func CapsuleSharesHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/capsules/"), "/")
+	id, rest, _ := strings.Cut(path, "/")
+	shareID := strings.TrimPrefix(strings.TrimPrefix(rest, "shares"), "/")
+	if shareID != "" {
+		UnshareCapsule(w, r, id, shareID)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		ListCapsuleShares(w, r, id)
+	case http.MethodPost:
+		ShareCapsule(w, r, id)
+	default:
+		utils.ErrorResponse(w, r, http.StatusMethodNotAllowed, nil)
+	}
+}
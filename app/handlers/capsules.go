@@ -5,74 +5,86 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"knowledge-capsule/app/middleware"
 	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
 	"knowledge-capsule/pkg/logger"
 	"knowledge-capsule/pkg/utils"
 )
 
-const maxTitleLen = 500
+const (
+	maxTitleLen   = 500
+	maxContentLen = 1 << 20
+)
 
 // GetCapsules godoc
 // @Summary Get capsules
-// @Description Get all capsules for the user (paginated, filterable)
+// @Description Get all capsules for the user (paginated, filterable). Each capsule carries the breadcrumb of its topic in topic_path.
 // @Tags capsules
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
 // @Param page query int false "Page number (default 1)"
 // @Param limit query int false "Items per page (default 20, max 100)"
-// @Param topic query string false "Filter by topic"
-// @Param tags query string false "Filter by tags (comma-separated)"
-// @Param q query string false "Search in title, content, tags"
+// @Param sort query string false "Sort key (default relevance with q, otherwise updated_at)" Enums(created_at, updated_at, title, relevance)
+// @Param order query string false "asc or desc (default desc, asc for title)" Enums(asc, desc)
+// @Param cursor query string false "next_cursor from the previous page; replaces page"
+// @Param topic query string false "Filter by topic name (case-insensitive)"
+// @Param topic_id query string false "Filter by topic ID"
+// @Param include_subtopics query bool false "With topic or topic_id, also match capsules in its subtopics"
+// @Param tags query string false "Filter by tags (comma-separated, any spelling or alias)"
+// @Param q query string false "Full-text search in title, tags and content; results are ordered by relevance"
 // @Param is_private query bool false "Filter by is_private"
-// @Success 200 {object} models.PaginatedResponse "Paginated list: data, page, limit, total"
+// @Param render query string false "html to include each capsule's content rendered as sanitized HTML, with its table of contents and code languages" Enums(html)
+// @Success 200 {object} models.PaginatedResponse "Paginated list: data, page, limit, total, next_cursor"
 // @Failure 400 {object} map[string]interface{}
 // @Router /api/capsules [get]
 func GetCapsules(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserContextKey).(string)
+	opts, err := utils.ParseListOptions(r, models.SortCreatedAt, models.SortUpdatedAt, models.SortTitle, models.SortRelevance)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	render, ok := renderParam(w, r)
+	if !ok {
+		return
+	}
 
-	var filters *models.CapsuleFilters
-	if topic := r.URL.Query().Get("topic"); topic != "" ||
-		r.URL.Query().Get("tags") != "" ||
-		r.URL.Query().Get("q") != "" ||
-		r.URL.Query().Get("is_private") != "" {
-		filters = &models.CapsuleFilters{
-			Topic: r.URL.Query().Get("topic"),
-			Q:     r.URL.Query().Get("q"),
-		}
-		if tags := r.URL.Query().Get("tags"); tags != "" {
-			for _, t := range strings.Split(tags, ",") {
-				if t = strings.TrimSpace(t); t != "" {
-					filters.Tags = append(filters.Tags, t)
-				}
-			}
-		}
-		if ip := r.URL.Query().Get("is_private"); ip == "true" {
-			t := true
-			filters.IsPrivate = &t
-		} else if ip == "false" {
-			t := false
-			filters.IsPrivate = &t
-		}
+	filters, err := parseCapsuleFilters(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
 	}
 
-	capsules, err := CapsuleStore.GetCapsulesByUser(userID, filters)
+	capsules, info, err := CapsuleStore.GetCapsulesByUser(userID, filters, opts)
 	if err != nil {
-		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrInvalidCursor) {
+			status = http.StatusBadRequest
+		}
+		utils.ErrorResponse(w, r, status, err)
 		return
 	}
-	page, limit := utils.ParsePagination(r)
-	paged, total := utils.SlicePage(capsules, page, limit)
-	logger.LogEvent(logger.EventCapsule, r, slog.String("action", "list"), slog.Int("count", len(paged)), slog.Int("total", total))
-	utils.JSONPaginatedResponse(w, http.StatusOK, "Capsules fetched", paged, page, limit, total)
+	refs := make([]*models.Capsule, len(capsules))
+	for i := range capsules {
+		refs[i] = &capsules[i]
+	}
+	withTopicPaths(r, refs...)
+	if render {
+		withRendered(r, refs...)
+	}
+	logger.LogEvent(logger.EventCapsule, r, slog.String("action", "list"), slog.Int("count", len(capsules)), slog.Int("total", info.Total))
+	utils.JSONListResponse(w, http.StatusOK, "Capsules fetched", capsules, opts, info)
 }
 
 // CreateCapsule godoc
 // @Summary Create capsule
-// @Description Create a new capsule
+// @Description Create a new capsule. The topic is set by topic_id or by name (matched case-insensitively); an unknown name is proposed as a new topic, pending admin approval. Content is limited to 1 MB.
 // @Tags capsules
 // @Accept  json
 // @Produce  json
@@ -102,12 +114,22 @@ func CreateCapsule(w http.ResponseWriter, r *http.Request) {
 		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "title", Message: "exceeds maximum length"})
 		return
 	}
+	if len(req.Content) > maxContentLen {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "content", Message: "exceeds maximum length of 1 MB"})
+		return
+	}
+	if req.ContentFormat != "" && !models.ValidContentFormat(req.ContentFormat) {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "content_format", Message: "must be plain or markdown"})
+		return
+	}
 
-	capsule, err := CapsuleStore.AddCapsule(userID, title, req.Content, req.Topic, []string(req.Tags), req.IsPrivate)
+	req.Title = title
+	capsule, err := CapsuleStore.AddCapsule(userID, req)
 	if err != nil {
 		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
 		return
 	}
+	withTopicPaths(r, capsule)
 	logger.LogEvent(logger.EventCapsule, r, slog.String("action", "create"), slog.String("capsule_id", capsule.ID), slog.String("title", title))
 	utils.JSONResponse(w, http.StatusCreated, true, "Capsule created", capsule)
 }
@@ -123,3 +145,65 @@ func CapsuleHandler(w http.ResponseWriter, r *http.Request) {
 		utils.ErrorResponse(w, r, http.StatusMethodNotAllowed, nil)
 	}
 }
+
+// parseCapsuleFilters reads the topic, topic_id, include_subtopics, tags, q, is_private,
+// created_from and created_before query params. It returns nil when none are set, and a
+// ValidationError for a date it cannot read.
+func parseCapsuleFilters(r *http.Request) (*models.CapsuleFilters, error) {
+	return capsuleFilters(r.URL.Query())
+}
+
+// capsuleFilters reads capsule filters from query values, as parseCapsuleFilters does.
+func capsuleFilters(query url.Values) (*models.CapsuleFilters, error) {
+	if query.Get("topic") == "" &&
+		query.Get("topic_id") == "" &&
+		query.Get("tags") == "" &&
+		query.Get("q") == "" &&
+		query.Get("is_private") == "" &&
+		query.Get("created_from") == "" &&
+		query.Get("created_before") == "" {
+		return nil, nil
+	}
+	filters := &models.CapsuleFilters{
+		Topic:            query.Get("topic"),
+		TopicID:          query.Get("topic_id"),
+		IncludeSubtopics: query.Get("include_subtopics") == "true",
+		Q:                query.Get("q"),
+	}
+	var err error
+	if filters.CreatedFrom, err = parseDateParam("created_from", query.Get("created_from")); err != nil {
+		return nil, err
+	}
+	if filters.CreatedBefore, err = parseDateParam("created_before", query.Get("created_before")); err != nil {
+		return nil, err
+	}
+	if tags := query.Get("tags"); tags != "" {
+		for _, t := range strings.Split(tags, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				filters.Tags = append(filters.Tags, t)
+			}
+		}
+	}
+	if ip := query.Get("is_private"); ip == "true" {
+		t := true
+		filters.IsPrivate = &t
+	} else if ip == "false" {
+		t := false
+		filters.IsPrivate = &t
+	}
+	return filters, nil
+}
+
+// parseDateParam reads the date (2006-01-02, UTC) or RFC 3339 timestamp in query param field.
+// It returns nil when val is empty, and a ValidationError naming field when it is invalid.
+func parseDateParam(field, val string) (*time.Time, error) {
+	if val == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, val); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, &utils.ValidationError{Field: field, Message: "must be a date (YYYY-MM-DD) or RFC 3339 timestamp"}
+}
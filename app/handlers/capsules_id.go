@@ -9,19 +9,22 @@ import (
 
 	"knowledge-capsule/app/middleware"
 	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
 	"knowledge-capsule/pkg/logger"
 	"knowledge-capsule/pkg/utils"
 )
 
 // GetCapsuleByID godoc
 // @Summary Get capsule by ID
-// @Description Get a single capsule (user must own it)
+// @Description Get a single capsule the user owns or that is shared with them, with the breadcrumb of its topic. Shared capsules include the caller's permission.
 // @Tags capsules
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
 // @Param id path string true "Capsule ID"
+// @Param render query string false "html to include the content rendered as sanitized HTML, with its table of contents and code languages" Enums(html)
 // @Success 200 {object} models.Capsule
+// @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Router /api/capsules/{id} [get]
 func GetCapsuleByID(w http.ResponseWriter, r *http.Request) {
@@ -31,21 +34,25 @@ func GetCapsuleByID(w http.ResponseWriter, r *http.Request) {
 		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("missing capsule id"))
 		return
 	}
-	capsule, err := CapsuleStore.FindByID(id)
+	render, ok := renderParam(w, r)
+	if !ok {
+		return
+	}
+	capsule, err := CapsuleStore.FindAccessible(id, userID)
 	if err != nil {
 		utils.ErrorResponse(w, r, http.StatusNotFound, err)
 		return
 	}
-	if capsule.UserID != userID {
-		utils.ErrorResponse(w, r, http.StatusNotFound, errors.New("capsule not found"))
-		return
+	withTopicPaths(r, capsule)
+	if render {
+		withRendered(r, capsule)
 	}
 	utils.JSONResponse(w, http.StatusOK, true, "Capsule fetched", capsule)
 }
 
 // UpdateCapsule godoc
 // @Summary Update capsule by ID
-// @Description Update a capsule (user must own it)
+// @Description Update a capsule (owner or editor; only the owner can change is_private). Each update is saved as a new revision. The topic is set by topic_id or by name; an unknown name is proposed as a new topic, pending admin approval. Content is limited to 1 MB.
 // @Tags capsules
 // @Accept  json
 // @Produce  json
@@ -82,20 +89,33 @@ func UpdateCapsule(w http.ResponseWriter, r *http.Request) {
 		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "title", Message: "exceeds maximum length"})
 		return
 	}
+	if len(req.Content) > maxContentLen {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "content", Message: "exceeds maximum length of 1 MB"})
+		return
+	}
+	if req.ContentFormat != "" && !models.ValidContentFormat(req.ContentFormat) {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "content_format", Message: "must be plain or markdown"})
+		return
+	}
 
 	updated := models.Capsule{CapsuleInput: req}
 	capsule, err := CapsuleStore.UpdateCapsule(id, userID, updated)
 	if err != nil {
-		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		status := http.StatusNotFound
+		if errors.Is(err, store.ErrTopicNotFound) {
+			status = http.StatusBadRequest
+		}
+		utils.ErrorResponse(w, r, status, err)
 		return
 	}
+	withTopicPaths(r, capsule)
 	logger.LogEvent(logger.EventCapsule, r, slog.String("action", "update"), slog.String("capsule_id", id))
 	utils.JSONResponse(w, http.StatusOK, true, "Capsule updated", capsule)
 }
 
 // DeleteCapsule godoc
 // @Summary Delete capsule by ID
-// @Description Delete a capsule (user must own it)
+// @Description Move a capsule to the trash (user must own it). It can be restored from /api/trash until it is purged.
 // @Tags capsules
 // @Accept  json
 // @Produce  json
@@ -116,11 +136,48 @@ func DeleteCapsule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	logger.LogEvent(logger.EventCapsule, r, slog.String("action", "delete"), slog.String("capsule_id", id))
-	utils.JSONResponse(w, http.StatusOK, true, "Capsule deleted", nil)
+	utils.JSONResponse(w, http.StatusOK, true, "Capsule moved to trash", nil)
 }
 
-// CapsuleByIDHandler routes GET/PUT/DELETE to the appropriate handler.
+// CapsuleByIDHandler routes GET/PUT/DELETE to the appropriate handler,
+// /api/capsules/shared to ListSharedWithMe, /api/capsules/search to SearchCapsules and /api/capsules/{id}/revisions|shares|links/...
+// to CapsuleRevisionsHandler, CapsuleSharesHandler and CapsuleLinksHandler, and /api/capsules/{id}/outlinks|backlinks to
+// CapsuleWikiLinksHandler.
 func CapsuleByIDHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/capsules/")
+	switch path {
+	case "shared":
+		ListSharedWithMe(w, r)
+		return
+	case "search":
+		SearchCapsules(w, r)
+		return
+	case "export":
+		ExportCapsules(w, r)
+		return
+	case "import":
+		ImportCapsules(w, r)
+		return
+	case "bulk":
+		BulkCapsules(w, r)
+		return
+	}
+	if _, rest, ok := strings.Cut(path, "/"); ok {
+		switch {
+		case rest == "revisions" || strings.HasPrefix(rest, "revisions/"):
+			CapsuleRevisionsHandler(w, r)
+		case rest == "shares" || strings.HasPrefix(rest, "shares/"):
+			CapsuleSharesHandler(w, r)
+		case rest == "links" || strings.HasPrefix(rest, "links/"):
+			CapsuleLinksHandler(w, r)
+		case rest == "outlinks" || rest == "backlinks":
+			CapsuleWikiLinksHandler(w, r)
+		default:
+			utils.ErrorResponse(w, r, http.StatusNotFound, nil)
+		}
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		GetCapsuleByID(w, r)
@@ -2,13 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"sync"
 
 	"knowledge-capsule/app/middleware"
 	"knowledge-capsule/app/models"
-	"knowledge-capsule/pkg/utils"
+	"knowledge-capsule/app/store"
 
 	"github.com/gorilla/websocket"
 )
@@ -51,6 +52,8 @@ type WSGetHistoryPayload struct {
 	UserID string `json:"user_id"`
 	Page   int    `json:"page"`
 	Limit  int    `json:"limit"`
+	Order  string `json:"order"`  // asc (default, oldest first) or desc
+	Cursor string `json:"cursor"` // next_cursor from the previous history page; replaces page
 }
 
 func sendWSResponse(conn *websocket.Conn, msgType string, payload interface{}) {
@@ -136,24 +139,41 @@ func ChatWebSocketHandler(w http.ResponseWriter, r *http.Request) {
 				sendWSResponse(conn, "error", map[string]string{"message": "user_id required"})
 				continue
 			}
-			page, limit := histPayload.Page, histPayload.Limit
-			if page < 1 {
-				page = 1
+			opts := models.ListOptions{
+				Page:   histPayload.Page,
+				Limit:  histPayload.Limit,
+				Order:  histPayload.Order,
+				Cursor: histPayload.Cursor,
 			}
-			if limit < 1 || limit > 100 {
-				limit = 20
+			if opts.Page < 1 {
+				opts.Page = 1
 			}
-			messages, err := MessageStore.GetMessagesBetweenUsers(userID, histPayload.UserID)
+			if opts.Limit < 1 || opts.Limit > 100 {
+				opts.Limit = 20
+			}
+			switch opts.Order {
+			case "":
+				opts.Order = models.OrderAsc
+			case models.OrderAsc, models.OrderDesc:
+			default:
+				sendWSResponse(conn, "error", map[string]string{"message": "order must be asc or desc"})
+				continue
+			}
+			messages, info, err := MessageStore.GetMessagesBetweenUsers(userID, histPayload.UserID, opts)
 			if err != nil {
-				sendWSResponse(conn, "error", map[string]string{"message": "failed to fetch history"})
+				message := "failed to fetch history"
+				if errors.Is(err, store.ErrInvalidCursor) {
+					message = err.Error()
+				}
+				sendWSResponse(conn, "error", map[string]string{"message": message})
 				continue
 			}
-			paged, total := utils.SlicePage(messages, page, limit)
 			sendWSResponse(conn, "history", map[string]interface{}{
-				"data":  paged,
-				"page":  page,
-				"limit": limit,
-				"total": total,
+				"data":        messages,
+				"page":        opts.Page,
+				"limit":       opts.Limit,
+				"total":       info.Total,
+				"next_cursor": info.NextCursor,
 			})
 
 		default:
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+// Graph size bounds
+const (
+	defaultGraphLimit = 100
+	maxGraphLimit     = 500
+	defaultGraphDepth = 1
+	maxGraphDepth     = 3
+)
+
+// GetGraph godoc
+// @Summary Knowledge graph
+// @Description Nodes for capsules (own and shared, outside the trash), their topics and tags, with weighted edges: link (capsule to capsule it links to, weight = number of links), shared_tags (weight = tags in common), same_topic, and topic / tag membership. Without capsule, the most recently updated capsules are included; with capsule, those within depth hops of it (a link either way, a shared tag or topic), nearest first. truncated is set when the limit cut capsules off. format=graphml or dot returns GraphML or Graphviz instead of JSON.
+// @Tags capsules
+// @Produce  json
+// @Produce  xml
+// @Produce  plain
+// @Security BearerAuth
+// @Param capsule query string false "Capsule ID to center a neighborhood on"
+// @Param depth query int false "Hops from capsule (default 1, max 3)"
+// @Param limit query int false "Most capsules in the graph (default 100, max 500)"
+// @Param format query string false "json (default), graphml or dot" Enums(json, graphml, dot)
+// @Success 200 {object} models.Graph
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/graph [get]
+func GetGraph(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	switch format {
+	case "":
+		format = models.GraphFormatJSON
+	case models.GraphFormatJSON, models.GraphFormatGraphML, models.GraphFormatDOT:
+	default:
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "format", Message: "must be json, graphml or dot"})
+		return
+	}
+	depth, ok := graphParam(w, r, "depth", defaultGraphDepth, maxGraphDepth)
+	if !ok {
+		return
+	}
+	limit, ok := graphParam(w, r, "limit", defaultGraphLimit, maxGraphLimit)
+	if !ok {
+		return
+	}
+
+	// A neighborhood can reach any visible capsule; the whole graph only needs the newest
+	// limit, plus one to tell whether it was cut.
+	rootID := query.Get("capsule")
+	scan := limit + 1
+	if rootID != "" {
+		if _, ok := readableCapsule(w, r, rootID); !ok {
+			return
+		}
+		scan = 0
+	}
+	capsules, err := CapsuleStore.GraphCapsules(userID, scan)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	links, err := CapsuleStore.GraphLinks(userID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	graph := utils.BuildGraph(capsules, links, rootID, depth, limit)
+
+	switch format {
+	case models.GraphFormatGraphML:
+		w.Header().Set("Content-Type", "application/graphml+xml; charset=utf-8")
+		err = utils.WriteGraphML(w, graph)
+	case models.GraphFormatDOT:
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		err = utils.WriteGraphDOT(w, graph)
+	default:
+		utils.JSONResponse(w, http.StatusOK, true, "Graph fetched", graph)
+	}
+	if err != nil {
+		logger.ErrorRequest(r, logger.EventCapsule, err, slog.String("action", "write_graph"), slog.String("format", format))
+	}
+}
+
+// graphParam reads an optional integer query parameter between 1 and max, responding 400
+// when it is out of range.
+func graphParam(w http.ResponseWriter, r *http.Request, name string, def, max int) (int, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > max {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: name, Message: "must be between 1 and " + strconv.Itoa(max)})
+		return 0, false
+	}
+	return n, true
+}
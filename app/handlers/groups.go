@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+const maxGroupNameLen = 100
+
+// ListGroups godoc
+// @Summary List my groups
+// @Description List the groups the caller owns, belongs to or is invited to. membership is "invited" until the caller accepts.
+// @Tags sharing
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} models.Group
+// @Router /api/groups [get]
+func ListGroups(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	groups, err := GroupStore.ListGroups(userID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Groups fetched", groups)
+}
+
+// CreateGroup godoc
+// @Summary Create group
+// @Description Create a group to share capsules with. The caller becomes its owner and first member.
+// @Tags sharing
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param input body models.GroupInput true "Group name"
+// @Success 201 {object} models.Group
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/groups [post]
+func CreateGroup(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	name, ok := parseGroupName(w, r)
+	if !ok {
+		return
+	}
+	group, err := GroupStore.CreateGroup(userID, name)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	logger.LogEvent(logger.EventUser, r, slog.String("action", "group_create"), slog.String("group_id", group.ID))
+	utils.JSONResponse(w, http.StatusCreated, true, "Group created", group)
+}
+
+// GetGroup godoc
+// @Summary Get group
+// @Description Get a group and its members (members only)
+// @Tags sharing
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/groups/{id} [get]
+func GetGroup(w http.ResponseWriter, r *http.Request, id string) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	if member, err := GroupStore.IsMember(id, userID); err != nil || !member {
+		utils.ErrorResponse(w, r, http.StatusNotFound, errors.New("group not found"))
+		return
+	}
+	group, err := GroupStore.FindByID(id)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	members, err := GroupStore.ListMembers(id)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Group fetched", map[string]interface{}{
+		"group":   group,
+		"members": members,
+	})
+}
+
+// RenameGroup godoc
+// @Summary Rename group
+// @Description Rename a group (owner only)
+// @Tags sharing
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Param input body models.GroupInput true "New name"
+// @Success 200 {object} models.Group
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/groups/{id} [patch]
+func RenameGroup(w http.ResponseWriter, r *http.Request, id string) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	name, ok := parseGroupName(w, r)
+	if !ok {
+		return
+	}
+	group, err := GroupStore.RenameGroup(id, userID, name)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Group renamed", group)
+}
+
+// DeleteGroup godoc
+// @Summary Delete group
+// @Description Delete a group (owner only). Capsule shares granted to the group are revoked.
+// @Tags sharing
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/groups/{id} [delete]
+func DeleteGroup(w http.ResponseWriter, r *http.Request, id string) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	if err := GroupStore.DeleteGroup(id, userID); err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	logger.LogEvent(logger.EventUser, r, slog.String("action", "group_delete"), slog.String("group_id", id))
+	utils.JSONResponse(w, http.StatusOK, true, "Group deleted", nil)
+}
+
+// AddGroupMember godoc
+// @Summary Invite group member
+// @Description Invite a user to a group by ID or email (owner only). The user joins once they accept.
+// @Description Inviting by email gives the same response whether or not the address has an account.
+// @Tags sharing
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Param input body models.GroupMemberInput true "User ID or email"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Router /api/groups/{id}/members [post]
+func AddGroupMember(w http.ResponseWriter, r *http.Request, id string) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	var req models.GroupMemberInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	// Ownership first, so only owners get to probe user IDs or emails.
+	group, err := GroupStore.FindByID(id)
+	if err != nil || group.OwnerID != userID {
+		utils.ErrorResponse(w, r, http.StatusNotFound, errors.New("group not found"))
+		return
+	}
+	if req.UserID == "" {
+		if email := strings.TrimSpace(req.Email); email != "" {
+			inviteByEmail(w, r, id, userID, email)
+			return
+		}
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "user_id", Message: "user ID or email is required"})
+		return
+	}
+	member, err := UserStore.FindByID(req.UserID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("user not found"))
+		return
+	}
+	if err := GroupStore.InviteMember(id, userID, member.ID); err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	logger.LogEvent(logger.EventUser, r, slog.String("action", "group_invite"), slog.String("group_id", id), slog.String("member_id", member.ID))
+	utils.JSONResponse(w, http.StatusOK, true, "Member invited", nil)
+}
+
+// inviteByEmail invites the user registered under email. Like shareByEmail, the response is
+// the same whether or not there is one, and unknown addresses count against the owner.
+func inviteByEmail(w http.ResponseWriter, r *http.Request, groupID, userID, email string) {
+	if rejectIfLocked(w, r, "group_invite", models.ThrottleKey(models.ThrottleScopeShareEmail, userID)) {
+		return
+	}
+	member, err := UserStore.FindByEmail(email)
+	if err != nil {
+		recordFailure(r, models.ThrottleScopeShareEmail, userID, throttleSettings.IP)
+		logger.LogEvent(logger.EventUser, r, slog.String("action", "group_invite"), slog.String("group_id", groupID), slog.String("reason", "unknown_email"))
+	} else {
+		if err := GroupStore.InviteMember(groupID, userID, member.ID); err != nil {
+			utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		logger.LogEvent(logger.EventUser, r, slog.String("action", "group_invite"), slog.String("group_id", groupID), slog.String("member_id", member.ID))
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Invitation sent if the email belongs to a user", models.GroupMemberInput{Email: email})
+}
+
+// AcceptGroupInvite godoc
+// @Summary Accept group invitation
+// @Description Join a group the caller was invited to. Decline by removing yourself from its members.
+// @Tags sharing
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/groups/{id}/accept [post]
+func AcceptGroupInvite(w http.ResponseWriter, r *http.Request, id string) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	if err := GroupStore.AcceptInvite(id, userID); err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	logger.LogEvent(logger.EventUser, r, slog.String("action", "group_join"), slog.String("group_id", id))
+	utils.JSONResponse(w, http.StatusOK, true, "Joined group", nil)
+}
+
+// RemoveGroupMember godoc
+// @Summary Remove group member
+// @Description Remove a member or invitation (owner), or leave the group or decline an invitation by removing yourself
+// @Tags sharing
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Param userId path string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/groups/{id}/members/{userId} [delete]
+func RemoveGroupMember(w http.ResponseWriter, r *http.Request, id, memberID string) {
+	if !utils.AllowMethod(w, r, http.MethodDelete) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	if err := GroupStore.RemoveMember(id, userID, memberID); err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	logger.LogEvent(logger.EventUser, r, slog.String("action", "group_remove_member"), slog.String("group_id", id), slog.String("member_id", memberID))
+	utils.JSONResponse(w, http.StatusOK, true, "Member removed", nil)
+}
+
+// GroupsHandler routes /api/groups, /api/groups/{id}, /api/groups/{id}/accept and
+// /api/groups/{id}/members[/{userId}].
+func GroupsHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/groups"), "/")
+	if path == "" {
+		switch r.Method {
+		case http.MethodGet:
+			ListGroups(w, r)
+		case http.MethodPost:
+			CreateGroup(w, r)
+		default:
+			utils.ErrorResponse(w, r, http.StatusMethodNotAllowed, nil)
+		}
+		return
+	}
+
+	id, rest, _ := strings.Cut(path, "/")
+	switch {
+	case rest == "":
+		switch r.Method {
+		case http.MethodGet:
+			GetGroup(w, r, id)
+		case http.MethodPatch:
+			RenameGroup(w, r, id)
+		case http.MethodDelete:
+			DeleteGroup(w, r, id)
+		default:
+			utils.ErrorResponse(w, r, http.StatusMethodNotAllowed, nil)
+		}
+	case rest == "accept":
+		AcceptGroupInvite(w, r, id)
+	case rest == "members":
+		AddGroupMember(w, r, id)
+	case strings.HasPrefix(rest, "members/"):
+		RemoveGroupMember(w, r, id, strings.TrimPrefix(rest, "members/"))
+	default:
+		utils.ErrorResponse(w, r, http.StatusNotFound, nil)
+	}
+}
+
+// parseGroupName decodes a GroupInput and validates the name.
+func parseGroupName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req models.GroupInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return "", false
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "name", Message: "cannot be empty"})
+		return "", false
+	}
+	if len(name) > maxGroupNameLen {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "name", Message: "exceeds maximum length"})
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
+)
+
+// fakeGroups holds one group owned by "owner" and records invitations.
+type fakeGroups struct {
+	store.GroupStore
+	invited []string
+}
+
+func (f *fakeGroups) FindByID(id string) (*models.Group, error) {
+	if id != "g1" {
+		return nil, errors.New("group not found")
+	}
+	return &models.Group{ID: "g1", OwnerID: "owner", Name: "Team"}, nil
+}
+
+func (f *fakeGroups) InviteMember(_, _, userID string) error {
+	f.invited = append(f.invited, userID)
+	return nil
+}
+
+func setupGroupInvites(t *testing.T) *fakeGroups {
+	t.Helper()
+	users, groups, throttles, settings := UserStore, GroupStore, ThrottleStore, throttleSettings
+	t.Cleanup(func() { UserStore, GroupStore, ThrottleStore, throttleSettings = users, groups, throttles, settings })
+	fake := &fakeGroups{}
+	UserStore = fakeUsers{users: map[string]*models.User{"bob": {ID: "bob", Email: "bob@example.com"}}}
+	GroupStore = fake
+	ThrottleStore = store.NewThrottleStore(newTestDB(t, &models.AuthThrottle{}))
+	throttleSettings = ThrottleSettings{
+		IP: models.ThrottlePolicy{MaxFailures: 2, BaseLockout: time.Minute, MaxLockout: time.Hour, Window: time.Hour},
+	}
+	return fake
+}
+
+// invite posts an invitation to g1 as userID.
+func invite(t *testing.T, userID string, body models.GroupMemberInput) (int, string) {
+	t.Helper()
+	w := serveJSON(t, asUser(userID, GroupsHandler), http.MethodPost, "/api/groups/g1/members", body)
+	return w.Code, w.Body.String()
+}
+
+func TestInviteByEmailIsUniform(t *testing.T) {
+	groups := setupGroupInvites(t)
+
+	knownStatus, known := invite(t, "owner", models.GroupMemberInput{Email: "bob@example.com"})
+	unknownStatus, unknown := invite(t, "owner", models.GroupMemberInput{Email: "nobody@example.com"})
+	if knownStatus != http.StatusOK || unknownStatus != knownStatus {
+		t.Fatalf("status known = %d, unknown = %d; want both 200", knownStatus, unknownStatus)
+	}
+	// The bodies may differ only in the echoed address.
+	if strings.ReplaceAll(known, "bob@example.com", "nobody@example.com") != unknown {
+		t.Errorf("responses differ:\nknown:   %s\nunknown: %s", known, unknown)
+	}
+	if len(groups.invited) != 1 || groups.invited[0] != "bob" {
+		t.Errorf("invited = %v, want only bob", groups.invited)
+	}
+}
+
+func TestInviteByEmailThrottlesUnknownAddresses(t *testing.T) {
+	groups := setupGroupInvites(t)
+	for i := 0; i < 2; i++ {
+		if status, _ := invite(t, "owner", models.GroupMemberInput{Email: "nobody@example.com"}); status != http.StatusOK {
+			t.Fatalf("invite %d: status = %d, want 200", i, status)
+		}
+	}
+	if status, _ := invite(t, "owner", models.GroupMemberInput{Email: "bob@example.com"}); status != http.StatusTooManyRequests {
+		t.Errorf("invite after repeated unknown addresses: status = %d, want 429", status)
+	}
+	if len(groups.invited) != 0 {
+		t.Errorf("invited = %v while locked", groups.invited)
+	}
+}
+
+func TestInviteRequiresOwner(t *testing.T) {
+	groups := setupGroupInvites(t)
+	for name, body := range map[string]models.GroupMemberInput{
+		"by email": {Email: "bob@example.com"},
+		"by ID":    {UserID: "bob"},
+		"unknown":  {Email: "nobody@example.com"},
+	} {
+		if status, _ := invite(t, "bob", body); status != http.StatusNotFound {
+			t.Errorf("non-owner invite %s: status = %d, want 404", name, status)
+		}
+	}
+	if len(groups.invited) != 0 {
+		t.Errorf("invited = %v by a non-owner", groups.invited)
+	}
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"knowledge-capsule/pkg/utils"
+)
+
+// JWKSHandler godoc
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens (RS256/EdDSA), including retired keys whose tokens are still valid. Served as a plain JWK Set, not the usual response envelope. Empty when tokens are signed with HS256.
+// @Tags auth
+// @Produce  json
+// @Success 200 {object} map[string][]utils.JWK
+// @Router /.well-known/jwks.json [get]
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(map[string][]utils.JWK{"keys": utils.PublicJWKS()})
+}
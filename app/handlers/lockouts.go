@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+// ListLockouts godoc
+// @Summary List login lockouts (admin only)
+// @Description List IP addresses and accounts with recent failed login or registration attempts. Keys look like ip:203.0.113.7, account:user@example.com or register:203.0.113.7.
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Param locked query bool false "Only entries that are currently locked out"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} models.PaginatedResponse
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/admin/lockouts [get]
+func ListLockouts(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	page, limit := utils.ParsePagination(r)
+	lockedOnly := r.URL.Query().Get("locked") == "true"
+
+	throttles, total, err := ThrottleStore.ListThrottles(lockedOnly, page, limit)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONPaginatedResponse(w, http.StatusOK, "Lockouts fetched", throttles, page, limit, total)
+}
+
+// ClearLockout godoc
+// @Summary Clear a lockout (admin only)
+// @Description Reset failed attempts and lift any lockout for an IP address or account
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Param key path string true "Lockout key, e.g. account:user@example.com"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/admin/lockouts/{key} [delete]
+func ClearLockout(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodDelete) {
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/api/admin/lockouts/")
+	if key == "" {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, nil)
+		return
+	}
+
+	cleared, err := ThrottleStore.Reset(key)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if !cleared {
+		utils.ErrorResponse(w, r, http.StatusNotFound, nil)
+		return
+	}
+	logger.LogEvent(logger.EventAdmin, r, slog.String("action", "clear_lockout"), slog.String("key", key))
+	utils.JSONResponse(w, http.StatusOK, true, "Lockout cleared", map[string]string{"key": key})
+}
+
+// LockoutsHandler routes /api/admin/lockouts and /api/admin/lockouts/{key}.
+func LockoutsHandler(w http.ResponseWriter, r *http.Request) {
+	if strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/lockouts"), "/") == "" {
+		ListLockouts(w, r)
+		return
+	}
+	ClearLockout(w, r)
+}
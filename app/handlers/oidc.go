@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/oidc"
+	"knowledge-capsule/pkg/utils"
+)
+
+const (
+	oidcStateCookie = "kc_oidc_state"
+	oidcStateTTL    = 10 * time.Minute
+)
+
+var (
+	oidcProviders       = map[string]*oidc.Provider{}
+	oidcSuccessRedirect string
+)
+
+// oidcState is kept in a signed cookie between /start and /callback.
+type oidcState struct {
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	Expires  int64  `json:"e"`
+}
+
+// InitOIDC registers the configured identity providers. When successRedirect is set, the
+// callback redirects there with the login response in the URL fragment instead of returning JSON.
+func InitOIDC(providers []*oidc.Provider, successRedirect string) {
+	oidcProviders = make(map[string]*oidc.Provider, len(providers))
+	for _, p := range providers {
+		oidcProviders[p.Name] = p
+	}
+	oidcSuccessRedirect = successRedirect
+}
+
+// ListOIDCProviders godoc
+// @Summary List SSO providers
+// @Description List the configured OIDC identity providers and their login URLs
+// @Tags auth
+// @Produce  json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/auth/oidc [get]
+func ListOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	providers := make([]map[string]string, 0, len(oidcProviders))
+	for name := range oidcProviders {
+		providers = append(providers, map[string]string{
+			"name":      name,
+			"login_url": "/api/auth/oidc/" + name + "/start",
+		})
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i]["name"] < providers[j]["name"] })
+	utils.JSONResponse(w, http.StatusOK, true, "SSO providers fetched", providers)
+}
+
+// StartOIDCLogin godoc
+// @Summary Start SSO login
+// @Description Redirect to the identity provider using the authorization code flow with PKCE
+// @Tags auth
+// @Param provider path string true "Provider name"
+// @Success 302
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/auth/oidc/{provider}/start [get]
+func StartOIDCLogin(w http.ResponseWriter, r *http.Request, provider *oidc.Provider) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	state, err := oidc.RandomString(24)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	nonce, err := oidc.RandomString(24)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state, nonce, challenge)
+	if err != nil {
+		logger.ErrorRequest(r, logger.EventAuth, err, slog.String("action", "oidc_start"), slog.String("provider", provider.Name))
+		utils.ErrorResponse(w, r, http.StatusBadGateway, errors.New("identity provider unavailable"))
+		return
+	}
+
+	payload, err := json.Marshal(oidcState{
+		Provider: provider.Name,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		Expires:  time.Now().Add(oidcStateTTL).Unix(),
+	})
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    utils.SignValue(base64.RawURLEncoding.EncodeToString(payload)),
+		Path:     "/api/auth/oidc/",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(provider.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback godoc
+// @Summary Complete SSO login
+// @Description Handle the identity provider redirect: verify state and the ID token, link or provision the account and start a session. Accounts with 2FA get a challenge token for /api/auth/login/2fa.
+// @Tags auth
+// @Produce  json
+// @Param provider path string true "Provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/auth/oidc/{provider}/callback [get]
+func OIDCCallback(w http.ResponseWriter, r *http.Request, provider *oidc.Provider) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	state, err := readOIDCState(r)
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/api/auth/oidc/", MaxAge: -1})
+	if err != nil || state.Provider != provider.Name || state.State != r.URL.Query().Get("state") {
+		utils.ErrorResponse(w, r, http.StatusUnauthorized, errors.New("invalid or expired login state"))
+		return
+	}
+	if idpErr := r.URL.Query().Get("error"); idpErr != "" {
+		logger.LogEvent(logger.EventAuth, r, slog.String("action", "oidc_login_failed"), slog.String("provider", provider.Name), slog.String("reason", idpErr))
+		utils.ErrorResponse(w, r, http.StatusUnauthorized, fmt.Errorf("identity provider error: %s", idpErr))
+		return
+	}
+
+	claims, err := provider.Exchange(r.Context(), r.URL.Query().Get("code"), state.Verifier, state.Nonce)
+	if err != nil {
+		logger.LogEvent(logger.EventAuth, r, slog.String("action", "oidc_login_failed"), slog.String("provider", provider.Name), slog.String("reason", err.Error()))
+		utils.ErrorResponse(w, r, http.StatusUnauthorized, errors.New("identity provider login failed"))
+		return
+	}
+
+	user, status, err := resolveOIDCUser(r, provider, claims)
+	if err != nil {
+		logger.LogEvent(logger.EventAuth, r, slog.String("action", "oidc_login_failed"), slog.String("provider", provider.Name), slog.String("subject", claims.Subject), slog.String("reason", err.Error()))
+		utils.ErrorResponse(w, r, status, err)
+		return
+	}
+
+	message, data, err := completeLogin(r, user)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if oidcSuccessRedirect != "" {
+		http.Redirect(w, r, oidcSuccessRedirect+"#"+fragmentValues(data).Encode(), http.StatusFound)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, message, data)
+}
+
+// OIDCHandler routes /api/auth/oidc and /api/auth/oidc/{provider}/{start|callback}.
+func OIDCHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/auth/oidc")
+	path = strings.Trim(path, "/")
+	if path == "" {
+		ListOIDCProviders(w, r)
+		return
+	}
+
+	name, action, _ := strings.Cut(path, "/")
+	provider, ok := oidcProviders[name]
+	if !ok {
+		utils.ErrorResponse(w, r, http.StatusNotFound, errors.New("unknown SSO provider"))
+		return
+	}
+	switch action {
+	case "start":
+		StartOIDCLogin(w, r, provider)
+	case "callback":
+		OIDCCallback(w, r, provider)
+	default:
+		utils.ErrorResponse(w, r, http.StatusNotFound, nil)
+	}
+}
+
+// resolveOIDCUser finds the user for an ID token: by linked identity, else by email (linking
+// it when both the provider and the account have verified the address), else by
+// provisioning a new account when the provider allows it. Group mapping is applied when
+// the provider has admin groups configured.
+func resolveOIDCUser(r *http.Request, provider *oidc.Provider, claims *oidc.Claims) (*models.User, int, error) {
+	var user *models.User
+	identity, err := IdentityStore.FindIdentity(provider.Name, claims.Subject)
+	if err == nil {
+		if user, err = UserStore.FindByID(identity.UserID); err != nil {
+			return nil, http.StatusUnauthorized, errors.New("linked account no longer exists")
+		}
+		if err := IdentityStore.RecordLogin(identity.ID, claims.Email); err != nil {
+			logger.ErrorRequest(r, logger.EventAuth, err, slog.String("action", "oidc_record_login"))
+		}
+	} else {
+		if claims.Email == "" || !claims.EmailVerified {
+			return nil, http.StatusForbidden, errors.New("identity provider did not return a verified email")
+		}
+
+		if user, err = UserStore.FindByEmail(claims.Email); err == nil {
+			// Anyone can register an address they do not own; linking that account would let
+			// its creator keep a password on the real owner's SSO login.
+			if user.EmailVerifiedAt == nil {
+				return nil, http.StatusForbidden, errors.New("an account with this email exists but its email is not verified; sign in with your password and verify it first")
+			}
+			if err := IdentityStore.LinkIdentity(user.ID, provider.Name, claims.Subject, claims.Email); err != nil {
+				return nil, http.StatusInternalServerError, err
+			}
+			logger.LogEvent(logger.EventAuth, r, slog.String("action", "oidc_link"), slog.String("provider", provider.Name), slog.String("user_id", user.ID))
+		} else {
+			if !provider.AutoProvision {
+				return nil, http.StatusForbidden, errors.New("no account exists for this email")
+			}
+			name := claims.Name
+			if name == "" {
+				name, _, _ = strings.Cut(claims.Email, "@")
+			}
+			if user, err = IdentityStore.ProvisionUser(name, claims.Email, provider.Name, claims.Subject); err != nil {
+				return nil, http.StatusInternalServerError, err
+			}
+			logger.LogEvent(logger.EventAuth, r, slog.String("action", "oidc_provision"), slog.String("provider", provider.Name), slog.String("user_id", user.ID), slog.String("email", user.Email))
+		}
+	}
+
+	// Superadmins are managed locally and never demoted by group mapping.
+	if len(provider.AdminGroups) > 0 && user.Role != models.RoleSuperAdmin {
+		role := models.RoleUser
+		if provider.IsAdmin(claims.Groups) {
+			role = models.RoleAdmin
+		}
+		if role != user.Role {
+			if err := UserStore.UpdateUserRole(user.ID, role); err != nil {
+				return nil, http.StatusInternalServerError, err
+			}
+			logger.LogEvent(logger.EventAuth, r, slog.String("action", "oidc_role_sync"), slog.String("user_id", user.ID), slog.String("from", user.Role), slog.String("to", role))
+			user.Role = role
+		}
+	}
+	return user, http.StatusOK, nil
+}
+
+// readOIDCState verifies and decodes the state cookie.
+func readOIDCState(r *http.Request) (*oidcState, error) {
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := utils.VerifySignedValue(cookie.Value)
+	if !ok {
+		return nil, errors.New("invalid state signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var state oidcState
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return nil, err
+	}
+	if time.Now().Unix() > state.Expires {
+		return nil, errors.New("state expired")
+	}
+	return &state, nil
+}
+
+// fragmentValues flattens a login response for the success redirect fragment.
+func fragmentValues(data map[string]interface{}) url.Values {
+	values := url.Values{}
+	for k, v := range data {
+		switch v := v.(type) {
+		case time.Time:
+			values.Set(k, v.Format(time.RFC3339))
+		default:
+			values.Set(k, fmt.Sprint(v))
+		}
+	}
+	return values
+}
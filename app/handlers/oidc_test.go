@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
+	"knowledge-capsule/pkg/oidc"
+)
+
+// fakeIdentities records links in memory; other methods are not used.
+type fakeIdentities struct {
+	store.IdentityStore
+	linked map[string]string // subject -> user ID
+}
+
+func (f *fakeIdentities) FindIdentity(_, subject string) (*models.UserIdentity, error) {
+	if userID, ok := f.linked[subject]; ok {
+		return &models.UserIdentity{ID: "i-" + subject, UserID: userID, Subject: subject}, nil
+	}
+	return nil, errors.New("identity not found")
+}
+
+func (f *fakeIdentities) LinkIdentity(userID, _, subject, _ string) error {
+	f.linked[subject] = userID
+	return nil
+}
+
+func (f *fakeIdentities) RecordLogin(string, string) error { return nil }
+
+func TestResolveOIDCUserLinking(t *testing.T) {
+	verifiedAt := time.Now()
+	users, identities := UserStore, IdentityStore
+	t.Cleanup(func() { UserStore, IdentityStore = users, identities })
+	UserStore = fakeUsers{users: map[string]*models.User{
+		"verified":   {ID: "verified", Email: "ada@example.com", EmailVerifiedAt: &verifiedAt},
+		"unverified": {ID: "unverified", Email: "grace@example.com"},
+	}}
+	links := &fakeIdentities{linked: map[string]string{"linked-sub": "unverified"}}
+	IdentityStore = links
+	provider := oidc.NewProvider(oidc.Config{Name: "test"})
+	r := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/test/callback", nil)
+
+	tests := []struct {
+		name       string
+		claims     oidc.Claims
+		wantUser   string
+		wantStatus int
+	}{
+		{"verified account", oidc.Claims{Subject: "sub-1", Email: "ada@example.com", EmailVerified: true}, "verified", http.StatusOK},
+		{"unverified account", oidc.Claims{Subject: "sub-2", Email: "grace@example.com", EmailVerified: true}, "", http.StatusForbidden},
+		{"unverified provider email", oidc.Claims{Subject: "sub-3", Email: "ada@example.com"}, "", http.StatusForbidden},
+		{"already linked", oidc.Claims{Subject: "linked-sub", Email: "someone@example.com"}, "unverified", http.StatusOK},
+		{"no account", oidc.Claims{Subject: "sub-4", Email: "new@example.com", EmailVerified: true}, "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, status, err := resolveOIDCUser(r, provider, &tt.claims)
+			if status != tt.wantStatus {
+				t.Fatalf("resolveOIDCUser() status = %d, %v; want %d", status, err, tt.wantStatus)
+			}
+			if tt.wantUser != "" && (user == nil || user.ID != tt.wantUser) {
+				t.Errorf("resolveOIDCUser() user = %+v, want %s", user, tt.wantUser)
+			}
+			if tt.wantUser == "" && links.linked[tt.claims.Subject] != "" {
+				t.Errorf("resolveOIDCUser() linked %s to %s", tt.claims.Subject, links.linked[tt.claims.Subject])
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+// linkPasswordHeader lets API clients open a password-protected link with GET.
+const linkPasswordHeader = "X-Link-Password"
+
+// publicPage is the read-only HTML view served at /p/{slug}.
+var publicPage = template.Must(template.New("public").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{if .Capsule}}{{.Capsule.Title}}{{else}}Knowledge Capsule{{end}}</title>
+<style>
+body{font-family:system-ui,sans-serif;max-width:46rem;margin:2rem auto;padding:0 1rem;line-height:1.6;color:#222}
+.meta{color:#666;font-size:.9rem}.tag{background:#eee;border-radius:.3rem;padding:0 .4rem;margin-right:.3rem}
+.content{white-space:pre-wrap;margin-top:1.5rem}.rendered{margin-top:1.5rem}.error{color:#b00}
+pre{background:#f5f5f5;padding:.8rem;overflow-x:auto}
+</style>
+</head>
+<body>
+{{if .Capsule}}
+<h1>{{.Capsule.Title}}</h1>
+<p class="meta">By {{.Capsule.Author}}{{if .Capsule.Topic}} · {{.Capsule.Topic}}{{end}} · updated {{.Capsule.UpdatedAt.Format "Jan 2, 2006"}} · {{.Capsule.ViewCount}} views</p>
+<p>{{range .Capsule.Tags}}<span class="tag">{{.}}</span>{{end}}</p>
+{{if .ContentHTML}}<div class="rendered">{{.ContentHTML}}</div>{{else}}<div class="content">{{.Capsule.Content}}</div>{{end}}
+{{else if .NeedPassword}}
+<h1>Password required</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post">
+<input type="password" name="password" autofocus required>
+<button type="submit">Open</button>
+</form>
+{{else}}
+<h1>{{.Error}}</h1>
+{{end}}
+</body>
+</html>
+`))
+
+type publicPageData struct {
+	Capsule      *models.PublicCapsule
+	ContentHTML  template.HTML
+	NeedPassword bool
+	Error        string
+}
+
+// PublicCapsulePage godoc
+// @Summary Open public link
+// @Description Read-only view of a capsule through a public link, without authentication. Returns HTML, or JSON when the Accept header asks for application/json. Markdown capsules are shown rendered. Password-protected links take the password as a form or JSON field with POST, or in the X-Link-Password header.
+// @Tags public
+// @Produce  html
+// @Produce  json
+// @Param slug path string true "Link slug"
+// @Param render query string false "html to include the rendered content in JSON" Enums(html)
+// @Success 200 {object} models.PublicCapsule
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Router /p/{slug} [get]
+func PublicCapsulePage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		utils.ErrorResponse(w, r, http.StatusMethodNotAllowed, nil)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'")
+	render, ok := renderParam(w, r)
+	if !ok {
+		return
+	}
+
+	slug := strings.Trim(strings.TrimPrefix(r.URL.Path, "/p/"), "/")
+	link, capsule, err := LinkStore.ResolveLink(slug)
+	if err != nil {
+		renderPublic(w, r, http.StatusNotFound, publicPageData{Error: "Link not found"})
+		return
+	}
+	if link.Expired() {
+		renderPublic(w, r, http.StatusGone, publicPageData{Error: "This link has expired"})
+		return
+	}
+
+	if link.PasswordProtected {
+		// Wrong passwords are limited per client, and per link so that guesses spread over many
+		// addresses still lock out.
+		ipSubject := link.ID + "|" + utils.ClientIP(r)
+		if rejectIfLocked(w, r, "link_password",
+			models.ThrottleKey(models.ThrottleScopeLink, ipSubject),
+			models.ThrottleKey(models.ThrottleScopeLink, link.ID)) {
+			return
+		}
+		password := linkPassword(w, r)
+		if password == "" {
+			renderPublic(w, r, http.StatusUnauthorized, publicPageData{NeedPassword: true})
+			return
+		}
+		if !utils.CheckPassword(password, link.PasswordHash) {
+			logger.LogEvent(logger.EventCapsule, r, slog.String("action", "link_password_failed"), slog.String("link_id", link.ID))
+			recordFailure(r, models.ThrottleScopeLink, ipSubject, throttleSettings.Account)
+			recordFailure(r, models.ThrottleScopeLink, link.ID, throttleSettings.IP)
+			renderPublic(w, r, http.StatusUnauthorized, publicPageData{NeedPassword: true, Error: "Wrong password"})
+			return
+		}
+	}
+
+	if err := LinkStore.RecordView(link); err != nil {
+		logger.ErrorRequest(r, logger.EventCapsule, err, slog.String("action", "record_view"), slog.String("link_id", link.ID))
+	} else {
+		capsule.ViewCount++
+	}
+	if render {
+		withPublicRendered(r, capsule)
+	}
+	renderPublic(w, r, http.StatusOK, publicPageData{Capsule: capsule})
+}
+
+// Explore godoc
+// @Summary Explore public capsules
+// @Description List non-private capsules from all users, without authentication. Items carry an excerpt instead of the full content.
+// @Tags public
+// @Produce  json
+// @Param topic query string false "Only capsules in this topic"
+// @Param tag query string false "Only capsules with this tag"
+// @Param q query string false "Full-text search in title, tags and content"
+// @Param sort query string false "recent or popular; defaults to relevance with q, otherwise recent" Enums(recent, popular)
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {object} models.PaginatedResponse "Paginated list: data, page, limit, total"
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/explore [get]
+func Explore(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	q := r.URL.Query()
+	filters := &models.ExploreFilters{
+		Topic: strings.TrimSpace(q.Get("topic")),
+		Tag:   strings.TrimSpace(q.Get("tag")),
+		Q:     strings.TrimSpace(q.Get("q")),
+		Sort:  q.Get("sort"),
+	}
+	if filters.Sort != "" && filters.Sort != "recent" && filters.Sort != "popular" {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "sort", Message: "must be recent or popular"})
+		return
+	}
+
+	page, limit := utils.ParsePagination(r)
+	capsules, total, err := CapsuleStore.ListPublicCapsules(filters, page, limit)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONPaginatedResponse(w, http.StatusOK, "Public capsules fetched", capsules, page, limit, total)
+}
+
+// GetExploreCapsule godoc
+// @Summary Read public capsule
+// @Description Read a non-private capsule without authentication. Each read counts as a view.
+// @Tags public
+// @Produce  json
+// @Param id path string true "Capsule ID"
+// @Param render query string false "html to include the content rendered as sanitized HTML, with its table of contents and code languages" Enums(html)
+// @Success 200 {object} models.PublicCapsule
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/explore/{id} [get]
+func GetExploreCapsule(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	render, ok := renderParam(w, r)
+	if !ok {
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/explore/"), "/")
+	capsule, err := CapsuleStore.GetPublicCapsule(id)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, errors.New("capsule not found"))
+		return
+	}
+	if render {
+		withPublicRendered(r, capsule)
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Capsule fetched", capsule)
+}
+
+// ExploreHandler routes /api/explore and /api/explore/{id}.
+func ExploreHandler(w http.ResponseWriter, r *http.Request) {
+	if strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/explore"), "/") == "" {
+		Explore(w, r)
+		return
+	}
+	GetExploreCapsule(w, r)
+}
+
+// linkPassword reads a link password from the header, a POSTed form or a JSON body.
+func linkPassword(w http.ResponseWriter, r *http.Request) string {
+	if p := r.Header.Get(linkPasswordHeader); p != "" {
+		return p
+	}
+	if r.Method != http.MethodPost {
+		return ""
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body struct {
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&body); err != nil {
+			return ""
+		}
+		return body.Password
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	return r.PostFormValue("password")
+}
+
+// renderPublic writes a public page as JSON for API clients and HTML for browsers. The HTML
+// page shows Markdown capsules rendered.
+func renderPublic(w http.ResponseWriter, r *http.Request, status int, data publicPageData) {
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		switch {
+		case data.Capsule != nil:
+			utils.JSONResponse(w, status, true, "Capsule fetched", data.Capsule)
+		case data.NeedPassword && data.Error == "":
+			utils.ErrorResponse(w, r, status, errors.New("password required"))
+		default:
+			utils.ErrorResponse(w, r, status, errors.New(strings.ToLower(data.Error)))
+		}
+		return
+	}
+	if c := data.Capsule; c != nil && c.ContentFormat == models.ContentMarkdown {
+		if c.Rendered == nil {
+			withPublicRendered(r, c)
+		}
+		if c.Rendered != nil {
+			// Rendered HTML has been through the sanitizer.
+			data.ContentHTML = template.HTML(c.Rendered.HTML)
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := publicPage.Execute(w, data); err != nil {
+		logger.ErrorRequest(r, logger.EventCapsule, err, slog.String("action", "render_public"))
+	}
+}
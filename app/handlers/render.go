@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/markdown"
+	"knowledge-capsule/pkg/utils"
+)
+
+// renderParam reports whether the request asks for rendered content with ?render=html. It
+// responds 400 for other values.
+func renderParam(w http.ResponseWriter, r *http.Request) (render, ok bool) {
+	switch r.URL.Query().Get("render") {
+	case "":
+		return false, true
+	case models.RenderHTML:
+		return true, true
+	default:
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "render", Message: "must be html"})
+		return false, false
+	}
+}
+
+// withRendered fills in the rendered content of capsules, from the render cache while it is
+// current. A failed render is logged and only leaves it out.
+func withRendered(r *http.Request, capsules ...*models.Capsule) {
+	ids := make([]string, len(capsules))
+	for i, c := range capsules {
+		ids[i] = c.ID
+	}
+	cached, err := CapsuleStore.CachedRenders(ids)
+	if err != nil {
+		logger.ErrorRequest(r, logger.EventCapsule, err, slog.String("action", "cached_renders"))
+		cached = map[string]models.CapsuleRender{}
+	}
+	for _, c := range capsules {
+		c.Rendered = renderedContent(r, c.ID, c.ContentFormat, c.Content, cached)
+	}
+}
+
+// renderedContent returns a capsule's content rendered to HTML, reusing its cached render when
+// the content has not changed since, and caching a new render otherwise.
+func renderedContent(r *http.Request, id, format, content string, cached map[string]models.CapsuleRender) *models.RenderedContent {
+	hash := markdown.Hash(format, content)
+	if render, ok := cached[id]; ok && render.Hash == hash {
+		return &render.Output
+	}
+	out, err := markdown.Render(format, content)
+	if err != nil {
+		logger.ErrorRequest(r, logger.EventCapsule, err, slog.String("action", "render"), slog.String("capsule_id", id))
+		return nil
+	}
+	if err := CapsuleStore.SaveRender(&models.CapsuleRender{CapsuleID: id, Hash: hash, Output: *out}); err != nil {
+		logger.ErrorRequest(r, logger.EventCapsule, err, slog.String("action", "save_render"), slog.String("capsule_id", id))
+	}
+	return out
+}
+
+// withPublicRendered fills in the rendered content of a public capsule.
+func withPublicRendered(r *http.Request, capsule *models.PublicCapsule) {
+	cached, err := CapsuleStore.CachedRenders([]string{capsule.ID})
+	if err != nil {
+		logger.ErrorRequest(r, logger.EventCapsule, err, slog.String("action", "cached_renders"))
+	}
+	capsule.Rendered = renderedContent(r, capsule.ID, capsule.ContentFormat, capsule.Content, cached)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/utils"
+)
+
+// SearchSuggest godoc
+// @Summary Search suggestions
+// @Description Autocomplete as the user types: capsule titles and tags from the caller's capsules, and topic names, ranked together. Candidates that start with the typed text rank first; misspelled input still finds close matches. Topics are left out for API tokens without topics:read.
+// @Tags search
+// @Produce  json
+// @Security BearerAuth
+// @Param q query string true "Partly typed query"
+// @Param limit query int false "Max suggestions (default 10, max 50)"
+// @Success 200 {array} models.Suggestion
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/search/suggest [get]
+func SearchSuggest(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "q", Message: "cannot be empty"})
+		return
+	}
+	limit := 10
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 50 {
+			limit = v
+		}
+	}
+
+	titles, err := CapsuleStore.SuggestTitles(userID, q, limit)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	tags, err := CapsuleStore.SuggestTags(userID, q, limit)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	suggestions := append(titles, tags...)
+	if middleware.HasScope(r, models.ScopeTopicsRead) {
+		topics, err := TopicStore.SuggestTopics(q, limit)
+		if err != nil {
+			utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		suggestions = append(suggestions, topics...)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].Score > suggestions[j].Score })
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Suggestions fetched", suggestions)
+}
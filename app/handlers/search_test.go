@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
+)
+
+// fakeSuggestions serves fixed title and tag suggestions and records the limit asked for.
+type fakeSuggestions struct {
+	store.CapsuleStore
+	limit int
+}
+
+func (f *fakeSuggestions) SuggestTitles(_, _ string, limit int) ([]models.Suggestion, error) {
+	f.limit = limit
+	return []models.Suggestion{
+		{Type: models.SuggestionTitle, Text: "Go interfaces", CapsuleID: "c1", Score: 1.8},
+		{Type: models.SuggestionTitle, Text: "Gophers", CapsuleID: "c2", Score: 0.4},
+	}, nil
+}
+
+func (f *fakeSuggestions) SuggestTags(string, string, int) ([]models.Suggestion, error) {
+	return []models.Suggestion{{Type: models.SuggestionTag, Text: "golang", Score: 1.2}}, nil
+}
+
+type fakeTopicSuggestions struct {
+	store.TopicStore
+}
+
+func (fakeTopicSuggestions) SuggestTopics(string, int) ([]models.Suggestion, error) {
+	return []models.Suggestion{{Type: models.SuggestionTopic, Text: "Go", Score: 1.5}}, nil
+}
+
+func suggest(t *testing.T, handler http.HandlerFunc, query string) (int, []models.Suggestion) {
+	t.Helper()
+	w := serveJSON(t, handler, http.MethodGet, "/api/search/suggest?"+query, nil)
+	var body struct {
+		Data []models.Suggestion `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body.Data
+}
+
+func TestSearchSuggest(t *testing.T) {
+	capsules, topics := CapsuleStore, TopicStore
+	t.Cleanup(func() { CapsuleStore, TopicStore = capsules, topics })
+	fake := &fakeSuggestions{}
+	CapsuleStore, TopicStore = fake, fakeTopicSuggestions{}
+	handler := asUser("u1", SearchSuggest)
+
+	status, got := suggest(t, handler, "q=go")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	want := []string{"Go interfaces", "Go", "golang", "Gophers"}
+	if len(got) != len(want) {
+		t.Fatalf("suggestions = %+v, want %v", got, want)
+	}
+	for i, s := range got {
+		if s.Text != want[i] {
+			t.Errorf("suggestion %d = %q, want %q (ranked by score across kinds)", i, s.Text, want[i])
+		}
+	}
+
+	if _, got := suggest(t, handler, "q=go&limit=2"); len(got) != 2 || fake.limit != 2 {
+		t.Errorf("limit=2: %d suggestions, store limit %d", len(got), fake.limit)
+	}
+	if suggest(t, handler, "q=go&limit=500"); fake.limit != 10 {
+		t.Errorf("limit=500: store limit = %d, want the default 10", fake.limit)
+	}
+	if status, _ := suggest(t, handler, "q=%20"); status != http.StatusBadRequest {
+		t.Errorf("blank q: status = %d, want 400", status)
+	}
+
+	// An API token without topics:read sees no topic names.
+	scoped := func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r.WithContext(context.WithValue(r.Context(), middleware.ScopesContextKey, models.Scopes{models.ScopeCapsulesRead})))
+	}
+	_, got = suggest(t, scoped, "q=go")
+	for _, s := range got {
+		if s.Type == models.SuggestionTopic {
+			t.Errorf("token without topics:read got topic %q", s.Text)
+		}
+	}
+}
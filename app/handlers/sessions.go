@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+// ListSessions godoc
+// @Summary List my sessions
+// @Description List the authenticated user's active login sessions
+// @Tags users
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} models.Session
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/users/me/sessions [get]
+func ListSessions(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	currentID, _ := r.Context().Value(middleware.SessionContextKey).(string)
+
+	sessions, err := SessionStore.ListActiveSessions(userID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].FamilyID == currentID
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Sessions fetched", sessions)
+}
+
+// RevokeOtherSessions godoc
+// @Summary Revoke my other sessions
+// @Description Revoke every session of the authenticated user except the current one
+// @Tags users
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/users/me/sessions [delete]
+func RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodDelete) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	currentID, _ := r.Context().Value(middleware.SessionContextKey).(string)
+
+	revoked, err := SessionStore.RevokeUserSessions(userID, currentID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "revoke_other_sessions"), slog.Int("count", revoked))
+	utils.JSONResponse(w, http.StatusOK, true, "Sessions revoked", map[string]int{"revoked": revoked})
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Revoke one of the authenticated user's sessions
+// @Tags users
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/users/me/sessions/{id} [delete]
+func RevokeSession(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodDelete) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	id := strings.TrimPrefix(r.URL.Path, "/api/users/me/sessions/")
+	if id == "" {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("missing session id"))
+		return
+	}
+
+	if err := SessionStore.RevokeSession(id, userID); err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "revoke_session"), slog.String("session_id", id))
+	utils.JSONResponse(w, http.StatusOK, true, "Session revoked", nil)
+}
+
+// SessionsHandler routes /api/users/me/sessions (GET, DELETE) and /api/users/me/sessions/:id (DELETE).
+func SessionsHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/users/me/sessions")
+	path = strings.TrimPrefix(path, "/")
+
+	if path == "" {
+		switch r.Method {
+		case http.MethodGet:
+			ListSessions(w, r)
+		case http.MethodDelete:
+			RevokeOtherSessions(w, r)
+		default:
+			utils.ErrorResponse(w, r, http.StatusMethodNotAllowed, nil)
+		}
+		return
+	}
+	RevokeSession(w, r)
+}
@@ -7,10 +7,20 @@ import (
 )
 
 var (
-	UserStore    store.UserStore
-	CapsuleStore store.CapsuleStore
-	TopicStore   store.TopicStore
-	MessageStore store.MessageStore
+	UserStore      store.UserStore
+	CapsuleStore   store.CapsuleStore
+	TopicStore     store.TopicStore
+	TagStore       store.TagStore
+	GroupStore     store.GroupStore
+	LinkStore      store.CapsuleLinkStore
+	MessageStore   store.MessageStore
+	SessionStore   store.SessionStore
+	TokenStore     store.OneTimeTokenStore
+	APITokenStore  store.APITokenStore
+	TwoFactorStore store.TwoFactorStore
+	SettingsStore  store.SettingsStore
+	IdentityStore  store.IdentityStore
+	ThrottleStore  store.ThrottleStore
 )
 
 // InitStores initializes all stores with the database connection.
@@ -18,5 +28,15 @@ func InitStores(db *gorm.DB) {
 	UserStore = store.NewUserStore(db)
 	CapsuleStore = store.NewCapsuleStore(db)
 	TopicStore = store.NewTopicStore(db)
+	TagStore = store.NewTagStore(db)
+	GroupStore = store.NewGroupStore(db)
+	LinkStore = store.NewCapsuleLinkStore(db)
 	MessageStore = store.NewMessageStore(db)
+	SessionStore = store.NewSessionStore(db)
+	TokenStore = store.NewOneTimeTokenStore(db)
+	APITokenStore = store.NewAPITokenStore(db)
+	TwoFactorStore = store.NewTwoFactorStore(db)
+	SettingsStore = store.NewSettingsStore(db)
+	IdentityStore = store.NewIdentityStore(db)
+	ThrottleStore = store.NewThrottleStore(db)
 }
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+// maxTagChanges caps the renames or merge sources in one request.
+const maxTagChanges = 100
+
+// ListTags godoc
+// @Summary List tags
+// @Description List the tag registry with usage counts (capsules outside the trash) and aliases. Capsule tags are saved as these normalized slugs.
+// @Tags tags
+// @Produce  json
+// @Security BearerAuth
+// @Param q query string false "Part of the tag slug"
+// @Param sort query string false "usage (default, most used first) or name" Enums(usage, name)
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {object} models.PaginatedResponse "Paginated list of models.Tag"
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/tags [get]
+func ListTags(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	query := r.URL.Query()
+	sort := query.Get("sort")
+	if sort != "" && sort != "usage" && sort != models.SortName {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "sort", Message: "must be usage or name"})
+		return
+	}
+	page, limit := utils.ParsePagination(r)
+	tags, total, err := TagStore.ListTags(strings.TrimSpace(query.Get("q")), sort, page, limit)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONPaginatedResponse(w, http.StatusOK, "Tags fetched", tags, page, limit, total)
+}
+
+// RenameTags godoc
+// @Summary Rename tags (admin only)
+// @Description Rename tags in order, all in one transaction, rewriting every capsule that uses them (trashed ones included). Capsules get no new revision. Each old slug becomes an alias of the renamed tag. Renaming to a slug already in use fails with 409; merge the tags instead.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param input body models.TagRenameInput true "Renames"
+// @Success 200 {object} models.TagChangeResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/admin/tags/rename [post]
+func RenameTags(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req models.TagRenameInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.Renames) == 0 || len(req.Renames) > maxTagChanges {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "renames", Message: "must hold 1 to 100 renames"})
+		return
+	}
+	for _, rename := range req.Renames {
+		if models.TagSlug(rename.From) == "" || models.TagSlug(rename.To) == "" {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "renames", Message: "from and to must be non-empty tags"})
+			return
+		}
+	}
+
+	updated, err := TagStore.RenameTags(req.Renames)
+	if err != nil {
+		tagChangeError(w, r, err)
+		return
+	}
+	logger.LogEvent(logger.EventAdmin, r, slog.String("action", "rename_tags"), slog.Int("renames", len(req.Renames)), slog.Int("capsules", updated))
+	utils.JSONResponse(w, http.StatusOK, true, "Tags renamed", models.TagChangeResult{CapsulesUpdated: updated})
+}
+
+// MergeTags godoc
+// @Summary Merge tags (admin only)
+// @Description Fold source tags into a target tag (created if needed) in one transaction, rewriting every capsule that uses them (trashed ones included). Capsules get no new revision. Source slugs and their aliases become aliases of the target.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param input body models.TagMergeInput true "Source tags and target"
+// @Success 200 {object} models.TagChangeResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/admin/tags/merge [post]
+func MergeTags(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req models.TagMergeInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if models.TagSlug(req.Target) == "" {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "target", Message: "cannot be empty"})
+		return
+	}
+	if n := len(models.NormalizeTags(req.Sources)); n == 0 || n > maxTagChanges {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "sources", Message: "must hold 1 to 100 tags"})
+		return
+	}
+
+	updated, err := TagStore.MergeTags(req.Sources, req.Target)
+	if err != nil {
+		tagChangeError(w, r, err)
+		return
+	}
+	logger.LogEvent(logger.EventAdmin, r, slog.String("action", "merge_tags"), slog.String("target", models.TagSlug(req.Target)), slog.Int("capsules", updated))
+	utils.JSONResponse(w, http.StatusOK, true, "Tags merged", models.TagChangeResult{CapsulesUpdated: updated})
+}
+
+// ListTagAliases godoc
+// @Summary List tag aliases (admin only)
+// @Description List the alias rules applied to capsule tags on create and update, e.g. golang saved as go
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} models.TagAliasView
+// @Router /api/admin/tags/aliases [get]
+func ListTagAliases(w http.ResponseWriter, r *http.Request) {
+	aliases, err := TagStore.ListAliases()
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Tag aliases fetched", aliases)
+}
+
+// SetTagAlias godoc
+// @Summary Set tag alias (admin only)
+// @Description Save capsule tags spelled as alias as the given tag from now on. An existing alias is pointed at the new tag. A tag that is already in use cannot become an alias (409); merge it instead.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param input body models.TagAliasInput true "Alias and tag"
+// @Success 200 {object} models.TagAliasView
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/admin/tags/aliases [post]
+func SetTagAlias(w http.ResponseWriter, r *http.Request) {
+	var req models.TagAliasInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	alias, tag := models.TagSlug(req.Alias), models.TagSlug(req.Tag)
+	if alias == "" || tag == "" {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "alias", Message: "alias and tag cannot be empty"})
+		return
+	}
+	if alias == tag {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "alias", Message: "must differ from the tag"})
+		return
+	}
+
+	view, err := TagStore.SetAlias(alias, tag)
+	if err != nil {
+		tagChangeError(w, r, err)
+		return
+	}
+	logger.LogEvent(logger.EventAdmin, r, slog.String("action", "set_tag_alias"), slog.String("alias", alias), slog.String("tag", tag))
+	utils.JSONResponse(w, http.StatusOK, true, "Tag alias saved", view)
+}
+
+// DeleteTagAlias godoc
+// @Summary Delete tag alias (admin only)
+// @Description Stop mapping an alias to its tag. Capsules keep the tags they were saved with.
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Param alias path string true "Alias slug"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/admin/tags/aliases/{alias} [delete]
+func DeleteTagAlias(w http.ResponseWriter, r *http.Request, alias string) {
+	if !utils.AllowMethod(w, r, http.MethodDelete) {
+		return
+	}
+	if err := TagStore.DeleteAlias(models.TagSlug(alias)); err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	logger.LogEvent(logger.EventAdmin, r, slog.String("action", "delete_tag_alias"), slog.String("alias", alias))
+	utils.JSONResponse(w, http.StatusOK, true, "Tag alias deleted", nil)
+}
+
+// AdminTagsHandler routes /api/admin/tags/rename, /merge and /aliases[/{alias}].
+func AdminTagsHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/tags"), "/")
+	switch {
+	case path == "rename":
+		RenameTags(w, r)
+	case path == "merge":
+		MergeTags(w, r)
+	case path == "aliases":
+		switch r.Method {
+		case http.MethodGet:
+			ListTagAliases(w, r)
+		case http.MethodPost:
+			SetTagAlias(w, r)
+		default:
+			utils.ErrorResponse(w, r, http.StatusMethodNotAllowed, nil)
+		}
+	case strings.HasPrefix(path, "aliases/"):
+		DeleteTagAlias(w, r, strings.TrimPrefix(path, "aliases/"))
+	default:
+		utils.ErrorResponse(w, r, http.StatusNotFound, nil)
+	}
+}
+
+// tagChangeError writes the response for a failed tag registry change.
+func tagChangeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, store.ErrTagNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, store.ErrTagExists):
+		status = http.StatusConflict
+	}
+	utils.ErrorResponse(w, r, status, err)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+// ThrottleSettings configures brute-force protection for login and registration.
+type ThrottleSettings struct {
+	Account  models.ThrottlePolicy // failed logins per email
+	IP       models.ThrottlePolicy // failed logins per IP address, and unknown share emails per user
+	Register models.ThrottlePolicy // registration attempts per IP address
+	Mail     models.ThrottlePolicy // reset and verification emails per address and per IP address
+}
+
+var throttleSettings ThrottleSettings
+
+// InitThrottle sets the lockout policies from config.
+func InitThrottle(settings ThrottleSettings) {
+	throttleSettings = settings
+}
+
+// accountSubject normalizes an email for per-account throttling, so case and
+// whitespace variants share one counter.
+func accountSubject(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// rejectIfLocked responds 429 with Retry-After when any of keys is locked out.
+// Lookup errors fail open so a database hiccup does not block every login.
+func rejectIfLocked(w http.ResponseWriter, r *http.Request, action string, keys ...string) bool {
+	until, err := ThrottleStore.LockedUntil(keys...)
+	if err != nil {
+		logger.ErrorRequest(r, logger.EventAuth, err, slog.String("action", action))
+		return false
+	}
+	if until.IsZero() {
+		return false
+	}
+	retryAfter := int(math.Ceil(time.Until(until).Seconds()))
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", action+"_blocked"), slog.String("reason", "locked_out"), slog.Int("retry_after", retryAfter))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	utils.ErrorResponse(w, r, http.StatusTooManyRequests, errors.New("too many attempts, try again later"))
+	return true
+}
+
+// recordLoginFailure logs a failed login and counts it against the client IP and the account.
+func recordLoginFailure(r *http.Request, email, reason string) {
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "login_failed"), slog.String("email", email), slog.String("reason", reason))
+	recordFailure(r, models.ThrottleScopeIP, utils.ClientIP(r), throttleSettings.IP)
+	if email != "" {
+		recordFailure(r, models.ThrottleScopeAccount, accountSubject(email), throttleSettings.Account)
+	}
+}
+
+// throttleMail counts a request that may send email against the client IP and the address,
+// responding 429 once either is locked out. Requests count whether or not the address is
+// registered, so the limit reveals nothing about it.
+func throttleMail(w http.ResponseWriter, r *http.Request, action, email string) bool {
+	ip, subject := utils.ClientIP(r), accountSubject(email)
+	if rejectIfLocked(w, r, action,
+		models.ThrottleKey(models.ThrottleScopeMailIP, ip),
+		models.ThrottleKey(models.ThrottleScopeMail, subject)) {
+		return true
+	}
+	recordFailure(r, models.ThrottleScopeMailIP, ip, throttleSettings.Mail)
+	recordFailure(r, models.ThrottleScopeMail, subject, throttleSettings.Mail)
+	return false
+}
+
+// recordFailure counts one failure and logs when it triggers a lockout.
+func recordFailure(r *http.Request, scope, subject string, policy models.ThrottlePolicy) {
+	throttle, err := ThrottleStore.RecordFailure(scope, subject, policy)
+	if err != nil {
+		logger.ErrorRequest(r, logger.EventAuth, err, slog.String("action", "record_failure"), slog.String("scope", scope))
+		return
+	}
+	if throttle.LockedUntil != nil && throttle.LockedUntil.After(time.Now()) {
+		logger.LogEvent(logger.EventAuth, r, slog.String("action", "lockout"), slog.String("scope", scope), slog.String("subject", subject),
+			slog.Int("failures", throttle.Failures), slog.Time("locked_until", *throttle.LockedUntil))
+	}
+}
+
+// resetAccountThrottle clears an account's failures after a successful login. IP
+// counters are left to expire so one valid account cannot unlock an attacking IP.
+func resetAccountThrottle(r *http.Request, email string) {
+	if _, err := ThrottleStore.Reset(models.ThrottleKey(models.ThrottleScopeAccount, accountSubject(email))); err != nil {
+		logger.ErrorRequest(r, logger.EventAuth, err, slog.String("action", "reset_throttle"))
+	}
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+// ListTopicMaintainers godoc
+// @Summary List topic maintainers
+// @Description List the users who may edit, move and delete a topic. Emails are only shown to admins and the topic's maintainers; pending topics are only visible to their proposer and admins.
+// @Tags topics
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Topic ID"
+// @Success 200 {array} models.TopicMaintainerView
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/topics/{id}/maintainers [get]
+func ListTopicMaintainers(w http.ResponseWriter, r *http.Request, topicID string) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	topic, err := TopicStore.FindByID(topicID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	if topic.Status == models.TopicStatusPending && topic.CreatedBy != userID && !middleware.IsAdmin(r) {
+		utils.ErrorResponse(w, r, http.StatusNotFound, errors.New("topic not found"))
+		return
+	}
+	maintainers, err := TopicStore.ListMaintainers(topicID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	// Emails are for the people who manage the maintainers.
+	if !middleware.IsAdmin(r) && !slices.ContainsFunc(maintainers, func(m models.TopicMaintainerView) bool { return m.UserID == userID }) {
+		for i := range maintainers {
+			maintainers[i].Email = ""
+		}
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Maintainers fetched", maintainers)
+}
+
+// AddTopicMaintainer godoc
+// @Summary Add topic maintainer
+// @Description Let a user (by ID or email) maintain a topic (maintainers or admins)
+// @Tags topics
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Topic ID"
+// @Param input body models.TopicMaintainerInput true "User ID or email"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/topics/{id}/maintainers [post]
+func AddTopicMaintainer(w http.ResponseWriter, r *http.Request, topicID string) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	if !canModerateTopic(w, r, topicID) {
+		return
+	}
+	var req models.TopicMaintainerInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	var maintainer *models.User
+	var err error
+	if req.UserID != "" {
+		maintainer, err = UserStore.FindByID(req.UserID)
+	} else if email := strings.TrimSpace(req.Email); email != "" {
+		maintainer, err = UserStore.FindByEmail(email)
+	} else {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "user_id", Message: "user ID or email is required"})
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("user not found"))
+		return
+	}
+	if err := TopicStore.AddMaintainer(topicID, maintainer.ID, userID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrTopicNotFound) {
+			status = http.StatusNotFound
+		}
+		utils.ErrorResponse(w, r, status, err)
+		return
+	}
+	logger.LogEvent(logger.EventTopic, r, slog.String("action", "add_maintainer"), slog.String("topic_id", topicID), slog.String("maintainer_id", maintainer.ID))
+	utils.JSONResponse(w, http.StatusOK, true, "Maintainer added", nil)
+}
+
+// RemoveTopicMaintainer godoc
+// @Summary Remove topic maintainer
+// @Description Remove a maintainer from a topic, or step down by removing yourself (maintainers or admins)
+// @Tags topics
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Topic ID"
+// @Param userId path string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/topics/{id}/maintainers/{userId} [delete]
+func RemoveTopicMaintainer(w http.ResponseWriter, r *http.Request, topicID, maintainerID string) {
+	if !utils.AllowMethod(w, r, http.MethodDelete) {
+		return
+	}
+	if !canModerateTopic(w, r, topicID) {
+		return
+	}
+	if err := TopicStore.RemoveMaintainer(topicID, maintainerID); err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	logger.LogEvent(logger.EventTopic, r, slog.String("action", "remove_maintainer"), slog.String("topic_id", topicID), slog.String("maintainer_id", maintainerID))
+	utils.JSONResponse(w, http.StatusOK, true, "Maintainer removed", nil)
+}
+
+// TopicMaintainersHandler routes /api/topics/{id}/maintainers[/{userId}].
+func TopicMaintainersHandler(w http.ResponseWriter, r *http.Request, topicID, rest string) {
+	if userID := strings.TrimPrefix(strings.TrimPrefix(rest, "maintainers"), "/"); userID != "" {
+		RemoveTopicMaintainer(w, r, topicID, userID)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		ListTopicMaintainers(w, r, topicID)
+	case http.MethodPost:
+		AddTopicMaintainer(w, r, topicID)
+	default:
+		utils.ErrorResponse(w, r, http.StatusMethodNotAllowed, nil)
+	}
+}
+
+// ListPendingTopics godoc
+// @Summary List topic proposals (admin only)
+// @Description List topics proposed by regular users that wait for review, oldest first
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} models.PaginatedResponse
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/admin/topics [get]
+func ListPendingTopics(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	page, limit := utils.ParsePagination(r)
+	topics, total, err := TopicStore.ListPendingTopics(page, limit)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONPaginatedResponse(w, http.StatusOK, "Topic proposals fetched", topics, page, limit, total)
+}
+
+// ApproveTopic godoc
+// @Summary Approve topic proposal (admin only)
+// @Description Approve a pending topic. It appears in topic lists and its proposer becomes its maintainer.
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Topic ID"
+// @Success 200 {object} models.Topic
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/admin/topics/{id}/approve [post]
+func ApproveTopic(w http.ResponseWriter, r *http.Request, id string) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	topic, err := TopicStore.ApproveTopic(id, userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrTopicNotFound) {
+			status = http.StatusNotFound
+		}
+		utils.ErrorResponse(w, r, status, err)
+		return
+	}
+	logger.LogEvent(logger.EventAdmin, r, slog.String("action", "approve_topic"), slog.String("topic_id", id))
+	utils.JSONResponse(w, http.StatusOK, true, "Topic approved", topic)
+}
+
+// RejectTopic godoc
+// @Summary Reject topic proposal (admin only)
+// @Description Delete a pending topic. Capsules filed under it are left without a topic.
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Topic ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/admin/topics/{id}/reject [post]
+func RejectTopic(w http.ResponseWriter, r *http.Request, id string) {
+	if err := TopicStore.RejectTopic(id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrTopicNotFound) {
+			status = http.StatusNotFound
+		}
+		utils.ErrorResponse(w, r, status, err)
+		return
+	}
+	logger.LogEvent(logger.EventAdmin, r, slog.String("action", "reject_topic"), slog.String("topic_id", id))
+	utils.JSONResponse(w, http.StatusOK, true, "Topic rejected", nil)
+}
+
+// AdminTopicsHandler routes /api/admin/topics and /api/admin/topics/{id}/approve|reject.
+func AdminTopicsHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/topics"), "/")
+	if path == "" {
+		ListPendingTopics(w, r)
+		return
+	}
+	id, action, _ := strings.Cut(path, "/")
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	switch action {
+	case "approve":
+		ApproveTopic(w, r, id)
+	case "reject":
+		RejectTopic(w, r, id)
+	default:
+		utils.ErrorResponse(w, r, http.StatusNotFound, nil)
+	}
+}
+
+// canModerateTopic reports whether the caller may change a topic: admins always, other users
+// only as its maintainers. It writes the error response when they may not.
+func canModerateTopic(w http.ResponseWriter, r *http.Request, topicID string) bool {
+	if _, err := TopicStore.FindByID(topicID); err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return false
+	}
+	if middleware.IsAdmin(r) {
+		return true
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	maintainer, err := TopicStore.IsMaintainer(topicID, userID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return false
+	}
+	if !maintainer {
+		utils.ErrorResponse(w, r, http.StatusForbidden, errors.New("only topic maintainers or admins can change this topic"))
+		return false
+	}
+	return true
+}
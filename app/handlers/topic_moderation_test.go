@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
+)
+
+// setupTopicModeration stores the approved topic "go" maintained by "keeper" and the pending
+// proposal "zig" by "proposer".
+func setupTopicModeration(t *testing.T) {
+	t.Helper()
+	db := newTestDB(t, &models.User{}, &models.Topic{}, &models.TopicMaintainer{}, &models.Capsule{})
+	for _, u := range []models.User{
+		{ID: "keeper", Name: "Keeper", Email: "keeper@example.com"},
+		{ID: "proposer", Name: "Proposer", Email: "proposer@example.com"},
+		{ID: "other", Name: "Other", Email: "other@example.com"},
+	} {
+		if err := db.Create(&u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Create(&models.Topic{ID: "go", TopicInput: models.TopicInput{Name: "Go"}, Status: models.TopicStatusApproved})
+	db.Create(&models.Topic{ID: "zig", TopicInput: models.TopicInput{Name: "Zig"}, CreatedBy: "proposer", Status: models.TopicStatusPending})
+	db.Create(&models.TopicMaintainer{TopicID: "go", UserID: "keeper", AddedBy: "keeper"})
+
+	topics := TopicStore
+	t.Cleanup(func() { TopicStore = topics })
+	TopicStore = store.NewTopicStore(db)
+}
+
+// asRole runs handler as userID with the given role.
+func asRole(userID, role string, handler func(http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return asUser(userID, func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r.WithContext(context.WithValue(r.Context(), middleware.RoleContextKey, role)))
+	})
+}
+
+func TestTopicEditsNeedMaintainer(t *testing.T) {
+	setupTopicModeration(t)
+	tests := []struct {
+		name, userID, role string
+		want               int
+	}{
+		{"maintainer", "keeper", models.RoleUser, http.StatusOK},
+		{"other user", "other", models.RoleUser, http.StatusForbidden},
+		{"admin", "other", models.RoleAdmin, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveJSON(t, asRole(tt.userID, tt.role, UpdateTopicByID), http.MethodPut, "/api/topics/go",
+				models.TopicInput{Name: "Go " + tt.name})
+			if w.Code != tt.want {
+				t.Errorf("PUT as %s: status = %d, want %d", tt.name, w.Code, tt.want)
+			}
+		})
+	}
+
+	maintainers := func(w http.ResponseWriter, r *http.Request) { TopicMaintainersHandler(w, r, "go", "maintainers") }
+	w := serveJSON(t, asRole("other", models.RoleUser, maintainers), http.MethodPost, "/api/topics/go/maintainers",
+		models.TopicMaintainerInput{UserID: "other"})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("non-maintainer adding themselves: status = %d, want 403", w.Code)
+	}
+}
+
+func TestListTopicMaintainersVisibility(t *testing.T) {
+	setupTopicModeration(t)
+	list := func(userID, role, topicID string) (int, []models.TopicMaintainerView) {
+		handler := func(w http.ResponseWriter, r *http.Request) { ListTopicMaintainers(w, r, topicID) }
+		w := serveJSON(t, asRole(userID, role, handler), http.MethodGet, "/api/topics/"+topicID+"/maintainers", nil)
+		var body struct {
+			Data []models.TopicMaintainerView `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body.Data
+	}
+
+	for _, tt := range []struct {
+		name, userID, role string
+		wantEmail          bool
+	}{
+		{"other user", "other", models.RoleUser, false},
+		{"maintainer", "keeper", models.RoleUser, true},
+		{"admin", "other", models.RoleAdmin, true},
+	} {
+		status, got := list(tt.userID, tt.role, "go")
+		if status != http.StatusOK || len(got) != 1 || got[0].UserID != "keeper" {
+			t.Fatalf("%s: status = %d, maintainers = %+v", tt.name, status, got)
+		}
+		if hasEmail := got[0].Email != ""; hasEmail != tt.wantEmail {
+			t.Errorf("%s sees email = %v, want %v", tt.name, hasEmail, tt.wantEmail)
+		}
+	}
+
+	if status, _ := list("other", models.RoleUser, "zig"); status != http.StatusNotFound {
+		t.Errorf("pending topic for another user: status = %d, want 404", status)
+	}
+	if status, _ := list("proposer", models.RoleUser, "zig"); status != http.StatusOK {
+		t.Errorf("pending topic for its proposer: status = %d, want 200", status)
+	}
+}
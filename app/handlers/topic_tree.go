@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+// GetTopicTree godoc
+// @Summary Get topic tree
+// @Description Get all topics nested under their parents, each level sorted by name. With root, only that topic and its descendants.
+// @Tags topics
+// @Produce  json
+// @Security BearerAuth
+// @Param root query string false "Topic ID to return the subtree of"
+// @Success 200 {array} models.TopicNode
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/topics/tree [get]
+func GetTopicTree(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	tree, err := TopicStore.TopicTree(r.URL.Query().Get("root"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrTopicNotFound) {
+			status = http.StatusNotFound
+		}
+		utils.ErrorResponse(w, r, status, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Topic tree fetched", tree)
+}
+
+// MoveTopic godoc
+// @Summary Move topic
+// @Description Move a topic and its subtopics under another approved topic, or to the root with a null parent_id (maintainers or admins). A topic cannot be moved under itself or one of its subtopics.
+// @Tags topics
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Topic ID"
+// @Param input body models.TopicMoveInput true "New parent"
+// @Success 200 {object} models.Topic
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/topics/{id}/move [post]
+func MoveTopic(w http.ResponseWriter, r *http.Request, id string) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	if !canModerateTopic(w, r, id) {
+		return
+	}
+	var req models.TopicMoveInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if req.ParentID != nil && *req.ParentID == "" {
+		req.ParentID = nil
+	}
+
+	topic, err := TopicStore.MoveTopic(id, req.ParentID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, store.ErrTopicNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, store.ErrParentTopicNotFound), errors.Is(err, store.ErrTopicCycle):
+			status = http.StatusBadRequest
+		}
+		utils.ErrorResponse(w, r, status, err)
+		return
+	}
+	parent := ""
+	if topic.ParentID != nil {
+		parent = *topic.ParentID
+	}
+	logger.LogEvent(logger.EventTopic, r, slog.String("action", "move"), slog.String("topic_id", id), slog.String("parent_id", parent))
+	utils.JSONResponse(w, http.StatusOK, true, "Topic moved", topic)
+}
+
+// withTopicPaths fills in the topic breadcrumbs of capsules. A failed lookup is logged and
+// only leaves the breadcrumbs out.
+func withTopicPaths(r *http.Request, capsules ...*models.Capsule) {
+	ids := make([]string, 0, len(capsules))
+	for _, c := range capsules {
+		if c.TopicID != nil {
+			ids = append(ids, *c.TopicID)
+		}
+	}
+	paths, err := TopicStore.TopicPaths(ids)
+	if err != nil {
+		logger.ErrorRequest(r, logger.EventTopic, err, slog.String("action", "topic_paths"))
+		return
+	}
+	for _, c := range capsules {
+		if c.TopicID != nil {
+			c.TopicPath = paths[*c.TopicID]
+		}
+	}
+}
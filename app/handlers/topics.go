@@ -6,22 +6,27 @@ import (
 	"log/slog"
 	"net/http"
 
+	"knowledge-capsule/app/middleware"
 	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
 	"knowledge-capsule/pkg/logger"
 	"knowledge-capsule/pkg/utils"
 )
 
 // GetTopics godoc
 // @Summary Get topics
-// @Description Get all topics (paginated, filterable)
+// @Description Get all approved topics (paginated, filterable)
 // @Tags topics
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
 // @Param page query int false "Page number (default 1)"
 // @Param limit query int false "Items per page (default 20, max 100)"
+// @Param sort query string false "Sort key (default relevance with q, otherwise name)" Enums(created_at, updated_at, name, relevance)
+// @Param order query string false "asc or desc (default asc for name, desc otherwise)" Enums(asc, desc)
+// @Param cursor query string false "next_cursor from the previous page; replaces page"
 // @Param q query string false "Search in name or description"
-// @Success 200 {object} models.PaginatedResponse "Paginated list: data, page, limit, total"
+// @Success 200 {object} models.PaginatedResponse "Paginated list: data, page, limit, total, next_cursor"
 // @Failure 400 {object} map[string]interface{}
 // @Router /api/topics [get]
 func GetTopics(w http.ResponseWriter, r *http.Request) {
@@ -29,37 +34,55 @@ func GetTopics(w http.ResponseWriter, r *http.Request) {
 	if q := r.URL.Query().Get("q"); q != "" {
 		filters = &models.TopicFilters{Q: q}
 	}
+	opts, err := utils.ParseListOptions(r, models.SortCreatedAt, models.SortUpdatedAt, models.SortName, models.SortRelevance)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
 
-	topics, err := TopicStore.GetAllTopics(filters)
+	topics, info, err := TopicStore.GetAllTopics(filters, opts)
 	if err != nil {
-		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrInvalidCursor) {
+			status = http.StatusBadRequest
+		}
+		utils.ErrorResponse(w, r, status, err)
 		return
 	}
-	page, limit := utils.ParsePagination(r)
-	paged, total := utils.SlicePage(topics, page, limit)
-	logger.LogEvent(logger.EventTopic, r, slog.String("action", "list"), slog.Int("count", len(paged)), slog.Int("total", total))
-	utils.JSONPaginatedResponse(w, http.StatusOK, "Topics fetched", paged, page, limit, total)
+	logger.LogEvent(logger.EventTopic, r, slog.String("action", "list"), slog.Int("count", len(topics)), slog.Int("total", info.Total))
+	utils.JSONListResponse(w, http.StatusOK, "Topics fetched", topics, opts, info)
 }
 
 // CreateTopic godoc
 // @Summary Create topic
-// @Description Create a new topic
+// @Description Create a new topic, optionally under a parent topic (parent_id). Admins create approved topics and become their maintainer; other users propose a topic, which stays pending (202) until an admin approves it.
 // @Tags topics
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
 // @Param input body models.TopicInput true "Topic data"
 // @Success 201 {object} models.Topic
+// @Success 202 {object} models.Topic
 // @Failure 400 {object} map[string]interface{}
 // @Router /api/topics [post]
 func CreateTopic(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
 	var req models.TopicInput
 	json.NewDecoder(r.Body).Decode(&req)
-	topic, err := TopicStore.AddTopic(req.Name, req.Description)
+	if req.ParentID != nil && *req.ParentID == "" {
+		req.ParentID = nil
+	}
+	approved := middleware.IsAdmin(r)
+	topic, err := TopicStore.AddTopic(userID, req, approved)
 	if err != nil {
 		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
 		return
 	}
+	if !approved {
+		logger.LogEvent(logger.EventTopic, r, slog.String("action", "propose"), slog.String("topic_id", topic.ID), slog.String("name", req.Name))
+		utils.JSONResponse(w, http.StatusAccepted, true, "Topic proposed; an admin will review it", topic)
+		return
+	}
 	logger.LogEvent(logger.EventTopic, r, slog.String("action", "create"), slog.String("topic_id", topic.ID), slog.String("name", req.Name))
 	utils.JSONResponse(w, http.StatusCreated, true, "Topic created", topic)
 }
@@ -9,13 +9,14 @@ import (
 
 	"knowledge-capsule/app/middleware"
 	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
 	"knowledge-capsule/pkg/logger"
 	"knowledge-capsule/pkg/utils"
 )
 
 // GetTopicByID godoc
 // @Summary Get topic by ID
-// @Description Get a single topic
+// @Description Get a single topic with its breadcrumb path from the root topic. Pending proposals are only visible to their proposer and admins.
 // @Tags topics
 // @Accept  json
 // @Produce  json
@@ -25,7 +26,7 @@ import (
 // @Failure 404 {object} map[string]interface{}
 // @Router /api/topics/{id} [get]
 func GetTopicByID(w http.ResponseWriter, r *http.Request) {
-	_ = r.Context().Value(middleware.UserContextKey).(string) // auth required
+	userID := r.Context().Value(middleware.UserContextKey).(string)
 	id := strings.TrimPrefix(r.URL.Path, "/api/topics/")
 	if id == "" {
 		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("missing topic id"))
@@ -36,12 +37,21 @@ func GetTopicByID(w http.ResponseWriter, r *http.Request) {
 		utils.ErrorResponse(w, r, http.StatusNotFound, err)
 		return
 	}
+	if topic.Status == models.TopicStatusPending && topic.CreatedBy != userID && !middleware.IsAdmin(r) {
+		utils.ErrorResponse(w, r, http.StatusNotFound, errors.New("topic not found"))
+		return
+	}
+	if paths, err := TopicStore.TopicPaths([]string{topic.ID}); err != nil {
+		logger.ErrorRequest(r, logger.EventTopic, err, slog.String("action", "topic_paths"))
+	} else {
+		topic.Path = paths[topic.ID]
+	}
 	utils.JSONResponse(w, http.StatusOK, true, "Topic fetched", topic)
 }
 
 // UpdateTopicByID godoc
 // @Summary Update topic by ID
-// @Description Update a topic
+// @Description Update a topic's name and description (maintainers or admins); parent_id is ignored (use /api/topics/{id}/move). Renaming it renames the topic on all its capsules.
 // @Tags topics
 // @Accept  json
 // @Produce  json
@@ -50,15 +60,19 @@ func GetTopicByID(w http.ResponseWriter, r *http.Request) {
 // @Param input body models.TopicInput true "Updated topic fields"
 // @Success 200 {object} models.Topic
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Router /api/topics/{id} [put]
 func UpdateTopicByID(w http.ResponseWriter, r *http.Request) {
-	_ = r.Context().Value(middleware.UserContextKey).(string) // auth required
 	id := strings.TrimPrefix(r.URL.Path, "/api/topics/")
 	if id == "" {
 		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("missing topic id"))
 		return
 	}
+	if !canModerateTopic(w, r, id) {
+		return
+	}
 	var req models.TopicInput
 	if r.Body == nil {
 		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("empty request body"))
@@ -77,7 +91,11 @@ func UpdateTopicByID(w http.ResponseWriter, r *http.Request) {
 
 	topic, err := TopicStore.UpdateTopic(id, name, req.Description)
 	if err != nil {
-		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		status := http.StatusNotFound
+		if errors.Is(err, store.ErrTopicExists) {
+			status = http.StatusConflict
+		}
+		utils.ErrorResponse(w, r, status, err)
 		return
 	}
 	logger.LogEvent(logger.EventTopic, r, slog.String("action", "update"), slog.String("topic_id", id))
@@ -86,32 +104,60 @@ func UpdateTopicByID(w http.ResponseWriter, r *http.Request) {
 
 // DeleteTopicByID godoc
 // @Summary Delete topic by ID
-// @Description Delete a topic
+// @Description Move a topic to the caller's trash (maintainers or admins). It can be restored from /api/trash until it is purged. Topics with subtopics, or that capsules (including trashed ones) still belong to, cannot be deleted.
 // @Tags topics
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
 // @Param id path string true "Topic ID"
 // @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Router /api/topics/{id} [delete]
 func DeleteTopicByID(w http.ResponseWriter, r *http.Request) {
-	_ = r.Context().Value(middleware.UserContextKey).(string) // auth required
+	userID := r.Context().Value(middleware.UserContextKey).(string)
 	id := strings.TrimPrefix(r.URL.Path, "/api/topics/")
 	if id == "" {
 		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("missing topic id"))
 		return
 	}
-	if err := TopicStore.DeleteTopic(id); err != nil {
-		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+	if !canModerateTopic(w, r, id) {
+		return
+	}
+	if err := TopicStore.DeleteTopic(id, userID); err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, store.ErrTopicInUse) || errors.Is(err, store.ErrTopicHasChildren) {
+			status = http.StatusConflict
+		}
+		utils.ErrorResponse(w, r, status, err)
 		return
 	}
 	logger.LogEvent(logger.EventTopic, r, slog.String("action", "delete"), slog.String("topic_id", id))
-	utils.JSONResponse(w, http.StatusOK, true, "Topic deleted", nil)
+	utils.JSONResponse(w, http.StatusOK, true, "Topic moved to trash", nil)
 }
 
-// TopicByIDHandler routes GET/PUT/DELETE to the appropriate handler.
+// TopicByIDHandler routes GET/PUT/DELETE to the appropriate handler, /api/topics/tree to
+// GetTopicTree, /api/topics/{id}/move to MoveTopic and /api/topics/{id}/maintainers/... to
+// TopicMaintainersHandler.
 func TopicByIDHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/topics/"), "/")
+	if path == "tree" {
+		GetTopicTree(w, r)
+		return
+	}
+	if id, rest, ok := strings.Cut(path, "/"); ok {
+		switch {
+		case rest == "move":
+			MoveTopic(w, r, id)
+		case rest == "maintainers" || strings.HasPrefix(rest, "maintainers/"):
+			TopicMaintainersHandler(w, r, id, rest)
+		default:
+			utils.ErrorResponse(w, r, http.StatusNotFound, nil)
+		}
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		GetTopicByID(w, r)
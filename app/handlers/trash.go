@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+// trashRetention is how long trashed items are kept before the purge removes them.
+var trashRetention time.Duration
+
+// trashScopes maps each trash item type to its personal access token read and write scopes.
+var trashScopes = map[string][2]string{
+	models.TrashCapsule: {models.ScopeCapsulesRead, models.ScopeCapsulesWrite},
+	models.TrashTopic:   {models.ScopeTopicsRead, models.ScopeTopicsWrite},
+}
+
+// InitTrash sets the trash retention and starts a background job that purges expired
+// items every interval.
+func InitTrash(retention, interval time.Duration) {
+	trashRetention = retention
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purgeExpiredTrash()
+			<-ticker.C
+		}
+	}()
+}
+
+// purgeExpiredTrash permanently deletes every item trashed longer than the retention period.
+func purgeExpiredTrash() {
+	cutoff := time.Now().Add(-trashRetention)
+	capsules, err := CapsuleStore.PurgeTrashedCapsules("", cutoff)
+	if err != nil {
+		logger.Error(logger.EventTrash, err, slog.String("action", "purge"), slog.String("type", models.TrashCapsule))
+	}
+	topics, err := TopicStore.PurgeTrashedTopics("", cutoff)
+	if err != nil {
+		logger.Error(logger.EventTrash, err, slog.String("action", "purge"), slog.String("type", models.TrashTopic))
+	}
+	if capsules > 0 || topics > 0 {
+		logger.Info(logger.EventTrash, slog.String("action", "purge"), slog.Int("capsules", capsules), slog.Int("topics", topics))
+	}
+}
+
+// ListTrash godoc
+// @Summary List trash
+// @Description List the caller's trashed capsules and the topics they deleted, most recently deleted first
+// @Tags trash
+// @Produce  json
+// @Security BearerAuth
+// @Param type query string false "Only list one type" Enums(capsule, topic)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param order query string false "asc or desc (default desc)" Enums(asc, desc)
+// @Param cursor query string false "next_cursor from the previous page; replaces page"
+// @Success 200 {array} models.TrashItem
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/trash [get]
+func ListTrash(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	types, ok := trashTypes(w, r, r.URL.Query().Get("type"), false)
+	if !ok {
+		return
+	}
+
+	opts, err := utils.ParseListOptions(r, models.SortDeletedAt)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	items, info, err := CapsuleStore.ListTrash(userID, types, opts)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrInvalidCursor) {
+			status = http.StatusBadRequest
+		}
+		utils.ErrorResponse(w, r, status, err)
+		return
+	}
+	for i := range items {
+		items[i].PurgeAt = items[i].DeletedAt.Add(trashRetention)
+	}
+	utils.JSONListResponse(w, http.StatusOK, "Trash fetched", items, opts, info)
+}
+
+// EmptyTrash godoc
+// @Summary Empty trash
+// @Description Permanently delete everything in the caller's trash
+// @Tags trash
+// @Produce  json
+// @Security BearerAuth
+// @Param type query string false "Only empty one type" Enums(capsule, topic)
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/trash [delete]
+func EmptyTrash(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	types, ok := trashTypes(w, r, r.URL.Query().Get("type"), true)
+	if !ok {
+		return
+	}
+
+	purged := map[string]int{}
+	now := time.Now()
+	for _, t := range types {
+		var n int
+		var err error
+		switch t {
+		case models.TrashCapsule:
+			n, err = CapsuleStore.PurgeTrashedCapsules(userID, now)
+		case models.TrashTopic:
+			n, err = TopicStore.PurgeTrashedTopics(userID, now)
+		}
+		if err != nil {
+			utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		purged[t] = n
+	}
+	logger.LogEvent(logger.EventTrash, r, slog.String("action", "empty"), slog.Any("purged", purged))
+	utils.JSONResponse(w, http.StatusOK, true, "Trash emptied", map[string]interface{}{"purged": purged})
+}
+
+// RestoreTrashItem godoc
+// @Summary Restore from trash
+// @Description Move a capsule or topic out of the trash. A topic cannot be restored while another topic has its name.
+// @Tags trash
+// @Produce  json
+// @Security BearerAuth
+// @Param type path string true "Item type" Enums(capsule, topic)
+// @Param id path string true "Item ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/trash/{type}/{id}/restore [post]
+func RestoreTrashItem(w http.ResponseWriter, r *http.Request, itemType, id string) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+
+	switch itemType {
+	case models.TrashCapsule:
+		capsule, err := CapsuleStore.RestoreCapsule(id, userID)
+		if err != nil {
+			utils.ErrorResponse(w, r, http.StatusNotFound, err)
+			return
+		}
+		logger.LogEvent(logger.EventCapsule, r, slog.String("action", "restore"), slog.String("capsule_id", id))
+		utils.JSONResponse(w, http.StatusOK, true, "Capsule restored", capsule)
+	case models.TrashTopic:
+		topic, err := TopicStore.RestoreTopic(id, userID)
+		if err != nil {
+			status := http.StatusNotFound
+			if errors.Is(err, store.ErrTopicExists) {
+				status = http.StatusConflict
+			}
+			utils.ErrorResponse(w, r, status, err)
+			return
+		}
+		logger.LogEvent(logger.EventTopic, r, slog.String("action", "restore"), slog.String("topic_id", id))
+		utils.JSONResponse(w, http.StatusOK, true, "Topic restored", topic)
+	}
+}
+
+// PurgeTrashItem godoc
+// @Summary Delete permanently
+// @Description Permanently delete a capsule (with its revisions) or topic from the trash
+// @Tags trash
+// @Produce  json
+// @Security BearerAuth
+// @Param type path string true "Item type" Enums(capsule, topic)
+// @Param id path string true "Item ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/trash/{type}/{id} [delete]
+func PurgeTrashItem(w http.ResponseWriter, r *http.Request, itemType, id string) {
+	if !utils.AllowMethod(w, r, http.MethodDelete) {
+		return
+	}
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+
+	var err error
+	switch itemType {
+	case models.TrashCapsule:
+		err = CapsuleStore.PurgeCapsule(id, userID)
+	case models.TrashTopic:
+		err = TopicStore.PurgeTopic(id, userID)
+	}
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	logger.LogEvent(logger.EventTrash, r, slog.String("action", "purge"), slog.String("type", itemType), slog.String("id", id))
+	utils.JSONResponse(w, http.StatusOK, true, "Permanently deleted", nil)
+}
+
+// TrashHandler routes /api/trash and /api/trash/{type}/{id}[/restore].
+func TrashHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/trash"), "/")
+	if path == "" {
+		switch r.Method {
+		case http.MethodGet:
+			ListTrash(w, r)
+		case http.MethodDelete:
+			EmptyTrash(w, r)
+		default:
+			utils.ErrorResponse(w, r, http.StatusMethodNotAllowed, nil)
+		}
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] == "" || (len(parts) == 3 && parts[2] != "restore") {
+		utils.ErrorResponse(w, r, http.StatusNotFound, nil)
+		return
+	}
+	if _, ok := trashTypes(w, r, parts[0], true); !ok {
+		return
+	}
+	if len(parts) == 3 {
+		RestoreTrashItem(w, r, parts[0], parts[1])
+		return
+	}
+	PurgeTrashItem(w, r, parts[0], parts[1])
+}
+
+// trashTypes resolves an optional item type to the types the request may read (or write),
+// responding 400 for an unknown type and 403 when an API token lacks the scope.
+func trashTypes(w http.ResponseWriter, r *http.Request, itemType string, write bool) ([]string, bool) {
+	candidates := []string{models.TrashCapsule, models.TrashTopic}
+	if itemType != "" {
+		if _, ok := trashScopes[itemType]; !ok {
+			utils.ErrorResponse(w, r, http.StatusBadRequest, &utils.ValidationError{Field: "type", Message: "must be capsule or topic"})
+			return nil, false
+		}
+		candidates = []string{itemType}
+	}
+
+	var types []string
+	var missing string
+	for _, t := range candidates {
+		scope := trashScopes[t][0]
+		if write {
+			scope = trashScopes[t][1]
+		}
+		if middleware.HasScope(r, scope) {
+			types = append(types, t)
+		} else {
+			missing = scope
+		}
+	}
+	if len(types) == 0 {
+		utils.ErrorResponse(w, r, http.StatusForbidden, errors.New("API token lacks scope: "+missing))
+		return nil, false
+	}
+	return types, true
+}
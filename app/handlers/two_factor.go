@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/logger"
+	"knowledge-capsule/pkg/utils"
+)
+
+const totpIssuer = "Knowledge Capsule"
+
+// GetTwoFactorStatus godoc
+// @Summary Two-factor status
+// @Description Whether 2FA is enabled, required for this account, and how many recovery codes remain
+// @Tags two-factor
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} models.TwoFactorStatus
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/users/me/2fa [get]
+func GetTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	remaining, err := TwoFactorStore.CountRecoveryCodes(user.ID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Two-factor status fetched", models.TwoFactorStatus{
+		Enabled:                user.TwoFactorEnabled,
+		Required:               twoFactorRequired(user.Role),
+		RecoveryCodesRemaining: remaining,
+	})
+}
+
+// SetupTwoFactor godoc
+// @Summary Start two-factor setup
+// @Description Generate a new TOTP secret and otpauth URI. 2FA is not active until confirmed with POST /api/users/me/2fa/enable.
+// @Tags two-factor
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} models.TwoFactorSetup
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/users/me/2fa/setup [post]
+func SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if err := TwoFactorStore.SetPendingSecret(user.ID, secret); err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "2fa_setup"))
+	utils.JSONResponse(w, http.StatusOK, true, "Scan the URI with an authenticator app, then confirm with a code", models.TwoFactorSetup{
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPURI(totpIssuer, user.Email, secret),
+	})
+}
+
+// EnableTwoFactor godoc
+// @Summary Enable two-factor authentication
+// @Description Confirm setup with a current TOTP code. Returns recovery codes, shown only once.
+// @Tags two-factor
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param input body object{code=string} true "Current TOTP code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/users/me/2fa/enable [post]
+func EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if !utils.ParseAndValidateBody(w, r, &req) {
+		return
+	}
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	if user.TwoFactorEnabled {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("two-factor authentication is already enabled"))
+		return
+	}
+	if user.TOTPSecret == "" {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("two-factor setup not started"))
+		return
+	}
+	step, valid := utils.ValidateTOTP(user.TOTPSecret, req.Code, time.Now())
+	if !valid {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("invalid code"))
+		return
+	}
+
+	codes, err := TwoFactorStore.Enable(user.ID, step)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, err)
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "2fa_enabled"))
+	utils.JSONResponse(w, http.StatusOK, true, "Two-factor authentication enabled; store the recovery codes safely", map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Description Turn off 2FA with the account password and a TOTP or recovery code. Not allowed for admins while 2FA is required.
+// @Tags two-factor
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param input body object{password=string,code=string} true "Password and TOTP or recovery code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/users/me/2fa/disable [post]
+func DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if !utils.ParseAndValidateBody(w, r, &req) {
+		return
+	}
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	if !user.TwoFactorEnabled {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("two-factor authentication is not enabled"))
+		return
+	}
+	if twoFactorRequired(user.Role) {
+		utils.ErrorResponse(w, r, http.StatusForbidden, errors.New("two-factor authentication is required for admin accounts"))
+		return
+	}
+	if !utils.CheckPassword(req.Password, user.PasswordHash) {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("invalid credentials"))
+		return
+	}
+	if _, ok := verifySecondFactor(user, req.Code); !ok {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("invalid code"))
+		return
+	}
+
+	if err := TwoFactorStore.Disable(user.ID); err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "2fa_disabled"))
+	utils.JSONResponse(w, http.StatusOK, true, "Two-factor authentication disabled", nil)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes after verifying a current TOTP code. Returns the new codes, shown only once.
+// @Tags two-factor
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param input body object{code=string} true "Current TOTP code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/users/me/2fa/recovery-codes [post]
+func RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if !utils.ParseAndValidateBody(w, r, &req) {
+		return
+	}
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	if !user.TwoFactorEnabled {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("two-factor authentication is not enabled"))
+		return
+	}
+	if !verifyTOTP(user, req.Code) {
+		utils.ErrorResponse(w, r, http.StatusBadRequest, errors.New("invalid code"))
+		return
+	}
+
+	codes, err := TwoFactorStore.RegenerateRecoveryCodes(user.ID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	logger.LogEvent(logger.EventAuth, r, slog.String("action", "2fa_recovery_codes_regenerated"))
+	utils.JSONResponse(w, http.StatusOK, true, "Recovery codes regenerated", map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
+
+// TwoFactorHandler routes /api/users/me/2fa and its actions.
+func TwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	action := strings.TrimPrefix(r.URL.Path, "/api/users/me/2fa")
+	action = strings.TrimPrefix(action, "/")
+
+	switch action {
+	case "":
+		GetTwoFactorStatus(w, r)
+	case "setup":
+		SetupTwoFactor(w, r)
+	case "enable":
+		EnableTwoFactor(w, r)
+	case "disable":
+		DisableTwoFactor(w, r)
+	case "recovery-codes":
+		RegenerateRecoveryCodes(w, r)
+	default:
+		utils.ErrorResponse(w, r, http.StatusNotFound, nil)
+	}
+}
+
+// verifySecondFactor accepts a TOTP code or an unused recovery code and returns which was used.
+func verifySecondFactor(user *models.User, code string) (string, bool) {
+	if !user.TwoFactorEnabled {
+		return "", false
+	}
+	if verifyTOTP(user, code) {
+		return "totp", true
+	}
+	if used, err := TwoFactorStore.UseRecoveryCode(user.ID, code); err == nil && used {
+		return "recovery_code", true
+	}
+	return "", false
+}
+
+// verifyTOTP checks a TOTP code and marks its time step used so it cannot be replayed.
+func verifyTOTP(user *models.User, code string) bool {
+	step, valid := utils.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !valid {
+		return false
+	}
+	fresh, err := TwoFactorStore.MarkStepUsed(user.ID, step)
+	return err == nil && fresh
+}
+
+// twoFactorRequired reports whether the admin 2FA policy applies to role.
+func twoFactorRequired(role string) bool {
+	if role != models.RoleAdmin && role != models.RoleSuperAdmin {
+		return false
+	}
+	required, err := SettingsStore.GetBool(models.SettingRequireAdmin2FA)
+	return err == nil && required
+}
+
+// currentUser loads the authenticated user, writing an error response on failure.
+func currentUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	user, err := UserStore.FindByID(userID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusNotFound, err)
+		return nil, false
+	}
+	return user, true
+}
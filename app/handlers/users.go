@@ -2,13 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
-	"errors"
 	"log/slog"
 	"net/http"
 	"strings"
 
 	"knowledge-capsule/app/middleware"
-	"knowledge-capsule/app/models"
 	"knowledge-capsule/pkg/logger"
 	"knowledge-capsule/pkg/utils"
 )
@@ -138,11 +136,24 @@ func GetUserByID(w http.ResponseWriter, r *http.Request) {
 	utils.JSONResponse(w, http.StatusOK, true, "User fetched", user)
 }
 
-// UserHandler routes /api/users/me (GET, PATCH) and /api/users/:id (GET, admin only).
+// UserHandler routes /api/users/me (GET, PATCH), /api/users/me/sessions, /api/users/me/tokens, /api/users/me/2fa and /api/users/:id (GET, admin only).
 func UserHandler(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/users")
 	path = strings.TrimPrefix(path, "/")
 
+	if path == "me/sessions" || strings.HasPrefix(path, "me/sessions/") {
+		SessionsHandler(w, r)
+		return
+	}
+	if path == "me/tokens" || strings.HasPrefix(path, "me/tokens/") {
+		APITokensHandler(w, r)
+		return
+	}
+	if path == "me/2fa" || strings.HasPrefix(path, "me/2fa/") {
+		TwoFactorHandler(w, r)
+		return
+	}
+
 	if path == "me" {
 		switch r.Method {
 		case http.MethodGet:
@@ -157,12 +168,7 @@ func UserHandler(w http.ResponseWriter, r *http.Request) {
 
 	// /api/users/:id - admin only
 	if path != "" {
-		role, _ := r.Context().Value(middleware.RoleContextKey).(string)
-		if role != models.RoleAdmin && role != models.RoleSuperAdmin {
-			utils.ErrorResponse(w, r, http.StatusForbidden, errors.New("admin access required"))
-			return
-		}
-		GetUserByID(w, r)
+		middleware.RequireAdmin(http.HandlerFunc(GetUserByID)).ServeHTTP(w, r)
 		return
 	}
 
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/pkg/utils"
+)
+
+// ListCapsuleOutlinks godoc
+// @Summary List capsule outgoing links
+// @Description List the [[Title]] and [[id]] wiki links in a capsule's content, in order. Links resolve to the owner's capsules; broken ones match no capsule or one in the trash. Targets the caller cannot open carry only their link text.
+// @Tags capsules
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Capsule ID"
+// @Success 200 {array} models.WikiLinkView
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/capsules/{id}/outlinks [get]
+func ListCapsuleOutlinks(w http.ResponseWriter, r *http.Request, id string) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	if _, ok := readableCapsule(w, r, id); !ok {
+		return
+	}
+	links, err := CapsuleStore.ListOutlinks(id, userID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Outgoing links fetched", links)
+}
+
+// ListCapsuleBacklinks godoc
+// @Summary List capsule backlinks
+// @Description List the capsules whose content links to this one, by title. Capsules in the trash or that the caller cannot open are left out.
+// @Tags capsules
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Capsule ID"
+// @Success 200 {array} models.WikiLinkView
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/capsules/{id}/backlinks [get]
+func ListCapsuleBacklinks(w http.ResponseWriter, r *http.Request, id string) {
+	userID := r.Context().Value(middleware.UserContextKey).(string)
+	if _, ok := readableCapsule(w, r, id); !ok {
+		return
+	}
+	links, err := CapsuleStore.ListBacklinks(id, userID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, true, "Backlinks fetched", links)
+}
+
+// CapsuleWikiLinksHandler routes /api/capsules/{id}/outlinks and /api/capsules/{id}/backlinks.
+func CapsuleWikiLinksHandler(w http.ResponseWriter, r *http.Request) {
+	if !utils.AllowMethod(w, r, http.MethodGet) {
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/capsules/"), "/")
+	id, rest, _ := strings.Cut(path, "/")
+	switch rest {
+	case "outlinks":
+		ListCapsuleOutlinks(w, r, id)
+	case "backlinks":
+		ListCapsuleBacklinks(w, r, id)
+	default:
+		utils.ErrorResponse(w, r, http.StatusNotFound, nil)
+	}
+}
@@ -7,16 +7,35 @@ import (
 	"strings"
 
 	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
 	"knowledge-capsule/pkg/contextkeys"
 	"knowledge-capsule/pkg/utils"
 )
 
 // Re-export for handler convenience
 var (
-	UserContextKey = contextkeys.UserContextKey
-	RoleContextKey = contextkeys.RoleContextKey
+	UserContextKey    = contextkeys.UserContextKey
+	RoleContextKey    = contextkeys.RoleContextKey
+	SessionContextKey = contextkeys.SessionContextKey
+	ScopesContextKey  = contextkeys.ScopesContextKey
+	MFAContextKey     = contextkeys.MFAContextKey
 )
 
+var (
+	sessionStore  store.SessionStore
+	apiTokenStore store.APITokenStore
+	userStore     store.UserStore
+)
+
+// InitAuth sets the stores used to reject revoked sessions and to resolve personal access tokens.
+func InitAuth(sessions store.SessionStore, apiTokens store.APITokenStore, users store.UserStore) {
+	sessionStore = sessions
+	apiTokenStore = apiTokens
+	userStore = users
+}
+
+// AuthMiddleware accepts a session JWT or a personal access token (kc_pat_...).
+// For access tokens the granted scopes are stored in the context and checked by RequireScope.
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -32,12 +51,34 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			utils.ErrorResponse(w, r, http.StatusUnauthorized, errors.New("missing or invalid Authorization header or token"))
 			return
 		}
+
+		if strings.HasPrefix(tokenString, models.APITokenPrefix) {
+			authenticateAPIToken(w, r, next, tokenString)
+			return
+		}
+
 		claims, err := utils.VerifyJWT(tokenString)
 		if err != nil {
 			utils.ErrorResponse(w, r, http.StatusUnauthorized, err)
 			return
 		}
 
+		if claims.SessionID == "" {
+			utils.ErrorResponse(w, r, http.StatusUnauthorized, errors.New("token has no session"))
+			return
+		}
+		if sessionStore != nil {
+			active, err := sessionStore.IsSessionActive(claims.SessionID)
+			if err != nil {
+				utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+				return
+			}
+			if !active {
+				utils.ErrorResponse(w, r, http.StatusUnauthorized, errors.New("session revoked"))
+				return
+			}
+		}
+
 		role := claims.Role
 		if role == "" {
 			role = models.RoleUser
@@ -46,6 +87,40 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, contextkeys.UserContextKey, claims.UserID)
 		ctx = context.WithValue(ctx, contextkeys.RoleContextKey, role)
+		ctx = context.WithValue(ctx, contextkeys.SessionContextKey, claims.SessionID)
+		ctx = context.WithValue(ctx, contextkeys.MFAContextKey, claims.MFA)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// authenticateAPIToken resolves a personal access token. The role is read from the
+// user record on every request so demotions apply immediately. Tokens count as
+// second-factor authenticated while their owner has 2FA enabled.
+func authenticateAPIToken(w http.ResponseWriter, r *http.Request, next http.Handler, raw string) {
+	if apiTokenStore == nil || userStore == nil {
+		utils.ErrorResponse(w, r, http.StatusUnauthorized, errors.New("API tokens are not enabled"))
+		return
+	}
+	token, err := apiTokenStore.Authenticate(raw, utils.ClientIP(r))
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	user, err := userStore.FindByID(token.UserID)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusUnauthorized, errors.New("invalid API token"))
+		return
+	}
+
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+
+	ctx := r.Context()
+	ctx = context.WithValue(ctx, contextkeys.UserContextKey, user.ID)
+	ctx = context.WithValue(ctx, contextkeys.RoleContextKey, role)
+	ctx = context.WithValue(ctx, contextkeys.ScopesContextKey, token.Scopes)
+	ctx = context.WithValue(ctx, contextkeys.MFAContextKey, user.TwoFactorEnabled)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
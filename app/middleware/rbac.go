@@ -5,17 +5,33 @@ import (
 	"net/http"
 
 	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
 	"knowledge-capsule/pkg/utils"
 )
 
+var settingsStore store.SettingsStore
+
+// InitRBAC sets the settings store used for the admin two-factor policy.
+func InitRBAC(settings store.SettingsStore) {
+	settingsStore = settings
+}
+
+// IsAdmin reports whether the authenticated user's role is admin or superadmin.
+func IsAdmin(r *http.Request) bool {
+	role, _ := r.Context().Value(RoleContextKey).(string)
+	return role == models.RoleAdmin || role == models.RoleSuperAdmin
+}
+
 // RequireAdmin wraps a handler and returns 403 if the user's role is not admin or superadmin.
 func RequireAdmin(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		role, _ := r.Context().Value(RoleContextKey).(string)
-		if role != models.RoleAdmin && role != models.RoleSuperAdmin {
+		if !IsAdmin(r) {
 			utils.ErrorResponse(w, r, http.StatusForbidden, errors.New("admin access required"))
 			return
 		}
+		if !checkAdminTwoFactor(w, r) {
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
@@ -28,6 +44,27 @@ func RequireSuperAdmin(next http.Handler) http.Handler {
 			utils.ErrorResponse(w, r, http.StatusForbidden, errors.New("superadmin access required"))
 			return
 		}
+		if !checkAdminTwoFactor(w, r) {
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// checkAdminTwoFactor enforces the superadmin-controlled policy that admin access
+// needs a login completed with a second factor.
+func checkAdminTwoFactor(w http.ResponseWriter, r *http.Request) bool {
+	if settingsStore == nil {
+		return true
+	}
+	required, err := settingsStore.GetBool(models.SettingRequireAdmin2FA)
+	if err != nil {
+		utils.ErrorResponse(w, r, http.StatusInternalServerError, err)
+		return false
+	}
+	if mfa, _ := r.Context().Value(MFAContextKey).(bool); required && !mfa {
+		utils.ErrorResponse(w, r, http.StatusForbidden, errors.New("two-factor authentication required for admin access; enable it at /api/users/me/2fa and sign in again"))
+		return false
+	}
+	return true
+}
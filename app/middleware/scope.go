@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/utils"
+)
+
+// RequireScope returns a middleware that checks personal access token scopes:
+// readScope for GET/HEAD requests and writeScope for everything else.
+// Requests authenticated with a session JWT are not restricted.
+func RequireScope(readScope, writeScope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, isToken := r.Context().Value(ScopesContextKey).(models.Scopes)
+			if !isToken {
+				next.ServeHTTP(w, r)
+				return
+			}
+			scope := writeScope
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				scope = readScope
+			}
+			if !scopes.Has(scope) {
+				utils.ErrorResponse(w, r, http.StatusForbidden, errors.New("API token lacks scope: "+scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HasScope reports whether the request may use scope. Only personal access tokens are
+// restricted; requests authenticated with a session JWT have every scope.
+func HasScope(r *http.Request, scope string) bool {
+	scopes, isToken := r.Context().Value(ScopesContextKey).(models.Scopes)
+	return !isToken || scopes.Has(scope)
+}
+
+// RejectAPITokens returns 403 for requests authenticated with a personal access token.
+// Used on account management routes that need an interactive login.
+func RejectAPITokens(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, isToken := r.Context().Value(ScopesContextKey).(models.Scopes); isToken {
+			utils.ErrorResponse(w, r, http.StatusForbidden, errors.New("API tokens cannot access this endpoint"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
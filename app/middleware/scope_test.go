@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/app/store"
+)
+
+// fakeAPITokens resolves raw tokens from a map; other methods are not used.
+type fakeAPITokens struct {
+	store.APITokenStore
+	tokens map[string]*models.APIToken
+}
+
+func (f fakeAPITokens) Authenticate(raw, _ string) (*models.APIToken, error) {
+	if token, ok := f.tokens[raw]; ok {
+		return token, nil
+	}
+	return nil, errors.New("invalid API token")
+}
+
+type fakeUsers struct {
+	store.UserStore
+	users map[string]*models.User
+}
+
+func (f fakeUsers) FindByID(id string) (*models.User, error) {
+	if user, ok := f.users[id]; ok {
+		return user, nil
+	}
+	return nil, errors.New("user not found")
+}
+
+func TestRequireScope(t *testing.T) {
+	InitAuth(nil, fakeAPITokens{tokens: map[string]*models.APIToken{
+		"kc_pat_read":  {UserID: "u1", Scopes: models.Scopes{models.ScopeCapsulesRead}},
+		"kc_pat_write": {UserID: "u1", Scopes: models.Scopes{models.ScopeCapsulesWrite}},
+		"kc_pat_admin": {UserID: "admin", Scopes: models.Scopes{models.ScopeAdmin}},
+		"kc_pat_none":  {UserID: "u1", Scopes: models.Scopes{}},
+	}}, fakeUsers{users: map[string]*models.User{
+		"u1":    {ID: "u1", Role: models.RoleUser},
+		"admin": {ID: "admin", Role: models.RoleAdmin},
+	}})
+	t.Cleanup(func() { InitAuth(nil, nil, nil) })
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	capsules := AuthMiddleware(RequireScope(models.ScopeCapsulesRead, models.ScopeCapsulesWrite)(ok))
+	admin := AuthMiddleware(RequireScope(models.ScopeAdmin, models.ScopeAdmin)(ok))
+	account := AuthMiddleware(RejectAPITokens(ok))
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		method  string
+		token   string
+		want    int
+	}{
+		{"read scope reads", capsules, http.MethodGet, "kc_pat_read", http.StatusOK},
+		{"read scope heads", capsules, http.MethodHead, "kc_pat_read", http.StatusOK},
+		{"read scope cannot write", capsules, http.MethodPost, "kc_pat_read", http.StatusForbidden},
+		{"read scope cannot delete", capsules, http.MethodDelete, "kc_pat_read", http.StatusForbidden},
+		{"write scope writes", capsules, http.MethodPatch, "kc_pat_write", http.StatusOK},
+		{"write scope implies read", capsules, http.MethodGet, "kc_pat_write", http.StatusOK},
+		{"no scopes", capsules, http.MethodGet, "kc_pat_none", http.StatusForbidden},
+		{"capsule scope on admin route", admin, http.MethodGet, "kc_pat_write", http.StatusForbidden},
+		{"admin scope on admin route", admin, http.MethodGet, "kc_pat_admin", http.StatusOK},
+		{"admin scope on capsule route", capsules, http.MethodGet, "kc_pat_admin", http.StatusForbidden},
+		{"unknown token", capsules, http.MethodGet, "kc_pat_unknown", http.StatusUnauthorized},
+		{"token on account route", account, http.MethodGet, "kc_pat_admin", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/capsules", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	session := httptest.NewRequest(http.MethodGet, "/", nil)
+	if !HasScope(session, models.ScopeAdmin) {
+		t.Error("HasScope() restricted a session request")
+	}
+
+	ctx := context.WithValue(session.Context(), ScopesContextKey, models.Scopes{models.ScopeTopicsWrite})
+	token := session.WithContext(ctx)
+	for scope, want := range map[string]bool{
+		models.ScopeTopicsWrite:   true,
+		models.ScopeTopicsRead:    true,
+		models.ScopeCapsulesRead:  false,
+		models.ScopeCapsulesWrite: false,
+		models.ScopeAdmin:         false,
+	} {
+		if got := HasScope(token, scope); got != want {
+			t.Errorf("HasScope(%s) = %v, want %v", scope, got, want)
+		}
+	}
+}
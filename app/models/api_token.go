@@ -0,0 +1,87 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// API token scopes.
+const (
+	ScopeCapsulesRead  = "capsules:read"
+	ScopeCapsulesWrite = "capsules:write"
+	ScopeTopicsRead    = "topics:read"
+	ScopeTopicsWrite   = "topics:write"
+	ScopeChat          = "chat"
+	ScopeAdmin         = "admin"
+)
+
+// ValidScopes lists every scope an API token may be granted.
+var ValidScopes = []string{ScopeCapsulesRead, ScopeCapsulesWrite, ScopeTopicsRead, ScopeTopicsWrite, ScopeChat, ScopeAdmin}
+
+// APITokenPrefix marks personal access tokens so they can be told apart from JWTs.
+const APITokenPrefix = "kc_pat_"
+
+// Scopes stores a list of API token scopes as JSON in the database.
+type Scopes []string
+
+// Has reports whether scope is granted. A write scope implies the matching read scope.
+func (s Scopes) Has(scope string) bool {
+	for _, granted := range s {
+		if granted == scope {
+			return true
+		}
+		if scope == ScopeCapsulesRead && granted == ScopeCapsulesWrite ||
+			scope == ScopeTopicsRead && granted == ScopeTopicsWrite {
+			return true
+		}
+	}
+	return false
+}
+
+// Value implements driver.Valuer for GORM.
+func (s Scopes) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	return json.Marshal(s)
+}
+
+// Scan implements sql.Scanner for GORM.
+func (s *Scopes) Scan(value interface{}) error {
+	if value == nil {
+		*s = []string{}
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("scopes: invalid type for scan")
+	}
+	return json.Unmarshal(bytes, s)
+}
+
+// APITokenInput request body for POST /api/users/me/tokens and PATCH /api/users/me/tokens/{id}
+type APITokenInput struct {
+	Name          string `json:"name" example:"CI deploy"`
+	Scopes        Scopes `json:"scopes" example:"capsules:read,capsules:write" swaggertype:"array,string"`
+	ExpiresInDays int    `json:"expires_in_days,omitempty" example:"90"`
+}
+
+// APIToken is a personal access token. Only the SHA-256 hash of the token is stored;
+// Prefix is kept so users can recognise a token in listings.
+type APIToken struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID     string     `json:"user_id" gorm:"index;not null"`
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"size:16"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	Scopes     Scopes     `json:"scopes" gorm:"type:jsonb" swaggertype:"array,string"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (APIToken) TableName() string { return "api_tokens" }
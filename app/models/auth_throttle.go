@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+)
+
+// Throttle scopes
+const (
+	ThrottleScopeIP         = "ip"          // failed logins from one IP address
+	ThrottleScopeAccount    = "account"     // failed logins for one email
+	ThrottleScopeRegister   = "register"    // registration attempts from one IP address
+	ThrottleScopeLink       = "link"        // wrong passwords for one public link, overall and from one IP address
+	ThrottleScopeMail       = "mail"        // reset and verification emails to one address
+	ThrottleScopeMailIP     = "mail_ip"     // reset and verification emails requested from one IP address
+	ThrottleScopeShareEmail = "share_email" // unregistered emails one user tried to share with or invite to a group
+)
+
+// AuthThrottle counts recent failed attempts for one IP address or account and
+// records a temporary lockout once the policy threshold is reached.
+type AuthThrottle struct {
+	Key           string     `json:"key" gorm:"primaryKey;size:320"`
+	Scope         string     `json:"scope" gorm:"size:16;index;not null"`
+	Subject       string     `json:"subject" gorm:"not null"`
+	Failures      int        `json:"failures"`
+	LockedUntil   *time.Time `json:"locked_until" gorm:"index"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (AuthThrottle) TableName() string { return "auth_throttles" }
+
+// ThrottleKey builds the AuthThrottle key for a scope and subject.
+func ThrottleKey(scope, subject string) string {
+	return scope + ":" + subject
+}
+
+// ThrottlePolicy sets when failures lock out and for how long. The lockout doubles with
+// every failure past MaxFailures, up to MaxLockout. Failures are forgotten after Window
+// without a new failure or lockout.
+type ThrottlePolicy struct {
+	MaxFailures int
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	Window      time.Duration
+}
+
+// LockoutFor returns the lockout after the given number of failures, or 0 if none.
+func (p ThrottlePolicy) LockoutFor(failures int) time.Duration {
+	if p.MaxFailures <= 0 || failures < p.MaxFailures {
+		return 0
+	}
+	d := p.BaseLockout
+	for i := p.MaxFailures; i < failures && d < p.MaxLockout; i++ {
+		d *= 2
+	}
+	if d > p.MaxLockout {
+		d = p.MaxLockout
+	}
+	return d
+}
@@ -2,15 +2,20 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // CapsuleInput request body for POST /api/capsules and PUT /api/capsules/{id}
 type CapsuleInput struct {
 	Title     string   `json:"title" example:"Interfaces in Go" gorm:"not null"`
 	Content   string   `json:"content" example:"Interfaces are named collections of method signatures..."`
-	Topic     string   `json:"topic" example:"Golang"`
+	Topic     string   `json:"topic" example:"Golang"` // topic name; kept in sync with TopicID
+	TopicID   *string  `json:"topic_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000" gorm:"type:varchar(36);index"`
 	Tags      Tags     `json:"tags" example:"programming,go" gorm:"type:jsonb"`
 	IsPrivate bool     `json:"is_private" example:"false" gorm:"default:false"`
+	// plain (default) or markdown
+	ContentFormat string `json:"content_format" example:"markdown" gorm:"size:20;default:plain"`
 }
 
 // Capsule extends CapsuleInput with ID, UserID and timestamps
@@ -20,6 +25,14 @@ type Capsule struct {
 	CapsuleInput
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	ViewCount int64     `json:"view_count" gorm:"default:0"` // public reads via explore and links
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"` // set while the capsule is in the trash
+	// Caller's access when it is not the owner: viewer, commenter or editor
+	Permission string `json:"permission,omitempty" gorm:"-"`
+	// Breadcrumb from the root topic down to the capsule's topic
+	TopicPath []TopicRef `json:"topic_path,omitempty" gorm:"-"`
+	// Content as sanitized HTML with its table of contents, when asked for with ?render=html
+	Rendered *RenderedContent `json:"rendered,omitempty" gorm:"-"`
 }
 
 func (Capsule) TableName() string { return "capsules" }
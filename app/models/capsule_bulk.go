@@ -0,0 +1,58 @@
+package models
+
+// Bulk actions for POST /api/capsules/bulk
+const (
+	BulkDelete     = "delete" // move to the trash
+	BulkAddTags    = "add_tags"
+	BulkRemoveTags = "remove_tags"
+	BulkSetTopic   = "set_topic"
+	BulkSetPrivate = "set_private"
+)
+
+// Bulk item statuses
+const (
+	BulkUpdated    = "updated"
+	BulkDeleted    = "deleted"
+	BulkUnchanged  = "unchanged" // the action would not change the capsule
+	BulkFailed     = "failed"
+	BulkRolledBack = "rolled_back" // succeeded, then undone because an atomic operation failed
+)
+
+// MaxBulkCapsules is the most capsules one bulk operation may change.
+const MaxBulkCapsules = 500
+
+// BulkInput request body for POST /api/capsules/bulk. The capsules are given by IDs, or by a
+// filter in the query syntax of GET /api/capsules (e.g. "topic=Golang&tags=draft"), which
+// matches the caller's own capsules.
+type BulkInput struct {
+	Action string   `json:"action" example:"add_tags"`
+	IDs    []string `json:"ids,omitempty"`
+	Filter string   `json:"filter,omitempty" example:"topic=Golang&tags=draft"`
+	// Tags to add or remove
+	Tags    Tags    `json:"tags,omitempty" example:"go,reviewed"`
+	Topic   string  `json:"topic,omitempty" example:"Golang"`
+	TopicID *string `json:"topic_id,omitempty"`
+	// Privacy to set; without it set_private toggles each capsule
+	IsPrivate *bool `json:"is_private,omitempty"`
+	// Roll back every change when any capsule fails
+	Atomic bool `json:"atomic"`
+}
+
+// BulkItemResult reports what a bulk operation did with one capsule.
+type BulkItemResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status" example:"updated"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkResult is the response of POST /api/capsules/bulk. Applied is false when an atomic
+// operation was rolled back.
+type BulkResult struct {
+	Action    string           `json:"action" example:"add_tags"`
+	Atomic    bool             `json:"atomic"`
+	Applied   bool             `json:"applied"`
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Items     []BulkItemResult `json:"items"`
+}
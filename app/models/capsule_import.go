@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Export and import formats for /api/capsules/export and /api/capsules/import. Obsidian
+// vaults are import only.
+const (
+	ExportFormatJSON        = "json"
+	ExportFormatMarkdownZip = "markdown-zip"
+	ImportFormatObsidian    = "obsidian"
+)
+
+// What an import does with an item whose title matches one of the user's capsules
+const (
+	DuplicateSkip   = "skip"
+	DuplicateUpdate = "update" // the existing capsule gets a new revision
+	DuplicateCreate = "create" // imported as another capsule
+)
+
+// Import item statuses. A dry run reports what an import would do with the same statuses.
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped"
+	ImportFailed  = "failed"
+)
+
+// CapsuleExportVersion is the version of the JSON export layout.
+const CapsuleExportVersion = 1
+
+// CapsuleExport is a capsule as exported, and as read from an import. In Markdown files the
+// fields other than content are the YAML front matter.
+type CapsuleExport struct {
+	Title         string     `json:"title" yaml:"title" example:"Interfaces in Go"`
+	Content       string     `json:"content" yaml:"-"`
+	ContentFormat string     `json:"content_format" yaml:"content_format" example:"markdown"`
+	Topic         string     `json:"topic" yaml:"topic,omitempty" example:"Golang"`
+	Tags          Tags       `json:"tags" yaml:"tags,omitempty"`
+	IsPrivate     bool       `json:"is_private" yaml:"is_private"`
+	CreatedAt     *time.Time `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty" yaml:"updated_at,omitempty"`
+}
+
+// CapsuleExportFile is the JSON export. Imports also take a bare array of capsules.
+type CapsuleExportFile struct {
+	Version    int             `json:"version" example:"1"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Capsules   []CapsuleExport `json:"capsules"`
+}
+
+// ImportItem is one capsule read from an import, with where it came from: its path in an
+// archive or vault, or its index in a JSON import.
+type ImportItem struct {
+	Source  string
+	Capsule CapsuleExport
+	Err     error // set when the item could not be read
+}
+
+// ImportItemResult reports what an import did with one item. DuplicateOf is the ID of the
+// capsule with the same title, or the source of an earlier item in the same import.
+type ImportItemResult struct {
+	Source      string `json:"source" example:"Golang/Interfaces in Go.md"`
+	Title       string `json:"title,omitempty" example:"Interfaces in Go"`
+	Status      string `json:"status" example:"created"`
+	CapsuleID   string `json:"capsule_id,omitempty"`
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ImportReport is the response of POST /api/capsules/import.
+type ImportReport struct {
+	DryRun  bool               `json:"dry_run"`
+	Total   int                `json:"total"`
+	Created int                `json:"created"`
+	Updated int                `json:"updated"`
+	Skipped int                `json:"skipped"`
+	Failed  int                `json:"failed"`
+	Items   []ImportItemResult `json:"items"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// CapsuleLink is a read-only public URL (/p/{slug}) for a public capsule. Anyone with the link
+// can read the capsule until the link expires or is deleted; it stops working while the capsule
+// is private.
+type CapsuleLink struct {
+	ID                string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	CapsuleID         string     `json:"capsule_id" gorm:"type:varchar(36);index;not null"`
+	Slug              string     `json:"slug" gorm:"size:64;uniqueIndex;not null"`
+	URL               string     `json:"url" gorm:"-"`
+	PasswordHash      string     `json:"-"`
+	PasswordProtected bool       `json:"password_protected" gorm:"default:false"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	ViewCount         int64      `json:"view_count" gorm:"default:0"`
+	CreatedBy         string     `json:"created_by" gorm:"type:varchar(36)"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+func (CapsuleLink) TableName() string { return "capsule_links" }
+
+// Expired reports whether the link has passed its expiry time.
+func (l *CapsuleLink) Expired() bool {
+	return l.ExpiresAt != nil && time.Now().After(*l.ExpiresAt)
+}
+
+// CapsuleLinkInput request body for POST /api/capsules/{id}/links. Slug is optional;
+// a random one is generated when empty.
+type CapsuleLinkInput struct {
+	Slug      string     `json:"slug" example:"go-interfaces"`
+	Password  string     `json:"password"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// PublicCapsule is the read-only view of a capsule served to anonymous readers.
+// Content is omitted from listings, which carry an excerpt instead.
+type PublicCapsule struct {
+	ID            string           `json:"id"`
+	Title         string           `json:"title"`
+	Content       string           `json:"content,omitempty"`
+	ContentFormat string           `json:"content_format"`
+	Excerpt       string           `json:"excerpt,omitempty"`
+	Topic         string           `json:"topic"`
+	Tags          Tags             `json:"tags"`
+	Author        string           `json:"author"`
+	ViewCount     int64            `json:"view_count"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	Rendered      *RenderedContent `json:"rendered,omitempty" gorm:"-"`
+}
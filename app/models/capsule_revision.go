@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// Diff operations
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// CapsuleRevision is an immutable snapshot of a capsule written on every create,
+// update and restore. Revision numbers start at 1 for each capsule.
+type CapsuleRevision struct {
+	ID           string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	CapsuleID    string `json:"capsule_id" gorm:"not null;uniqueIndex:idx_capsule_revision"`
+	Revision     int    `json:"revision" gorm:"not null;uniqueIndex:idx_capsule_revision"`
+	EditorID     string `json:"editor_id" gorm:"not null"`
+	RestoredFrom *int   `json:"restored_from,omitempty"`
+	CapsuleInput
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (CapsuleRevision) TableName() string { return "capsule_revisions" }
+
+// FieldChange is a changed capsule field between two revisions.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// DiffLine is one line of a line-based diff. OldLine / NewLine are 1-based line
+// numbers in the old and new text, or 0 when the line is absent from that side.
+type DiffLine struct {
+	Op      string `json:"op" example:"insert"`
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// RevisionDiff response for GET /api/capsules/{id}/revisions/diff
+type RevisionDiff struct {
+	From    int           `json:"from"`
+	To      int           `json:"to"`
+	Changes []FieldChange `json:"changes"`
+	Added   int           `json:"added"`
+	Removed int           `json:"removed"`
+	Lines   []DiffLine    `json:"lines"`
+	Unified string        `json:"unified"`
+}
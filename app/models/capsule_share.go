@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Capsule permissions, from least to most access. The owner always has full access.
+const (
+	PermissionViewer    = "viewer"
+	PermissionCommenter = "commenter"
+	PermissionEditor    = "editor"
+	PermissionOwner     = "owner"
+)
+
+// Share grantee types.
+const (
+	GranteeUser  = "user"
+	GranteeGroup = "group"
+)
+
+// permissionRanks orders permissions so the strongest of several grants wins.
+var permissionRanks = map[string]int{
+	PermissionViewer:    1,
+	PermissionCommenter: 2,
+	PermissionEditor:    3,
+	PermissionOwner:     4,
+}
+
+// ValidSharePermission reports whether p can be granted with a share.
+func ValidSharePermission(p string) bool {
+	return p == PermissionViewer || p == PermissionCommenter || p == PermissionEditor
+}
+
+// PermissionAtLeast reports whether permission p includes want.
+func PermissionAtLeast(p, want string) bool {
+	return p != "" && permissionRanks[p] >= permissionRanks[want]
+}
+
+// StrongerPermission returns whichever of a and b grants more access.
+func StrongerPermission(a, b string) string {
+	if permissionRanks[b] > permissionRanks[a] {
+		return b
+	}
+	return a
+}
+
+// CapsuleShare grants a user, or every member of a group, access to a capsule.
+type CapsuleShare struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	CapsuleID   string    `json:"capsule_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_capsule_share_grantee"`
+	GranteeType string    `json:"grantee_type" gorm:"size:10;not null;uniqueIndex:idx_capsule_share_grantee;index:idx_capsule_share_lookup"`
+	GranteeID   string    `json:"grantee_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_capsule_share_grantee;index:idx_capsule_share_lookup"`
+	GranteeName string    `json:"grantee_name" gorm:"-"` // user or group name, filled in for listings
+	Permission  string    `json:"permission" gorm:"size:20;not null"`
+	GrantedBy   string    `json:"granted_by" gorm:"type:varchar(36)"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (CapsuleShare) TableName() string { return "capsule_shares" }
+
+// CapsuleShareInput request body for POST /api/capsules/{id}/shares. A user grantee may be
+// given by email instead of ID. Sharing again with the same grantee changes its permission.
+type CapsuleShareInput struct {
+	GranteeType string `json:"grantee_type" example:"user"`
+	GranteeID   string `json:"grantee_id"`
+	Email       string `json:"email" example:"jane@example.com"`
+	Permission  string `json:"permission" example:"viewer"`
+}
@@ -1,14 +1,28 @@
 package models
 
+import "time"
+
 // CapsuleFilters for GET /api/capsules
 type CapsuleFilters struct {
-	Topic     string
-	Tags      []string
-	Q         string
-	IsPrivate *bool
+	Topic            string // topic name, case-insensitive
+	TopicID          string
+	IncludeSubtopics bool // also match capsules in descendants of the Topic or TopicID topic
+	Tags             []string
+	Q                string
+	IsPrivate        *bool
+	CreatedFrom      *time.Time // inclusive
+	CreatedBefore    *time.Time // exclusive
 }
 
 // TopicFilters for GET /api/topics
 type TopicFilters struct {
 	Q string
 }
+
+// ExploreFilters for GET /api/explore
+type ExploreFilters struct {
+	Topic string
+	Tag   string
+	Q     string
+	Sort  string // "recent" (default) or "popular"
+}
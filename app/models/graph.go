@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Graph node types
+const (
+	GraphNodeCapsule = "capsule"
+	GraphNodeTopic   = "topic"
+	GraphNodeTag     = "tag"
+)
+
+// Graph edge types. Link edges point from the linking capsule to the linked one; the others
+// are undirected.
+const (
+	GraphEdgeLink       = "link"        // explicit [[...]] link
+	GraphEdgeSharedTags = "shared_tags" // weight is the number of tags two capsules share
+	GraphEdgeSameTopic  = "same_topic"
+	GraphEdgeTopic      = "topic" // capsule to its topic
+	GraphEdgeTag        = "tag"   // capsule to one of its tags
+)
+
+// Graph formats for GET /api/graph
+const (
+	GraphFormatJSON    = "json"
+	GraphFormatGraphML = "graphml"
+	GraphFormatDOT     = "dot"
+)
+
+// GraphCapsule is the part of a capsule the knowledge graph is built from.
+type GraphCapsule struct {
+	ID        string
+	Title     string
+	TopicID   *string
+	Topic     string
+	Tags      Tags
+	UpdatedAt time.Time
+}
+
+// GraphNode is a capsule, topic or tag. IDs are prefixed with the type, e.g. "tag:go".
+type GraphNode struct {
+	ID    string `json:"id" example:"capsule:550e8400-e29b-41d4-a716-446655440000"`
+	Type  string `json:"type" example:"capsule"`
+	Label string `json:"label" example:"Interfaces in Go"` // capsule title, topic name or tag slug
+}
+
+// GraphEdge connects two nodes by their IDs.
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type" example:"shared_tags"`
+	Weight int    `json:"weight" example:"2"`
+}
+
+// Graph response for GET /api/graph. Truncated is set when capsules were left out to stay
+// within the limit.
+type Graph struct {
+	Nodes     []GraphNode `json:"nodes"`
+	Edges     []GraphEdge `json:"edges"`
+	Truncated bool        `json:"truncated"`
+}
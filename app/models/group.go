@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// GroupInput request body for POST /api/groups and PATCH /api/groups/{id}
+type GroupInput struct {
+	Name string `json:"name" example:"Backend team"`
+}
+
+// Group is a named set of users that capsules can be shared with. Only its owner
+// manages the name and members.
+type Group struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	OwnerID     string    `json:"owner_id" gorm:"type:varchar(36);index;not null"`
+	Name        string    `json:"name" gorm:"not null"`
+	MemberCount int       `json:"member_count" gorm:"-"`                          // active members
+	Membership  string    `json:"membership,omitempty" gorm:"-" example:"active"` // the caller's status in the group
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (Group) TableName() string { return "groups" }
+
+// Group membership states. Users the owner adds are invited and join once they accept.
+const (
+	GroupMemberInvited = "invited"
+	GroupMemberActive  = "active"
+)
+
+// GroupMember links a user to a group. Only active members get the group's shares.
+type GroupMember struct {
+	GroupID   string    `json:"group_id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string    `json:"user_id" gorm:"primaryKey;type:varchar(36);index"`
+	Status    string    `json:"status" gorm:"size:10;default:active;index"` // existing rows migrate as active
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (GroupMember) TableName() string { return "group_members" }
+
+// GroupMemberInput request body for POST /api/groups/{id}/members. Give either user_id or email.
+type GroupMemberInput struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email" example:"jane@example.com"`
+}
+
+// GroupMemberView is a member or invited user as listed by GET /api/groups/{id}.
+type GroupMemberView struct {
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status" example:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
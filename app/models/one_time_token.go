@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposeLoginChallenge    = "login_challenge"
+)
+
+// OneTimeToken is a single-use, expiring token emailed to a user (password reset, email verification).
+// Only the SHA-256 hash of the token is stored.
+type OneTimeToken struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string     `json:"user_id" gorm:"index;not null"`
+	Purpose   string     `json:"purpose" gorm:"size:32;index;not null"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (OneTimeToken) TableName() string { return "one_time_tokens" }
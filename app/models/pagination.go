@@ -0,0 +1,33 @@
+package models
+
+// Sort keys accepted by list endpoints
+const (
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+	SortTitle     = "title"
+	SortName      = "name"
+	SortDeletedAt = "deleted_at"
+	SortRelevance = "relevance" // only with a search query
+)
+
+// Sort orders accepted by list endpoints
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// ListOptions selects one page of a list and its order. When Cursor is set it replaces Page.
+type ListOptions struct {
+	Page   int
+	Limit  int
+	Sort   string // empty for the list's default
+	Order  string // empty for the sort key's default
+	Cursor string // opaque next_cursor from the previous page
+}
+
+// PageInfo describes a loaded page: the total across all pages and the cursor for the next
+// page, empty on the last one.
+type PageInfo struct {
+	Total      int
+	NextCursor string
+}
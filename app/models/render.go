@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Capsule content formats
+const (
+	ContentPlain    = "plain"
+	ContentMarkdown = "markdown"
+)
+
+// ValidContentFormat reports whether f is a known content format.
+func ValidContentFormat(f string) bool {
+	return f == ContentPlain || f == ContentMarkdown
+}
+
+// RenderHTML is the render query value that asks for rendered content.
+const RenderHTML = "html"
+
+// TOCEntry is a heading in rendered content. ID is the anchor of the heading element.
+type TOCEntry struct {
+	Level int    `json:"level" example:"2"`
+	Text  string `json:"text" example:"Embedding interfaces"`
+	ID    string `json:"id" example:"embedding-interfaces"`
+}
+
+// RenderedContent is capsule content rendered to sanitized HTML, with its table of contents
+// and the languages of its code blocks, as given or detected.
+type RenderedContent struct {
+	HTML          string     `json:"html"`
+	TOC           []TOCEntry `json:"toc"`
+	CodeLanguages []string   `json:"code_languages"`
+}
+
+// CapsuleRender caches a capsule's rendered content. Hash identifies the content, format and
+// renderer version it was rendered from; a render whose hash no longer matches is stale.
+type CapsuleRender struct {
+	CapsuleID  string          `gorm:"primaryKey;type:varchar(36)"`
+	Hash       string          `gorm:"size:64;not null"`
+	Output     RenderedContent `gorm:"type:jsonb;serializer:json"`
+	RenderedAt time.Time       `gorm:"autoCreateTime"`
+}
+
+func (CapsuleRender) TableName() string { return "capsule_renders" }
@@ -15,4 +15,13 @@ type PaginatedResponse struct {
 	Page    int         `json:"page"`
 	Limit   int         `json:"limit"`
 	Total   int         `json:"total"`
+	// NextCursor continues after this page; empty on the last page
+	NextCursor string `json:"next_cursor,omitempty" example:"eyJzIjoidXBkYXRlZF9hdCIsIm8iOiJkZXNjIn0"`
+}
+
+// CapsuleSearchResponse is the response format for GET /api/capsules/search: a page of
+// models.CapsuleSearchResult with facets over all matches.
+type CapsuleSearchResponse struct {
+	PaginatedResponse
+	Facets CapsuleFacets `json:"facets"`
 }
@@ -5,3 +5,49 @@ type SearchResult struct {
 	Count    int       `json:"count"`
 	Query    string    `json:"query"`
 }
+
+// CapsuleSearchResult is a capsule matched by full-text search, with its relevance and
+// a content snippet in which matched words are wrapped in <mark> tags. The rest of the
+// snippet is HTML-escaped.
+type CapsuleSearchResult struct {
+	Capsule
+	Rank     float64 `json:"rank" example:"0.6079271"`
+	Headline string  `json:"headline" example:"Interfaces are named collections of <mark>method</mark> signatures"`
+}
+
+// Suggestion types returned by GET /api/search/suggest
+const (
+	SuggestionTitle = "title"
+	SuggestionTag   = "tag"
+	SuggestionTopic = "topic"
+)
+
+// Suggestion is an autocomplete candidate for a partly typed search query.
+type Suggestion struct {
+	Type      string  `json:"type" example:"title"` // title, tag or topic
+	Text      string  `json:"text" example:"Interfaces in Go"`
+	CapsuleID string  `json:"capsule_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"` // set for titles
+	Score     float64 `json:"score" example:"1.5"`
+}
+
+// Created-at bucket sizes for capsule facets
+const (
+	FacetIntervalWeek  = "week"
+	FacetIntervalMonth = "month"
+)
+
+// FacetCount is how many capsules in a result set share one facet value.
+type FacetCount struct {
+	Value string `json:"value" example:"golang"`
+	Count int64  `json:"count" example:"12"`
+}
+
+// CapsuleFacets counts a capsule result set by tag, topic, privacy and creation date.
+// Created buckets are labelled with their first day (YYYY-MM-DD), newest first.
+type CapsuleFacets struct {
+	Tags     []FacetCount `json:"tags"`
+	Topics   []FacetCount `json:"topics"`
+	Privacy  []FacetCount `json:"privacy"` // "private" and "public"
+	Created  []FacetCount `json:"created"`
+	Interval string       `json:"interval" example:"month"` // week or month
+}
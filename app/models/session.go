@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is one refresh token in a login session. Every refresh rotates the
+// token: the old row is marked rotated and a new row is created with the same
+// FamilyID, so the family is the session the user sees and can revoke.
+type Session struct {
+	ID        string     `json:"-" gorm:"primaryKey;type:varchar(36)"`
+	FamilyID  string     `json:"id" gorm:"index;not null;type:varchar(36)"`
+	UserID    string     `json:"user_id" gorm:"index;not null"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	UserAgent string     `json:"user_agent"`
+	IPAddress string     `json:"ip_address"`
+	MFA       bool       `json:"mfa" gorm:"default:false"`
+	StartedAt time.Time  `json:"started_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RotatedAt *time.Time `json:"-"`
+	RevokedAt *time.Time `json:"-"`
+	CreatedAt time.Time  `json:"last_used_at"`
+	Current   bool       `json:"current" gorm:"-"`
+}
+
+func (Session) TableName() string { return "sessions" }
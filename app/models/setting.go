@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Setting keys
+const (
+	SettingRequireAdmin2FA = "require_admin_2fa"
+)
+
+// Setting is a global key/value setting managed by superadmins.
+type Setting struct {
+	Key       string    `json:"key" gorm:"primaryKey;size:64"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Setting) TableName() string { return "settings" }
+
+// SecuritySettings request/response body for /api/admin/security
+type SecuritySettings struct {
+	RequireAdmin2FA bool `json:"require_admin_2fa" example:"true"`
+}
@@ -4,8 +4,15 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+// MaxTagLen is the longest tag slug; longer tags are cut.
+const MaxTagLen = 50
+
 // Tags stores a string slice as JSON in the database.
 type Tags []string
 
@@ -29,3 +36,100 @@ func (t *Tags) Scan(value interface{}) error {
 	}
 	return json.Unmarshal(bytes, t)
 }
+
+// TagSlug returns the canonical form of a tag: lowercase, with runs of spaces, hyphens,
+// underscores and slashes joined by one hyphen. Other characters than letters, digits and
+// + # . are dropped, so "Go ", "go" and "GO" are the same tag and "C++" stays "c++".
+func TagSlug(tag string) string {
+	var b strings.Builder
+	gap := false
+	for _, r := range strings.ToLower(tag) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '#' || r == '.':
+			if gap && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			gap = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '_' || r == '/':
+			gap = true
+		}
+	}
+	slug := b.String()
+	for len(slug) > MaxTagLen {
+		_, size := utf8.DecodeLastRuneInString(slug)
+		slug = slug[:len(slug)-size]
+	}
+	return strings.TrimRight(slug, "-")
+}
+
+// NormalizeTags returns the slugs of tags in their original order, without blanks and
+// duplicates.
+func NormalizeTags(tags Tags) Tags {
+	slugs := Tags{}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if slug := TagSlug(tag); slug != "" && !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs
+}
+
+// Tag is a canonical tag in the registry. Capsules carry tag slugs.
+type Tag struct {
+	ID         string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	Slug       string    `json:"slug" example:"go" gorm:"size:50;uniqueIndex;not null"`
+	UsageCount int64     `json:"usage_count" gorm:"->;-:migration"` // capsules outside the trash using the tag
+	Aliases    []string  `json:"aliases,omitempty" gorm:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (Tag) TableName() string { return "tags" }
+
+// TagAlias maps another spelling to a canonical tag, e.g. golang to go. Capsule tags that
+// match an alias are saved as its tag.
+type TagAlias struct {
+	Slug      string    `json:"alias" gorm:"primaryKey;size:50"`
+	TagID     string    `json:"tag_id" gorm:"type:varchar(36);index;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (TagAlias) TableName() string { return "tag_aliases" }
+
+// TagAliasView is an alias as listed by GET /api/admin/tags/aliases.
+type TagAliasView struct {
+	Alias     string    `json:"alias" example:"golang"`
+	Tag       string    `json:"tag" example:"go"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TagAliasInput request body for POST /api/admin/tags/aliases
+type TagAliasInput struct {
+	Alias string `json:"alias" example:"golang"`
+	Tag   string `json:"tag" example:"go"`
+}
+
+// TagRename is one rename in a TagRenameInput.
+type TagRename struct {
+	From string `json:"from" example:"golang"`
+	To   string `json:"to" example:"go-lang"`
+}
+
+// TagRenameInput request body for POST /api/admin/tags/rename. Renames apply in order.
+type TagRenameInput struct {
+	Renames []TagRename `json:"renames"`
+}
+
+// TagMergeInput request body for POST /api/admin/tags/merge
+type TagMergeInput struct {
+	Sources []string `json:"sources" example:"golang,go-lang"`
+	Target  string   `json:"target" example:"go"`
+}
+
+// TagChangeResult is the response of tag renames and merges.
+type TagChangeResult struct {
+	CapsulesUpdated int `json:"capsules_updated"`
+}
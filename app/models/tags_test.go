@@ -0,0 +1,44 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTagSlug(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Go", "go"},
+		{"  GO  ", "go"},
+		{"Machine Learning", "machine-learning"},
+		{"machine_learning", "machine-learning"},
+		{"CI / CD", "ci-cd"},
+		{"C++", "c++"},
+		{"C#", "c#"},
+		{"node.js", "node.js"},
+		{"--leading and trailing--", "leading-and-trailing"},
+		{"emoji 🚀 dropped", "emoji-dropped"},
+		{"Ünïcode", "ünïcode"},
+		{"!!!", ""},
+		{strings.Repeat("a", MaxTagLen) + "b", strings.Repeat("a", MaxTagLen)},
+		{strings.Repeat("é", MaxTagLen), strings.Repeat("é", MaxTagLen/2)},
+		{strings.Repeat("a", MaxTagLen-1) + " b", strings.Repeat("a", MaxTagLen-1)},
+	}
+	for _, tt := range tests {
+		if got := TagSlug(tt.in); got != tt.want {
+			t.Errorf("TagSlug(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags(Tags{"Go", "go ", "", "  ", "Web Dev", "GO", "web-dev", "rust"})
+	if want := (Tags{"go", "web-dev", "rust"}); !slices.Equal(got, want) {
+		t.Errorf("NormalizeTags() = %v, want %v", got, want)
+	}
+	if got := NormalizeTags(nil); got == nil || len(got) != 0 {
+		t.Errorf("NormalizeTags(nil) = %#v, want an empty list", got)
+	}
+}
@@ -1,9 +1,14 @@
 package models
 
 type TokenClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
-	Exp    int64  `json:"exp"`
-	Iat    int64  `json:"iat"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"`
+	MFA       bool   `json:"mfa,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+	Exp       int64  `json:"exp"`
+	Nbf       int64  `json:"nbf,omitempty"`
+	Iat       int64  `json:"iat"`
 }
@@ -2,12 +2,39 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// Topic statuses. Topics proposed by regular users stay pending until an admin approves them.
+const (
+	TopicStatusPending  = "pending"
+	TopicStatusApproved = "approved"
 )
 
 // TopicInput request body for POST /api/topics and PUT /api/topics/{id}
 type TopicInput struct {
 	Name        string `json:"name" example:"Golang" gorm:"not null"`
 	Description string `json:"description" example:"Go programming language"`
+	// Parent topic; empty for a root topic. Set on create, changed with POST /api/topics/{id}/move
+	ParentID *string `json:"parent_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000" gorm:"type:varchar(36);index"`
+}
+
+// TopicMoveInput request body for POST /api/topics/{id}/move; a null parent_id makes it a root topic
+type TopicMoveInput struct {
+	ParentID *string `json:"parent_id"`
+}
+
+// TopicRef is one step of a topic breadcrumb
+type TopicRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// TopicNode is a topic with its subtopics, as returned by GET /api/topics/tree
+type TopicNode struct {
+	Topic
+	Children []TopicNode `json:"children"`
 }
 
 // Topic extends TopicInput with ID and timestamps
@@ -16,6 +43,38 @@ type Topic struct {
 	TopicInput
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"` // set while the topic is in the trash
+	DeletedBy string         `json:"-" gorm:"type:varchar(36);index"`
+	CreatedBy string         `json:"created_by,omitempty" gorm:"type:varchar(36);index"` // creator or proposer
+	Status    string         `json:"status" gorm:"size:20;default:approved;index"`
+	// Breadcrumb from the root topic down to this one
+	Path []TopicRef `json:"path,omitempty" gorm:"-"`
 }
 
 func (Topic) TableName() string { return "topics" }
+
+// TopicMaintainer lets a user edit, move and delete a topic and manage its maintainers.
+type TopicMaintainer struct {
+	TopicID   string    `json:"topic_id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string    `json:"user_id" gorm:"primaryKey;type:varchar(36);index"`
+	AddedBy   string    `json:"added_by" gorm:"type:varchar(36)"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (TopicMaintainer) TableName() string { return "topic_maintainers" }
+
+// TopicMaintainerInput request body for POST /api/topics/{id}/maintainers. Give either user_id or email.
+type TopicMaintainerInput struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email" example:"jane@example.com"`
+}
+
+// TopicMaintainerView is a maintainer as listed by GET /api/topics/{id}/maintainers. Email is
+// left empty for callers who are neither admins nor maintainers of the topic.
+type TopicMaintainerView struct {
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	AddedBy   string    `json:"added_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
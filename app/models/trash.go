@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Trash item types
+const (
+	TrashCapsule = "capsule"
+	TrashTopic   = "topic"
+)
+
+// TrashItem is a soft-deleted capsule or topic as listed by GET /api/trash.
+type TrashItem struct {
+	Type      string    `json:"type" example:"capsule"`
+	ID        string    `json:"id"`
+	Title     string    `json:"title" example:"Interfaces in Go"` // capsule title or topic name
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"` // when the background purge removes it for good
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode is a single-use backup code for two-factor login. Only its hash is stored.
+type RecoveryCode struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string     `json:"user_id" gorm:"index;not null"`
+	CodeHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (RecoveryCode) TableName() string { return "recovery_codes" }
+
+// TwoFactorStatus is the response for GET /api/users/me/2fa
+type TwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorSetup is the response for POST /api/users/me/2fa/setup
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
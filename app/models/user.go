@@ -11,14 +11,18 @@ const (
 )
 
 type User struct {
-	ID           string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
-	Name         string    `json:"name" gorm:"not null"`
-	Email        string    `json:"email" gorm:"uniqueIndex;not null"`
-	PasswordHash string    `json:"-" gorm:"column:password_hash;not null"`
-	Role         string    `json:"role" gorm:"default:user;size:20"`
-	AvatarURL    string    `json:"avatar_url" gorm:"column:avatar_url"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID               string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	Name             string     `json:"name" gorm:"not null"`
+	Email            string     `json:"email" gorm:"uniqueIndex;not null"`
+	PasswordHash     string     `json:"-" gorm:"column:password_hash;not null"`
+	Role             string     `json:"role" gorm:"default:user;size:20"`
+	AvatarURL        string     `json:"avatar_url" gorm:"column:avatar_url"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+	TOTPSecret       string     `json:"-" gorm:"column:totp_secret"`
+	TOTPLastStep     int64      `json:"-" gorm:"column:totp_last_step;default:0"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled" gorm:"default:false"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
 }
 
 func (User) TableName() string { return "users" }
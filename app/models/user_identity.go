@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// UserIdentity links a user to an account at an external identity provider (OIDC).
+// Subject is the provider's stable user ID; the email is kept for reference only.
+type UserIdentity struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string     `json:"user_id" gorm:"index;not null"`
+	Provider  string     `json:"provider" gorm:"size:64;not null;uniqueIndex:idx_identity_provider_subject"`
+	Subject   string     `json:"subject" gorm:"not null;uniqueIndex:idx_identity_provider_subject"`
+	Email     string     `json:"email"`
+	LastLogin *time.Time `json:"last_login_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (UserIdentity) TableName() string { return "user_identities" }
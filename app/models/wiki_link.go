@@ -0,0 +1,69 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxWikiLinkLen is the longest link target kept; it matches the longest capsule title.
+const MaxWikiLinkLen = 500
+
+// wikiLinkPattern matches [[target]] and [[target|label]] on a single line.
+var wikiLinkPattern = regexp.MustCompile(`\[\[([^\[\]|\n]+)(\|[^\[\]\n]*)?\]\]`)
+
+// WikiLink is a [[...]] link in a capsule's content. Target is the link text, a capsule title
+// or ID; TargetID is the capsule it resolves to, nil while the link is broken.
+type WikiLink struct {
+	SourceID string  `json:"source_id" gorm:"primaryKey;type:varchar(36)"`
+	Target   string  `json:"target" gorm:"primaryKey;size:500"`
+	TargetID *string `json:"target_id" gorm:"type:varchar(36);index"`
+	Position int     `json:"position"` // order of first appearance in the content
+}
+
+func (WikiLink) TableName() string { return "wiki_links" }
+
+// WikiLinkView is one end of a wiki link as listed by the outlinks and backlinks endpoints.
+// CapsuleID and Title are left out when the caller cannot open that capsule.
+type WikiLinkView struct {
+	Target    string  `json:"target" example:"Interfaces in Go"` // link text as written
+	CapsuleID *string `json:"capsule_id,omitempty"`
+	Title     string  `json:"title,omitempty"`
+	Broken    bool    `json:"broken"` // no capsule matches, or it is in the trash
+}
+
+// ParseWikiLinks returns the targets of the wiki links in content in order of appearance,
+// trimmed, without case-insensitive duplicates. Targets longer than MaxWikiLinkLen are skipped.
+func ParseWikiLinks(content string) []string {
+	var targets []string
+	seen := make(map[string]bool)
+	for _, m := range wikiLinkPattern.FindAllStringSubmatch(content, -1) {
+		target := strings.TrimSpace(m[1])
+		key := strings.ToLower(target)
+		if target == "" || utf8.RuneCountInString(target) > MaxWikiLinkLen || seen[key] {
+			continue
+		}
+		seen[key] = true
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// RewriteWikiLinks points the wiki links in content whose target is from (case-insensitively)
+// at to, keeping their labels.
+func RewriteWikiLinks(content, from, to string) string {
+	return wikiLinkPattern.ReplaceAllStringFunc(content, func(link string) string {
+		m := wikiLinkPattern.FindStringSubmatch(link)
+		if !strings.EqualFold(strings.TrimSpace(m[1]), from) {
+			return link
+		}
+		return "[[" + to + m[2] + "]]"
+	})
+}
+
+// WikiLinkable reports whether [[title]] is a link to that exact title. Titles with brackets,
+// pipes or surrounding spaces are linked by capsule ID instead.
+func WikiLinkable(title string) bool {
+	targets := ParseWikiLinks("[[" + title + "]]")
+	return len(targets) == 1 && targets[0] == title
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseWikiLinks(t *testing.T) {
+	long := strings.Repeat("a", MaxWikiLinkLen+1)
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"none", "no links here", nil},
+		{"title", "see [[Interfaces in Go]]", []string{"Interfaces in Go"}},
+		{"label", "see [[Interfaces in Go|interfaces]]", []string{"Interfaces in Go"}},
+		{"order of appearance", "[[B]] then [[A]]", []string{"B", "A"}},
+		{"trimmed", "[[  Spaced  ]]", []string{"Spaced"}},
+		{"case-insensitive duplicates", "[[Go]] and [[go]] and [[GO|label]]", []string{"Go"}},
+		{"empty target", "[[ ]] and [[|label]]", nil},
+		{"across lines", "[[Not\na link]]", nil},
+		{"nested brackets", "[[a [b] c]]", nil},
+		{"triple brackets", "[[[Inner]]]", []string{"Inner"}},
+		{"too long", "[[" + long + "]]", nil},
+		{"longest kept", "[[" + long[1:] + "]]", []string{long[1:]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseWikiLinks(tt.content); !slices.Equal(got, tt.want) {
+				t.Errorf("ParseWikiLinks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteWikiLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		from, to string
+		want     string
+	}{
+		{"plain link", "see [[Old]]", "Old", "New", "see [[New]]"},
+		{"keeps label", "see [[Old|label]]", "Old", "New", "see [[New|label]]"},
+		{"case-insensitive", "[[old]] and [[ OLD ]]", "Old", "New", "[[New]] and [[New]]"},
+		{"other links untouched", "[[Older]] [[Other|Old]]", "Old", "New", "[[Older]] [[Other|Old]]"},
+		{"to an ID", "[[Old]]", "Old", "0b6e1c1e-3c2a-4d8e-9f00-000000000001", "[[0b6e1c1e-3c2a-4d8e-9f00-000000000001]]"},
+		{"no links", "plain Old text", "Old", "New", "plain Old text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RewriteWikiLinks(tt.content, tt.from, tt.to); got != tt.want {
+				t.Errorf("RewriteWikiLinks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWikiLinkable(t *testing.T) {
+	tests := []struct {
+		title string
+		want  bool
+	}{
+		{"Interfaces in Go", true},
+		{"C++ [draft]", false},
+		{"a|b", false},
+		{" padded", false},
+		{"line\nbreak", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := WikiLinkable(tt.title); got != tt.want {
+			t.Errorf("WikiLinkable(%q) = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+}
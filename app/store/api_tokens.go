@@ -0,0 +1,129 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	apiTokenBytes = 32
+	// lastUsedResolution limits how often last-used tracking writes to the database.
+	lastUsedResolution = time.Minute
+)
+
+// apiTokenStore implements personal access token storage with GORM.
+type apiTokenStore struct {
+	DB *gorm.DB
+}
+
+// NewAPITokenStore returns an APITokenStore backed by GORM.
+func NewAPITokenStore(db *gorm.DB) APITokenStore {
+	return &apiTokenStore{DB: db}
+}
+
+// CreateToken creates a named, scoped token and returns it with the raw token value,
+// which is never stored and cannot be retrieved again.
+func (s *apiTokenStore) CreateToken(userID, name string, scopes []string, expiresAt *time.Time) (*models.APIToken, string, error) {
+	secret, err := utils.GenerateSecureToken(apiTokenBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	raw := models.APITokenPrefix + secret
+	token := models.APIToken{
+		ID:        utils.GenerateUUID(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    raw[:len(models.APITokenPrefix)+4],
+		TokenHash: utils.HashToken(raw),
+		Scopes:    models.Scopes(scopes),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.DB.Create(&token).Error; err != nil {
+		return nil, "", err
+	}
+	return &token, raw, nil
+}
+
+// ListTokens returns all tokens owned by a user, newest first.
+func (s *apiTokenStore) ListTokens(userID string) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := s.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// FindByID returns a token owned by userID.
+func (s *apiTokenStore) FindByID(id, userID string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := s.DB.First(&token, "id = ? AND user_id = ?", id, userID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// UpdateToken renames a token and replaces its scopes.
+func (s *apiTokenStore) UpdateToken(id, userID, name string, scopes []string) (*models.APIToken, error) {
+	result := s.DB.Model(&models.APIToken{}).Where("id = ? AND user_id = ?", id, userID).Updates(map[string]interface{}{
+		"name":   name,
+		"scopes": models.Scopes(scopes),
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("token not found")
+	}
+	return s.FindByID(id, userID)
+}
+
+// DeleteToken revokes a token owned by userID.
+func (s *apiTokenStore) DeleteToken(id, userID string) error {
+	result := s.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.APIToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("token not found")
+	}
+	return nil
+}
+
+// DeleteUserTokens revokes every token of a user and returns how many there were.
+func (s *apiTokenStore) DeleteUserTokens(userID string) (int, error) {
+	result := s.DB.Where("user_id = ?", userID).Delete(&models.APIToken{})
+	return int(result.RowsAffected), result.Error
+}
+
+// Authenticate resolves a raw token, rejecting unknown and expired ones, and records its use.
+func (s *apiTokenStore) Authenticate(raw, ipAddress string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := s.DB.Where("token_hash = ?", utils.HashToken(raw)).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid API token")
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, errors.New("API token expired")
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		s.DB.Model(&models.APIToken{}).Where("id = ?", token.ID).UpdateColumns(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ipAddress,
+		})
+		token.LastUsedAt = &now
+		token.LastUsedIP = ipAddress
+	}
+	return &token, nil
+}
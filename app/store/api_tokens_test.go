@@ -0,0 +1,52 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"knowledge-capsule/app/models"
+)
+
+func TestAuthenticateAPIToken(t *testing.T) {
+	s := NewAPITokenStore(newTestDB(t, &models.APIToken{}))
+	scopes := []string{models.ScopeCapsulesRead}
+	future, past := time.Now().Add(time.Hour), time.Now().Add(-time.Second)
+
+	_, forever, err := s.CreateToken("u1", "ci", scopes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, expiring, err := s.CreateToken("u1", "deploy", scopes, &future)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, expired, err := s.CreateToken("u1", "old", scopes, &past)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedToken, revoked, err := s.CreateToken("u1", "gone", scopes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteToken(revokedToken.ID, "u1"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, raw := range []string{forever, expiring} {
+		token, err := s.Authenticate(raw, "192.0.2.1")
+		if err != nil {
+			t.Fatalf("Authenticate() error = %v", err)
+		}
+		if token.UserID != "u1" || !token.Scopes.Has(models.ScopeCapsulesRead) || token.Scopes.Has(models.ScopeCapsulesWrite) {
+			t.Errorf("Authenticate() = %+v, want u1 with capsules:read only", token)
+		}
+		if token.LastUsedAt == nil || token.LastUsedIP != "192.0.2.1" {
+			t.Errorf("Authenticate() did not record use: %+v", token)
+		}
+	}
+	for name, raw := range map[string]string{"expired": expired, "revoked": revoked, "unknown": "kc_pat_unknown"} {
+		if _, err := s.Authenticate(raw, "192.0.2.1"); err == nil {
+			t.Errorf("Authenticate(%s) succeeded", name)
+		}
+	}
+}
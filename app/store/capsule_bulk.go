@@ -0,0 +1,140 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"knowledge-capsule/app/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrBulkTooLarge is returned when a bulk filter matches more than models.MaxBulkCapsules
+// capsules.
+var ErrBulkTooLarge = fmt.Errorf("filter matches more than %d capsules", models.MaxBulkCapsules)
+
+// errBulkRolledBack rolls back an atomic bulk operation that had a failure.
+var errBulkRolledBack = errors.New("bulk operation rolled back")
+
+// BulkUpdateCapsules applies one action to many capsules in a single transaction: those in
+// input.IDs, or the user's own capsules matching filters. Each capsule is changed as by
+// UpdateCapsule or DeleteCapsule, with the same permissions and a new revision per update;
+// capsules the action would not change are left alone. A capsule that fails is rolled back on
+// its own, or, when input.Atomic is set, together with every other change.
+func (s *capsuleStore) BulkUpdateCapsules(userID string, input models.BulkInput, filters *models.CapsuleFilters) (*models.BulkResult, error) {
+	result := &models.BulkResult{Action: input.Action, Atomic: input.Atomic, Items: []models.BulkItemResult{}}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		ids := input.IDs
+		if filters != nil {
+			query := applyCapsuleFilters(tx.Model(&models.Capsule{}).Where("capsules.user_id = ?", userID), filters)
+			if err := query.Order("capsules.created_at, capsules.id").Limit(models.MaxBulkCapsules+1).
+				Pluck("capsules.id", &ids).Error; err != nil {
+				return err
+			}
+			if len(ids) > models.MaxBulkCapsules {
+				return ErrBulkTooLarge
+			}
+		}
+		var removed models.Tags
+		if input.Action == models.BulkRemoveTags {
+			var err error
+			if removed, err = canonicalSlugs(tx, input.Tags); err != nil {
+				return err
+			}
+		}
+
+		for _, id := range ids {
+			item := models.BulkItemResult{ID: id}
+			// Each capsule runs in a savepoint, so a failure only undoes its own changes.
+			err := tx.Transaction(func(itx *gorm.DB) error {
+				var err error
+				item.Status, err = (&capsuleStore{DB: itx}).bulkApply(id, userID, input, removed)
+				return err
+			})
+			if err != nil {
+				item.Status, item.Error = models.BulkFailed, err.Error()
+				result.Failed++
+			} else {
+				result.Succeeded++
+			}
+			result.Items = append(result.Items, item)
+		}
+		result.Total = len(ids)
+		if input.Atomic && result.Failed > 0 {
+			return errBulkRolledBack
+		}
+		return nil
+	})
+	if errors.Is(err, errBulkRolledBack) {
+		for i := range result.Items {
+			if result.Items[i].Status != models.BulkFailed {
+				result.Items[i].Status = models.BulkRolledBack
+			}
+		}
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Applied = true
+	return result, nil
+}
+
+// bulkApply applies a bulk action to one capsule and returns its status. removed are the
+// canonical slugs of the tags to remove.
+func (s *capsuleStore) bulkApply(id, userID string, input models.BulkInput, removed models.Tags) (string, error) {
+	if input.Action == models.BulkDelete {
+		if err := s.DeleteCapsule(id, userID); err != nil {
+			return "", err
+		}
+		return models.BulkDeleted, nil
+	}
+
+	capsule, err := s.FindAccessible(id, userID)
+	if err != nil {
+		return "", err
+	}
+	next := capsule.CapsuleInput
+	switch input.Action {
+	case models.BulkAddTags:
+		next.Tags = append(slices.Clone(capsule.Tags), input.Tags...)
+	case models.BulkRemoveTags:
+		next.Tags = slices.DeleteFunc(slices.Clone(capsule.Tags), func(tag string) bool {
+			return slices.Contains(removed, tag)
+		})
+	case models.BulkSetTopic:
+		next.Topic, next.TopicID = input.Topic, input.TopicID
+	case models.BulkSetPrivate:
+		if capsule.UserID != userID {
+			return "", errors.New("only the owner can change is_private")
+		}
+		next.IsPrivate = !capsule.IsPrivate
+		if input.IsPrivate != nil {
+			next.IsPrivate = *input.IsPrivate
+		}
+	}
+
+	if err := resolveTopic(s.DB, userID, &next); err != nil {
+		return "", err
+	}
+	if err := resolveTags(s.DB, &next); err != nil {
+		return "", err
+	}
+	if next.Topic == capsule.Topic && sameTopicID(next.TopicID, capsule.TopicID) &&
+		slices.Equal(next.Tags, capsule.Tags) && next.IsPrivate == capsule.IsPrivate {
+		return models.BulkUnchanged, nil
+	}
+	if _, err := s.applyUpdate(id, userID, next, nil); err != nil {
+		return "", err
+	}
+	return models.BulkUpdated, nil
+}
+
+// sameTopicID reports whether two optional topic IDs are equal.
+func sameTopicID(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"strings"
+	"time"
+
+	"knowledge-capsule/app/models"
+)
+
+// ExportCapsules returns all of a user's capsules outside the trash that match the filters,
+// oldest first.
+func (s *capsuleStore) ExportCapsules(userID string, filters *models.CapsuleFilters) ([]models.Capsule, error) {
+	capsules := []models.Capsule{}
+	query := applyCapsuleFilters(s.DB.Where("capsules.user_id = ?", userID), filters)
+	if err := query.Order("capsules.created_at, capsules.id").Find(&capsules).Error; err != nil {
+		return nil, err
+	}
+	return capsules, nil
+}
+
+// FindByTitles returns a user's capsules outside the trash whose title matches one of titles,
+// case-insensitively, oldest first.
+func (s *capsuleStore) FindByTitles(userID string, titles []string) ([]models.Capsule, error) {
+	capsules := []models.Capsule{}
+	if len(titles) == 0 {
+		return capsules, nil
+	}
+	lowered := make([]string, len(titles))
+	for i, t := range titles {
+		lowered[i] = strings.ToLower(t)
+	}
+	err := s.DB.Where("user_id = ? AND LOWER(title) IN ?", userID, lowered).
+		Order("created_at, id").Find(&capsules).Error
+	if err != nil {
+		return nil, err
+	}
+	return capsules, nil
+}
+
+// ImportCapsule creates a capsule like AddCapsule, keeping the creation and update times it
+// was exported with. A missing creation time is taken from the update time, and a missing
+// update time is now.
+func (s *capsuleStore) ImportCapsule(userID string, input models.CapsuleInput, createdAt, updatedAt *time.Time) (*models.Capsule, error) {
+	capsule := models.Capsule{UserID: userID, CapsuleInput: input}
+	if createdAt == nil {
+		createdAt = updatedAt
+	}
+	if createdAt != nil {
+		capsule.CreatedAt = *createdAt
+		capsule.UpdatedAt = time.Now()
+		if updatedAt != nil {
+			capsule.UpdatedAt = *updatedAt
+		}
+		if capsule.UpdatedAt.Before(capsule.CreatedAt) {
+			capsule.UpdatedAt = capsule.CreatedAt
+		}
+	}
+	return s.addCapsule(capsule)
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"knowledge-capsule/app/models"
+
+	"gorm.io/gorm"
+)
+
+// maxFacetValues caps each facet list; the most common values are kept.
+const maxFacetValues = 50
+
+// CapsuleFacets counts the user's capsules matching filters by tag, topic, privacy and
+// creation week or month.
+func (s *capsuleStore) CapsuleFacets(userID string, filters *models.CapsuleFilters, interval string) (*models.CapsuleFacets, error) {
+	matching := func() *gorm.DB {
+		return applyCapsuleFilters(s.DB.Model(&models.Capsule{}).Where("capsules.user_id = ?", userID), filters)
+	}
+	facets := &models.CapsuleFacets{
+		Tags:     []models.FacetCount{},
+		Topics:   []models.FacetCount{},
+		Privacy:  []models.FacetCount{},
+		Created:  []models.FacetCount{},
+		Interval: interval,
+	}
+
+	err := matching().Joins("CROSS JOIN LATERAL jsonb_array_elements_text(capsules.tags) AS t(tag)").
+		Select("t.tag AS value, COUNT(*) AS count").Group("t.tag").
+		Order("count DESC, value").Limit(maxFacetValues).Scan(&facets.Tags).Error
+	if err != nil {
+		return nil, err
+	}
+	err = matching().Where("capsules.topic <> ''").
+		Select("capsules.topic AS value, COUNT(*) AS count").Group("capsules.topic").
+		Order("count DESC, value").Limit(maxFacetValues).Scan(&facets.Topics).Error
+	if err != nil {
+		return nil, err
+	}
+	err = matching().
+		Select("CASE WHEN capsules.is_private THEN 'private' ELSE 'public' END AS value, COUNT(*) AS count").
+		Group("capsules.is_private").Order("value").Scan(&facets.Privacy).Error
+	if err != nil {
+		return nil, err
+	}
+	err = matching().
+		Select("to_char(date_trunc(?, capsules.created_at), 'YYYY-MM-DD') AS value, COUNT(*) AS count", interval).
+		Group("value").Order("value DESC").Limit(maxFacetValues).Scan(&facets.Created).Error
+	if err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
@@ -0,0 +1,31 @@
+package store
+
+import "knowledge-capsule/app/models"
+
+// graphCapsuleVisible matches capsules a user owns or that are shared with them. It takes the
+// user ID followed by granteeArgs.
+const graphCapsuleVisible = "(user_id = ? OR id IN (SELECT capsule_id FROM capsule_shares WHERE " + granteeCondition + "))"
+
+// GraphCapsules returns the capsules a user owns or that are shared with them, outside the
+// trash, most recently updated first. A positive limit caps how many are returned.
+func (s *capsuleStore) GraphCapsules(userID string, limit int) ([]models.GraphCapsule, error) {
+	capsules := []models.GraphCapsule{}
+	query := s.DB.Model(&models.Capsule{}).Select("id, title, topic_id, topic, tags, updated_at").
+		Where(graphCapsuleVisible, linkedCapsuleArgs(userID)...).
+		Order("updated_at DESC, id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Scan(&capsules).Error
+	return capsules, err
+}
+
+// GraphLinks returns the resolved wiki links of the capsules a user owns or that are shared
+// with them, trashed ones included; the graph only keeps links between capsules it holds.
+func (s *capsuleStore) GraphLinks(userID string) ([]models.WikiLink, error) {
+	links := []models.WikiLink{}
+	err := s.DB.Where("target_id IS NOT NULL AND source_id IN (SELECT id FROM capsules WHERE "+graphCapsuleVisible+")",
+		linkedCapsuleArgs(userID)...).
+		Order("source_id, position").Find(&links).Error
+	return links, err
+}
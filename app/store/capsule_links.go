@@ -0,0 +1,119 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+// ErrSlugTaken is returned when a chosen slug is already used by another link.
+var ErrSlugTaken = errors.New("slug is already taken")
+
+// ErrCapsulePrivate is returned when creating a link to a private capsule.
+var ErrCapsulePrivate = errors.New("private capsules cannot have public links; make the capsule public first")
+
+// capsuleLinkStore implements public link storage with GORM.
+type capsuleLinkStore struct {
+	DB *gorm.DB
+}
+
+// NewCapsuleLinkStore returns a CapsuleLinkStore backed by GORM.
+func NewCapsuleLinkStore(db *gorm.DB) CapsuleLinkStore {
+	return &capsuleLinkStore{DB: db}
+}
+
+// CreateLink adds a public link to a public capsule (only owner). passwordHash and expiresAt
+// are optional.
+func (s *capsuleLinkStore) CreateLink(capsuleID, ownerID, slug, passwordHash string, expiresAt *time.Time) (*models.CapsuleLink, error) {
+	var capsule models.Capsule
+	if err := s.DB.Select("id", "is_private").Where("id = ? AND user_id = ?", capsuleID, ownerID).First(&capsule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("capsule not found or unauthorized")
+		}
+		return nil, err
+	}
+	if capsule.IsPrivate {
+		return nil, ErrCapsulePrivate
+	}
+	var count int64
+	if err := s.DB.Model(&models.CapsuleLink{}).Where("slug = ?", slug).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrSlugTaken
+	}
+
+	link := models.CapsuleLink{
+		ID:                utils.GenerateUUID(),
+		CapsuleID:         capsuleID,
+		Slug:              slug,
+		PasswordHash:      passwordHash,
+		PasswordProtected: passwordHash != "",
+		ExpiresAt:         expiresAt,
+		CreatedBy:         ownerID,
+	}
+	if err := s.DB.Create(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// ListLinks returns a capsule's public links, newest first.
+func (s *capsuleLinkStore) ListLinks(capsuleID string) ([]models.CapsuleLink, error) {
+	var links []models.CapsuleLink
+	err := s.DB.Where("capsule_id = ?", capsuleID).Order("created_at DESC").Find(&links).Error
+	return links, err
+}
+
+// DeleteLink removes a public link (only owner).
+func (s *capsuleLinkStore) DeleteLink(capsuleID, ownerID, linkID string) error {
+	var count int64
+	if err := s.DB.Model(&models.Capsule{}).Where("id = ? AND user_id = ?", capsuleID, ownerID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("capsule not found or unauthorized")
+	}
+	result := s.DB.Where("id = ? AND capsule_id = ?", linkID, capsuleID).Delete(&models.CapsuleLink{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("link not found")
+	}
+	return nil
+}
+
+// ResolveLink returns a link by slug and the capsule it points to. Links to trashed or
+// private capsules are not found; expiry is left to the caller.
+func (s *capsuleLinkStore) ResolveLink(slug string) (*models.CapsuleLink, *models.PublicCapsule, error) {
+	var link models.CapsuleLink
+	if err := s.DB.Where("slug = ?", slug).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("link not found")
+		}
+		return nil, nil, err
+	}
+	capsule, err := findPublicCapsule(capsulesWithAuthor(s.DB).
+		Where("capsules.id = ? AND capsules.is_private = ?", link.CapsuleID, false))
+	if err != nil {
+		return nil, nil, errors.New("link not found")
+	}
+	return &link, capsule, nil
+}
+
+// RecordView counts one read through a link, on the link and on its capsule.
+func (s *capsuleLinkStore) RecordView(link *models.CapsuleLink) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.CapsuleLink{}).Where("id = ?", link.ID).
+			UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Capsule{}).Where("id = ?", link.CapsuleID).
+			UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error
+	})
+}
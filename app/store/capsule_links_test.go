@@ -0,0 +1,48 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"knowledge-capsule/app/models"
+)
+
+func TestCapsuleLinksRequirePublicCapsule(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Capsule{}, &models.CapsuleLink{})
+	if err := db.Create(&models.User{ID: "owner", Name: "Owner", Email: "owner@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []models.Capsule{
+		{ID: "public", UserID: "owner", CapsuleInput: models.CapsuleInput{Title: "Public", Content: "hello", Tags: models.Tags{"go"}}},
+		{ID: "private", UserID: "owner", CapsuleInput: models.CapsuleInput{Title: "Private", Content: "secret", Tags: models.Tags{"go"}, IsPrivate: true}},
+	} {
+		if err := db.Create(&c).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := NewCapsuleLinkStore(db)
+
+	if _, err := links.CreateLink("private", "owner", "private-notes", "", nil); !errors.Is(err, ErrCapsulePrivate) {
+		t.Errorf("CreateLink(private) error = %v, want ErrCapsulePrivate", err)
+	}
+	if _, err := links.CreateLink("public", "someone", "stolen", "", nil); err == nil {
+		t.Error("CreateLink() allowed a non-owner")
+	}
+	if _, err := links.CreateLink("public", "owner", "go-notes", "", nil); err != nil {
+		t.Fatalf("CreateLink(public) error = %v", err)
+	}
+	if _, err := links.CreateLink("public", "owner", "go-notes", "", nil); !errors.Is(err, ErrSlugTaken) {
+		t.Errorf("CreateLink(taken slug) error = %v, want ErrSlugTaken", err)
+	}
+
+	if _, capsule, err := links.ResolveLink("go-notes"); err != nil || capsule.Content != "hello" {
+		t.Fatalf("ResolveLink() = %+v, %v", capsule, err)
+	}
+	// Making the capsule private takes every link down, whatever its slug.
+	if err := db.Model(&models.Capsule{}).Where("id = ?", "public").Update("is_private", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, capsule, err := links.ResolveLink("go-notes"); err == nil {
+		t.Errorf("ResolveLink() served a private capsule: %+v", capsule)
+	}
+}
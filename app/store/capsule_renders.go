@@ -0,0 +1,32 @@
+package store
+
+import (
+	"knowledge-capsule/app/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// CachedRenders returns the stored renders of capsules by capsule ID. Callers compare their
+// hash with the capsule's current one.
+func (s *capsuleStore) CachedRenders(ids []string) (map[string]models.CapsuleRender, error) {
+	renders := make(map[string]models.CapsuleRender, len(ids))
+	if len(ids) == 0 {
+		return renders, nil
+	}
+	var rows []models.CapsuleRender
+	if err := s.DB.Where("capsule_id IN ?", ids).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		renders[row.CapsuleID] = row
+	}
+	return renders, nil
+}
+
+// SaveRender stores a capsule's rendered content, replacing the one cached before.
+func (s *capsuleStore) SaveRender(render *models.CapsuleRender) error {
+	return s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "capsule_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"hash", "output", "rendered_at"}),
+	}).Create(render).Error
+}
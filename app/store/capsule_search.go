@@ -0,0 +1,97 @@
+package store
+
+import (
+	"html"
+	"strings"
+
+	"knowledge-capsule/app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// webSearchQuery parses user input with web search syntax: "quoted phrases", OR and -exclusions.
+const webSearchQuery = "websearch_to_tsquery('english', ?)"
+
+// capsuleTextMatch matches capsules whose generated search_vector satisfies the query, or
+// whose title is similar enough to it that a misspelled word still finds them. Both
+// placeholders take the query.
+const capsuleTextMatch = "(capsules.search_vector @@ " + webSearchQuery + " OR ? <% capsules.title)"
+
+// capsuleRank scores a capsule against a query: text rank plus trigram title similarity.
+// Both placeholders take the query.
+const capsuleRank = "(ts_rank(capsules.search_vector, " + webSearchQuery + ") + word_similarity(?, capsules.title))"
+
+// Matched words are wrapped in private-use characters so the snippet can be escaped
+// before they are turned into <mark> tags.
+const (
+	headlineStart   = "\uE000"
+	headlineStop    = "\uE001"
+	headlineOptions = `StartSel="` + headlineStart + `", StopSel="` + headlineStop + `", ` +
+		`MinWords=15, MaxWords=35, MaxFragments=2, FragmentDelimiter=" … "`
+)
+
+// SearchCapsules ranks a user's capsules against filters.Q, best match first, narrowed by
+// the other filters. Without a query, capsules come most recently updated first.
+func (s *capsuleStore) SearchCapsules(userID string, filters *models.CapsuleFilters, page, limit int) ([]models.CapsuleSearchResult, int, error) {
+	query := applyCapsuleFilters(s.DB.Model(&models.CapsuleSearchResult{}).Where("capsules.user_id = ?", userID), filters)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+	q := ""
+	if filters != nil {
+		q = filters.Q
+	}
+	results, err := findSearchResults(query, q, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return results, int(total), nil
+}
+
+// SearchAllCapsules ranks all capsules against a query (admin only, no user filter).
+func (s *capsuleStore) SearchAllCapsules(q string, limit int) ([]models.CapsuleSearchResult, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	return findSearchResults(s.DB.Model(&models.CapsuleSearchResult{}).Where(capsuleTextMatch, q, q), q, 0, limit)
+}
+
+// findSearchResults loads one page of a full-text query with rank and highlighted snippet.
+// An empty q loads the page by recency, without rank or snippet.
+func findSearchResults(query *gorm.DB, q string, offset, limit int) ([]models.CapsuleSearchResult, error) {
+	results := []models.CapsuleSearchResult{}
+	if q == "" {
+		err := query.Order("capsules.updated_at DESC").Offset(offset).Limit(limit).Find(&results).Error
+		return results, err
+	}
+	err := query.Select("capsules.*, "+capsuleRank+" AS rank, "+
+		"ts_headline('english', capsules.content, "+webSearchQuery+", ?) AS headline", q, q, q, headlineOptions).
+		Order("rank DESC, capsules.updated_at DESC").Offset(offset).Limit(limit).Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Headline = markHeadline(results[i].Headline)
+	}
+	return results, nil
+}
+
+// rankOrder orders a capsule query by relevance to a full-text query.
+func rankOrder(q string) clause.OrderBy {
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:  capsuleRank + " DESC",
+		Vars: []interface{}{q, q},
+	}}
+}
+
+// markHeadline escapes a ts_headline snippet and turns its match markers into <mark> tags.
+func markHeadline(headline string) string {
+	headline = html.EscapeString(headline)
+	return strings.NewReplacer(headlineStart, "<mark>", headlineStop, "</mark>").Replace(headline)
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"knowledge-capsule/app/models"
+)
+
+func TestMarkHeadline(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"match", "Interfaces are " + headlineStart + "method" + headlineStop + " sets", "Interfaces are <mark>method</mark> sets"},
+		{"content markup is escaped", `<img src=x onerror=alert(1)> ` + headlineStart + "go" + headlineStop, `&lt;img src=x onerror=alert(1)&gt; <mark>go</mark>`},
+		{"fragments", headlineStart + "a" + headlineStop + " … " + headlineStart + "b" + headlineStop, "<mark>a</mark> … <mark>b</mark>"},
+		{"no match", `"quoted" & plain`, "&#34;quoted&#34; &amp; plain"},
+	}
+	for _, tt := range tests {
+		if got := markHeadline(tt.in); got != tt.want {
+			t.Errorf("markHeadline(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSearchCapsulesQuery(t *testing.T) {
+	db, capture := newDryRunDB(t)
+	s := &capsuleStore{DB: db}
+	q := `"go interfaces" -java'); DROP TABLE capsules; --`
+
+	if _, _, err := s.SearchCapsules("u1", &models.CapsuleFilters{Q: q}, 2, 10); err != nil {
+		t.Fatal(err)
+	}
+	sql, vars := capture.last(t)
+	for _, want := range []string{
+		"websearch_to_tsquery('english', $",
+		"ts_rank(capsules.search_vector",
+		"<% capsules.title",
+		"ts_headline('english', capsules.content",
+		"capsules.user_id = $",
+		`"capsules"."deleted_at" IS NULL`,
+		"ORDER BY rank DESC, capsules.updated_at DESC",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("search SQL lacks %q:\n%s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "DROP TABLE") {
+		t.Errorf("query text was interpolated into SQL:\n%s", sql)
+	}
+	bound := 0
+	for _, v := range vars {
+		if v == q {
+			bound++
+		}
+	}
+	// Rank (2), headline (1) and the match filter (2).
+	if bound != 5 {
+		t.Errorf("query bound %d times, want 5: %v", bound, vars)
+	}
+	// Page 2 of 10 skips the first page.
+	if n := len(vars); n < 2 || vars[n-2] != 10 || vars[n-1] != 10 {
+		t.Errorf("limit and offset = %v, want 10 and 10", vars[len(vars)-2:])
+	}
+}
+
+func TestSearchCapsulesWithoutQuery(t *testing.T) {
+	db, capture := newDryRunDB(t)
+	s := &capsuleStore{DB: db}
+	if _, _, err := s.SearchCapsules("u1", &models.CapsuleFilters{}, 1, 20); err != nil {
+		t.Fatal(err)
+	}
+	sql, _ := capture.last(t)
+	if strings.Contains(sql, "ts_rank") || strings.Contains(sql, "ts_headline") {
+		t.Errorf("search without q ranks:\n%s", sql)
+	}
+	if !strings.Contains(sql, "ORDER BY capsules.updated_at DESC") {
+		t.Errorf("search without q is not by recency:\n%s", sql)
+	}
+}
@@ -0,0 +1,203 @@
+package store
+
+import (
+	"errors"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// granteeCondition matches shares granted to a user directly or to any group they are an
+// active member of.
+const granteeCondition = "((grantee_type = ? AND grantee_id = ?) OR (grantee_type = ? AND grantee_id IN (SELECT group_id FROM group_members WHERE user_id = ? AND status = ?)))"
+
+func granteeArgs(userID string) []interface{} {
+	return []interface{}{models.GranteeUser, userID, models.GranteeGroup, userID, models.GroupMemberActive}
+}
+
+// sharePermission returns the strongest permission a user holds on a capsule through
+// shares, or "" when it is not shared with them.
+func sharePermission(db *gorm.DB, capsuleID, userID string) (string, error) {
+	var permissions []string
+	err := db.Model(&models.CapsuleShare{}).Where("capsule_id = ?", capsuleID).
+		Where(granteeCondition, granteeArgs(userID)...).Pluck("permission", &permissions).Error
+	if err != nil {
+		return "", err
+	}
+	best := ""
+	for _, p := range permissions {
+		best = models.StrongerPermission(best, p)
+	}
+	return best, nil
+}
+
+// FindAccessible returns a capsule the user owns or that is shared with them. For shared
+// capsules Permission is set to the user's strongest grant.
+func (s *capsuleStore) FindAccessible(id, userID string) (*models.Capsule, error) {
+	capsule, err := s.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if capsule.UserID == userID {
+		return capsule, nil
+	}
+	permission, err := sharePermission(s.DB, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if permission == "" {
+		return nil, errors.New("capsule not found")
+	}
+	capsule.Permission = permission
+	return capsule, nil
+}
+
+// ListSharedWithUser returns one page of the capsules other users shared with the user or
+// their groups, with optional filters and, unless minPermission is empty, only those the user
+// holds at least minPermission on. Each capsule carries the user's strongest permission.
+// Lists with a search query default to relevance order, others to most recently updated.
+func (s *capsuleStore) ListSharedWithUser(userID, minPermission string, filters *models.CapsuleFilters, opts models.ListOptions) ([]models.Capsule, models.PageInfo, error) {
+	shared := s.DB.Model(&models.CapsuleShare{}).Select("capsule_id").Where(granteeCondition, granteeArgs(userID)...)
+	if minPermission != "" {
+		var permissions []string
+		for _, p := range []string{models.PermissionViewer, models.PermissionCommenter, models.PermissionEditor} {
+			if models.PermissionAtLeast(p, minPermission) {
+				permissions = append(permissions, p)
+			}
+		}
+		shared = shared.Where("permission IN ?", permissions)
+	}
+	query := applyCapsuleFilters(s.DB.Model(&models.Capsule{}).
+		Where("capsules.id IN (?) AND capsules.user_id <> ?", shared, userID), filters)
+	spec := listSpec[models.Capsule]{
+		id:          "capsules.id",
+		idOf:        func(c models.Capsule) string { return c.ID },
+		sorts:       capsuleSorts,
+		defaultSort: models.SortUpdatedAt,
+	}
+	if filters != nil && filters.Q != "" {
+		spec.relevance = &clause.Expr{SQL: capsuleRank, Vars: []interface{}{filters.Q, filters.Q}}
+		spec.defaultSort = models.SortRelevance
+	}
+	capsules, info, err := pageQuery(query, spec, opts)
+	if err != nil || len(capsules) == 0 {
+		return capsules, info, err
+	}
+
+	ids := make([]string, len(capsules))
+	for i := range capsules {
+		ids[i] = capsules[i].ID
+	}
+	var shares []models.CapsuleShare
+	if err := s.DB.Where("capsule_id IN ?", ids).Where(granteeCondition, granteeArgs(userID)...).
+		Find(&shares).Error; err != nil {
+		return nil, info, err
+	}
+	permissions := make(map[string]string, len(ids))
+	for _, share := range shares {
+		permissions[share.CapsuleID] = models.StrongerPermission(permissions[share.CapsuleID], share.Permission)
+	}
+	for i := range capsules {
+		capsules[i].Permission = permissions[capsules[i].ID]
+	}
+	return capsules, info, nil
+}
+
+// ListShares returns a capsule's shares with grantee names, oldest first.
+func (s *capsuleStore) ListShares(capsuleID string) ([]models.CapsuleShare, error) {
+	var shares []models.CapsuleShare
+	if err := s.DB.Where("capsule_id = ?", capsuleID).Order("created_at").Find(&shares).Error; err != nil {
+		return nil, err
+	}
+
+	var userIDs, groupIDs []string
+	for _, share := range shares {
+		if share.GranteeType == models.GranteeGroup {
+			groupIDs = append(groupIDs, share.GranteeID)
+		} else {
+			userIDs = append(userIDs, share.GranteeID)
+		}
+	}
+	names := map[string]string{}
+	if len(userIDs) > 0 {
+		var users []models.User
+		if err := s.DB.Select("id", "name").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			names[u.ID] = u.Name
+		}
+	}
+	if len(groupIDs) > 0 {
+		var groups []models.Group
+		if err := s.DB.Select("id", "name").Where("id IN ?", groupIDs).Find(&groups).Error; err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			names[g.ID] = g.Name
+		}
+	}
+	for i := range shares {
+		shares[i].GranteeName = names[shares[i].GranteeID]
+	}
+	return shares, nil
+}
+
+// ShareCapsule grants a permission on a capsule (only owner). Sharing again with the same
+// grantee replaces its permission.
+func (s *capsuleStore) ShareCapsule(capsuleID, ownerID, granteeType, granteeID, permission string) (*models.CapsuleShare, error) {
+	if !models.ValidSharePermission(permission) {
+		return nil, errors.New("invalid permission")
+	}
+	var count int64
+	if err := s.DB.Model(&models.Capsule{}).Where("id = ? AND user_id = ?", capsuleID, ownerID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errors.New("capsule not found or unauthorized")
+	}
+
+	share := models.CapsuleShare{
+		ID:          utils.GenerateUUID(),
+		CapsuleID:   capsuleID,
+		GranteeType: granteeType,
+		GranteeID:   granteeID,
+		Permission:  permission,
+		GrantedBy:   ownerID,
+	}
+	err := s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "capsule_id"}, {Name: "grantee_type"}, {Name: "grantee_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"permission", "granted_by", "updated_at"}),
+	}).Create(&share).Error
+	if err != nil {
+		return nil, err
+	}
+	// On conflict the existing row keeps its ID; reload it.
+	err = s.DB.Where("capsule_id = ? AND grantee_type = ? AND grantee_id = ?", capsuleID, granteeType, granteeID).First(&share).Error
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// UnshareCapsule removes a share from a capsule (only owner).
+func (s *capsuleStore) UnshareCapsule(capsuleID, ownerID, shareID string) error {
+	var count int64
+	if err := s.DB.Model(&models.Capsule{}).Where("id = ? AND user_id = ?", capsuleID, ownerID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("capsule not found or unauthorized")
+	}
+	result := s.DB.Where("id = ? AND capsule_id = ?", shareID, capsuleID).Delete(&models.CapsuleShare{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("share not found")
+	}
+	return nil
+}
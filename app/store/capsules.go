@@ -3,11 +3,13 @@ package store
 import (
 	"errors"
 	"strings"
+	"time"
 
 	"knowledge-capsule/app/models"
 	"knowledge-capsule/pkg/utils"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // capsuleStore implements capsule storage with GORM.
@@ -20,53 +22,104 @@ func NewCapsuleStore(db *gorm.DB) CapsuleStore {
 	return &capsuleStore{DB: db}
 }
 
-// AddCapsule creates a new capsule.
-func (s *capsuleStore) AddCapsule(userID, title, content, topic string, tags []string, isPrivate bool) (*models.Capsule, error) {
-	capsule := models.Capsule{
-		ID:     utils.GenerateUUID(),
-		UserID: userID,
-		CapsuleInput: models.CapsuleInput{
-			Title:     title,
-			Content:   content,
-			Topic:     topic,
-			Tags:      models.Tags(tags),
-			IsPrivate: isPrivate,
-		},
-	}
-	if err := s.DB.Create(&capsule).Error; err != nil {
-		return nil, err
-	}
-	return &capsule, nil
+// AddCapsule creates a new capsule. Its topic is linked by topic_id or name (see resolveTopic),
+// its tags are normalized (see resolveTags) and the wiki links in its content are stored.
+// Broken links of the owner that name it are resolved to it.
+func (s *capsuleStore) AddCapsule(userID string, input models.CapsuleInput) (*models.Capsule, error) {
+	return s.addCapsule(models.Capsule{UserID: userID, CapsuleInput: input})
 }
 
-// GetCapsulesByUser returns capsules owned by a user with optional filters.
-func (s *capsuleStore) GetCapsulesByUser(userID string, filters *models.CapsuleFilters) ([]models.Capsule, error) {
-	query := s.DB.Where("user_id = ?", userID)
-
-	if filters != nil {
-		if filters.Topic != "" {
-			query = query.Where("topic ILIKE ?", "%"+filters.Topic+"%")
+// addCapsule creates a capsule with a new ID. Zero timestamps are set to now.
+func (s *capsuleStore) addCapsule(capsule models.Capsule) (*models.Capsule, error) {
+	if capsule.ContentFormat == "" {
+		capsule.ContentFormat = models.ContentPlain
+	}
+	capsule.ID = utils.GenerateUUID()
+	userID := capsule.UserID
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := resolveTopic(tx, userID, &capsule.CapsuleInput); err != nil {
+			return err
 		}
-		if len(filters.Tags) > 0 {
-			for _, tag := range filters.Tags {
-				query = query.Where("tags::text ILIKE ?", "%"+tag+"%")
-			}
+		if err := resolveTags(tx, &capsule.CapsuleInput); err != nil {
+			return err
 		}
-		if filters.Q != "" {
-			pattern := "%" + strings.ToLower(filters.Q) + "%"
-			query = query.Where("LOWER(title) LIKE ? OR LOWER(content) LIKE ? OR tags::text ILIKE ?",
-				pattern, pattern, pattern)
+		if err := tx.Create(&capsule).Error; err != nil {
+			return err
 		}
-		if filters.IsPrivate != nil {
-			query = query.Where("is_private = ?", *filters.IsPrivate)
+		if err := syncWikiLinks(tx, &capsule); err != nil {
+			return err
 		}
+		if err := resolveWikiLinksTo(tx, &capsule); err != nil {
+			return err
+		}
+		return writeRevision(tx, &capsule, userID, nil)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &capsule, nil
+}
 
-	var capsules []models.Capsule
-	if err := query.Find(&capsules).Error; err != nil {
-		return nil, err
+// capsuleSorts are the sort keys for capsule lists.
+var capsuleSorts = map[string]sortKey[models.Capsule]{
+	models.SortCreatedAt: {"capsules.created_at", func(c models.Capsule) interface{} { return c.CreatedAt }},
+	models.SortUpdatedAt: {"capsules.updated_at", func(c models.Capsule) interface{} { return c.UpdatedAt }},
+	models.SortTitle:     {"capsules.title", func(c models.Capsule) interface{} { return c.Title }},
+}
+
+// GetCapsulesByUser returns one page of the capsules owned by a user with optional filters.
+// Lists with a search query default to relevance order, others to most recently updated.
+func (s *capsuleStore) GetCapsulesByUser(userID string, filters *models.CapsuleFilters, opts models.ListOptions) ([]models.Capsule, models.PageInfo, error) {
+	query := applyCapsuleFilters(s.DB.Model(&models.Capsule{}).Where("capsules.user_id = ?", userID), filters)
+	spec := listSpec[models.Capsule]{
+		id:          "capsules.id",
+		idOf:        func(c models.Capsule) string { return c.ID },
+		sorts:       capsuleSorts,
+		defaultSort: models.SortUpdatedAt,
 	}
-	return capsules, nil
+	if filters != nil && filters.Q != "" {
+		spec.relevance = &clause.Expr{SQL: capsuleRank, Vars: []interface{}{filters.Q, filters.Q}}
+		spec.defaultSort = models.SortRelevance
+	}
+	return pageQuery(query, spec, opts)
+}
+
+// applyCapsuleFilters narrows a capsule query by topic name or ID (optionally with
+// subtopics), tags (any spelling or alias), full-text or fuzzy title query, privacy and
+// creation date.
+func applyCapsuleFilters(query *gorm.DB, filters *models.CapsuleFilters) *gorm.DB {
+	if filters == nil {
+		return query
+	}
+	switch {
+	case filters.Topic != "" && filters.IncludeSubtopics:
+		query = query.Where("capsules.topic_id IN ("+topicSubtree("LOWER(name) = LOWER(?)")+")", filters.Topic)
+	case filters.Topic != "":
+		query = query.Where("LOWER(capsules.topic) = LOWER(?)", filters.Topic)
+	}
+	switch {
+	case filters.TopicID != "" && filters.IncludeSubtopics:
+		query = query.Where("capsules.topic_id IN ("+topicSubtree("id = ?")+")", filters.TopicID)
+	case filters.TopicID != "":
+		query = query.Where("capsules.topic_id = ?", filters.TopicID)
+	}
+	for _, tag := range filters.Tags {
+		slug := models.TagSlug(tag)
+		query = query.Where(capsuleHasTag, slug, slug)
+	}
+	if filters.Q != "" {
+		query = query.Where(capsuleTextMatch, filters.Q, filters.Q)
+	}
+	if filters.IsPrivate != nil {
+		query = query.Where("is_private = ?", *filters.IsPrivate)
+	}
+	if filters.CreatedFrom != nil {
+		query = query.Where("capsules.created_at >= ?", *filters.CreatedFrom)
+	}
+	if filters.CreatedBefore != nil {
+		query = query.Where("capsules.created_at < ?", *filters.CreatedBefore)
+	}
+	return query
 }
 
 // FindByID returns a capsule by its ID.
@@ -82,27 +135,95 @@ func (s *capsuleStore) FindByID(id string) (*models.Capsule, error) {
 	return &capsule, nil
 }
 
-// UpdateCapsule updates title, content, topic, and tags.
+// UpdateCapsule updates title, content, topic, and tags, recording a new revision. The
+// owner and users with editor access may update.
 func (s *capsuleStore) UpdateCapsule(id, userID string, updated models.Capsule) (*models.Capsule, error) {
-	result := s.DB.Model(&models.Capsule{}).Where("id = ? AND user_id = ?", id, userID).Updates(map[string]interface{}{
-		"title":      updated.Title,
-		"content":    updated.Content,
-		"topic":      updated.Topic,
-		"tags":       updated.Tags,
-		"is_private": updated.IsPrivate,
+	return s.applyUpdate(id, userID, updated.CapsuleInput, nil)
+}
+
+// applyUpdate writes input to the capsule and records it as a new revision. The owner and
+// editors may update; only the owner may change privacy. The capsule row is locked so
+// concurrent saves get consecutive revision numbers. An empty content format keeps the current
+// one. Its wiki links are stored again, a new title is written into the links of capsules
+// that named it by the old one, and its cached render is dropped.
+func (s *capsuleStore) applyUpdate(id, userID string, input models.CapsuleInput, restoredFrom *int) (*models.Capsule, error) {
+	var capsule models.Capsule
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&capsule).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("capsule not found or unauthorized")
+			}
+			return err
+		}
+		if capsule.UserID != userID {
+			permission, err := sharePermission(tx, id, userID)
+			if err != nil {
+				return err
+			}
+			if !models.PermissionAtLeast(permission, models.PermissionEditor) {
+				return errors.New("capsule not found or unauthorized")
+			}
+			input.IsPrivate = capsule.IsPrivate
+		}
+		// Capsules created before revisions existed get their pre-edit state as revision 1.
+		var count int64
+		if err := tx.Model(&models.CapsuleRevision{}).Where("capsule_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			if err := writeRevision(tx, &capsule, capsule.UserID, nil); err != nil {
+				return err
+			}
+		}
+
+		if err := resolveTopic(tx, userID, &input); err != nil {
+			return err
+		}
+		if err := resolveTags(tx, &input); err != nil {
+			return err
+		}
+		if input.ContentFormat == "" {
+			input.ContentFormat = capsule.ContentFormat
+		}
+		oldTitle := capsule.Title
+		if err := tx.Model(&capsule).Updates(map[string]interface{}{
+			"title":          input.Title,
+			"content":        input.Content,
+			"content_format": input.ContentFormat,
+			"topic":          input.Topic,
+			"topic_id":       input.TopicID,
+			"tags":           input.Tags,
+			"is_private":     input.IsPrivate,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("capsule_id = ?", id).Delete(&models.CapsuleRender{}).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&capsule, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if capsule.Title != oldTitle {
+			if err := retitleWikiLinks(tx, &capsule, oldTitle); err != nil {
+				return err
+			}
+		}
+		if err := syncWikiLinks(tx, &capsule); err != nil {
+			return err
+		}
+		if err := resolveWikiLinksTo(tx, &capsule); err != nil {
+			return err
+		}
+		return writeRevision(tx, &capsule, userID, restoredFrom)
 	})
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	if result.RowsAffected == 0 {
-		return nil, errors.New("capsule not found or unauthorized")
+	if err != nil {
+		return nil, err
 	}
-	var capsule models.Capsule
-	s.DB.First(&capsule, "id = ?", id)
 	return &capsule, nil
 }
 
-// DeleteCapsule removes a capsule by ID (only owner).
+// DeleteCapsule moves a capsule to the trash (only owner). Revisions are kept until it is purged.
 func (s *capsuleStore) DeleteCapsule(id, userID string) error {
 	result := s.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Capsule{})
 	if result.Error != nil {
@@ -114,14 +235,195 @@ func (s *capsuleStore) DeleteCapsule(id, userID string) error {
 	return nil
 }
 
-// SearchAllCapsules searches all capsules (admin only, no user filter).
-func (s *capsuleStore) SearchAllCapsules(query string, limit int) ([]models.Capsule, error) {
-	if limit <= 0 {
-		limit = 20
+// RestoreCapsule moves a capsule out of the trash (only owner). Broken links of the owner
+// that name it are resolved to it again.
+func (s *capsuleStore) RestoreCapsule(id, userID string) (*models.Capsule, error) {
+	var capsule models.Capsule
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&models.Capsule{}).
+			Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+			UpdateColumn("deleted_at", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("capsule not found in trash")
+		}
+		if err := tx.First(&capsule, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return resolveWikiLinksTo(tx, &capsule)
+	})
+	if err != nil {
+		return nil, err
 	}
-	pattern := "%" + strings.ToLower(query) + "%"
-	var capsules []models.Capsule
-	err := s.DB.Where("LOWER(title) LIKE ? OR LOWER(content) LIKE ? OR tags::text ILIKE ?",
-		pattern, pattern, pattern).Limit(limit).Find(&capsules).Error
-	return capsules, err
+	return &capsule, nil
+}
+
+// PurgeCapsule permanently deletes a trashed capsule with its revisions, shares, public links,
+// wiki links and cached render (only owner). Wiki links to it from other capsules become broken.
+func (s *capsuleStore) PurgeCapsule(id, userID string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).Delete(&models.Capsule{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("capsule not found in trash")
+		}
+		if err := tx.Where("capsule_id = ?", id).Delete(&models.CapsuleShare{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("capsule_id = ?", id).Delete(&models.CapsuleLink{}).Error; err != nil {
+			return err
+		}
+		if err := unlinkCapsules(tx, []string{id}); err != nil {
+			return err
+		}
+		if err := tx.Where("capsule_id = ?", id).Delete(&models.CapsuleRender{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("capsule_id = ?", id).Delete(&models.CapsuleRevision{}).Error
+	})
+}
+
+// purgeBatchSize is how many trashed items a purge deletes per transaction, keeping IN lists
+// and row locks small however much has piled up.
+const purgeBatchSize = 500
+
+// PurgeTrashedCapsules permanently deletes capsules trashed before the given time, with
+// their revisions, shares, public links, wiki links and cached renders. An empty userID purges
+// every user's trash. Capsules are deleted in batches, each in its own transaction; on error
+// the count still includes the batches already purged.
+func (s *capsuleStore) PurgeTrashedCapsules(userID string, deletedBefore time.Time) (int, error) {
+	purged := 0
+	for {
+		var ids []string
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			query := tx.Unscoped().Model(&models.Capsule{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore)
+			if userID != "" {
+				query = query.Where("user_id = ?", userID)
+			}
+			if err := query.Order("id").Limit(purgeBatchSize).Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+			if err := tx.Where("capsule_id IN ?", ids).Delete(&models.CapsuleRevision{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("capsule_id IN ?", ids).Delete(&models.CapsuleShare{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("capsule_id IN ?", ids).Delete(&models.CapsuleLink{}).Error; err != nil {
+				return err
+			}
+			if err := unlinkCapsules(tx, ids); err != nil {
+				return err
+			}
+			if err := tx.Where("capsule_id IN ?", ids).Delete(&models.CapsuleRender{}).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Where("id IN ?", ids).Delete(&models.Capsule{}).Error
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged += len(ids)
+		if len(ids) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// ListRevisions returns a capsule's revisions, newest first, without their content.
+func (s *capsuleStore) ListRevisions(capsuleID string) ([]models.CapsuleRevision, error) {
+	var revisions []models.CapsuleRevision
+	err := s.DB.Omit("content").Where("capsule_id = ?", capsuleID).Order("revision DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+// GetRevision returns one revision of a capsule.
+func (s *capsuleStore) GetRevision(capsuleID string, revision int) (*models.CapsuleRevision, error) {
+	var rev models.CapsuleRevision
+	err := s.DB.Where("capsule_id = ? AND revision = ?", capsuleID, revision).First(&rev).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("revision not found")
+		}
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// RestoreRevision copies a revision back onto the capsule. The restore is itself
+// recorded as a new revision, so history is never rewritten.
+func (s *capsuleStore) RestoreRevision(capsuleID, userID string, revision int) (*models.Capsule, error) {
+	rev, err := s.GetRevision(capsuleID, revision)
+	if err != nil {
+		return nil, err
+	}
+	return s.applyUpdate(capsuleID, userID, rev.CapsuleInput, &revision)
+}
+
+// writeRevision appends the capsule's current state as its next revision.
+func writeRevision(tx *gorm.DB, capsule *models.Capsule, editorID string, restoredFrom *int) error {
+	var last int
+	if err := tx.Model(&models.CapsuleRevision{}).Where("capsule_id = ?", capsule.ID).
+		Select("COALESCE(MAX(revision), 0)").Scan(&last).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.CapsuleRevision{
+		ID:           utils.GenerateUUID(),
+		CapsuleID:    capsule.ID,
+		Revision:     last + 1,
+		EditorID:     editorID,
+		RestoredFrom: restoredFrom,
+		CapsuleInput: capsule.CapsuleInput,
+	}).Error
+}
+
+// resolveTopic links a capsule input to its topic row. An existing topic_id wins; otherwise
+// the topic name is matched case-insensitively, and when none matches the name is proposed
+// as a new topic by userID, pending admin approval. The name is then replaced with the
+// topic's own, so capsules always carry its current name.
+func resolveTopic(tx *gorm.DB, userID string, input *models.CapsuleInput) error {
+	var topic models.Topic
+	if input.TopicID != nil && *input.TopicID != "" {
+		err := tx.Where("id = ?", *input.TopicID).First(&topic).Error
+		if err == nil {
+			input.Topic = topic.Name
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		// A revision may point at a topic that is gone; its name still identifies it.
+		if strings.TrimSpace(input.Topic) == "" {
+			return ErrTopicNotFound
+		}
+	}
+
+	name := strings.TrimSpace(input.Topic)
+	if name == "" {
+		input.Topic, input.TopicID = "", nil
+		return nil
+	}
+	err := tx.Where("LOWER(name) = LOWER(?)", name).Order("created_at").First(&topic).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		topic = models.Topic{
+			ID:         utils.GenerateUUID(),
+			TopicInput: models.TopicInput{Name: name},
+			CreatedBy:  userID,
+			Status:     models.TopicStatusPending,
+		}
+		err = tx.Create(&topic).Error
+	}
+	if err != nil {
+		return err
+	}
+	input.Topic, input.TopicID = topic.Name, &topic.ID
+	return nil
 }
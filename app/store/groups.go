@@ -0,0 +1,201 @@
+package store
+
+import (
+	"errors"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// groupStore implements group storage with GORM.
+type groupStore struct {
+	DB *gorm.DB
+}
+
+// NewGroupStore returns a GroupStore backed by GORM.
+func NewGroupStore(db *gorm.DB) GroupStore {
+	return &groupStore{DB: db}
+}
+
+// CreateGroup creates a group with its owner as the first member.
+func (s *groupStore) CreateGroup(ownerID, name string) (*models.Group, error) {
+	group := models.Group{ID: utils.GenerateUUID(), OwnerID: ownerID, Name: name, MemberCount: 1}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&group).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.GroupMember{GroupID: group.ID, UserID: ownerID, Status: models.GroupMemberActive}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// ListGroups returns the groups a user belongs to or is invited to, by name, each with the
+// user's membership status.
+func (s *groupStore) ListGroups(userID string) ([]models.Group, error) {
+	var memberships []models.GroupMember
+	if err := s.DB.Where("user_id = ?", userID).Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+	groups := []models.Group{}
+	if len(memberships) == 0 {
+		return groups, nil
+	}
+	ids := make([]string, len(memberships))
+	status := make(map[string]string, len(memberships))
+	for i, m := range memberships {
+		ids[i] = m.GroupID
+		status[m.GroupID] = m.Status
+	}
+	if err := s.DB.Where("id IN ?", ids).Order("name").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		GroupID string
+		Count   int
+	}
+	if err := s.DB.Model(&models.GroupMember{}).Select("group_id, COUNT(*) AS count").
+		Where("group_id IN ? AND status = ?", ids, models.GroupMemberActive).Group("group_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]int, len(counts))
+	for _, c := range counts {
+		byID[c.GroupID] = c.Count
+	}
+	for i := range groups {
+		groups[i].MemberCount = byID[groups[i].ID]
+		groups[i].Membership = status[groups[i].ID]
+	}
+	return groups, nil
+}
+
+// FindByID returns a group by its ID.
+func (s *groupStore) FindByID(id string) (*models.Group, error) {
+	var group models.Group
+	if err := s.DB.First(&group, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("group not found")
+		}
+		return nil, err
+	}
+	var count int64
+	if err := s.DB.Model(&models.GroupMember{}).Where("group_id = ? AND status = ?", id, models.GroupMemberActive).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	group.MemberCount = int(count)
+	return &group, nil
+}
+
+// RenameGroup changes a group's name (only owner).
+func (s *groupStore) RenameGroup(id, ownerID, name string) (*models.Group, error) {
+	result := s.DB.Model(&models.Group{}).Where("id = ? AND owner_id = ?", id, ownerID).Update("name", name)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("group not found or unauthorized")
+	}
+	return s.FindByID(id)
+}
+
+// DeleteGroup removes a group with its memberships and every capsule share granted to it (only owner).
+func (s *groupStore) DeleteGroup(id, ownerID string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND owner_id = ?", id, ownerID).Delete(&models.Group{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("group not found or unauthorized")
+		}
+		if err := tx.Where("group_id = ?", id).Delete(&models.GroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("grantee_type = ? AND grantee_id = ?", models.GranteeGroup, id).Delete(&models.CapsuleShare{}).Error
+	})
+}
+
+// ListMembers returns a group's members and invited users by name. Email addresses are
+// left out so joining a group does not reveal its members' accounts.
+func (s *groupStore) ListMembers(groupID string) ([]models.GroupMemberView, error) {
+	members := []models.GroupMemberView{}
+	err := s.DB.Table("group_members").
+		Select("group_members.user_id, users.name, group_members.status, group_members.created_at").
+		Joins("JOIN users ON users.id = group_members.user_id").
+		Where("group_members.group_id = ?", groupID).
+		Order("users.name").Scan(&members).Error
+	return members, err
+}
+
+// IsMember reports whether a user is an active member of a group. Pending invitations
+// do not count.
+func (s *groupStore) IsMember(groupID, userID string) (bool, error) {
+	var count int64
+	err := s.DB.Model(&models.GroupMember{}).
+		Where("group_id = ? AND user_id = ? AND status = ?", groupID, userID, models.GroupMemberActive).Count(&count).Error
+	return count > 0, err
+}
+
+// InviteMember invites a user to a group (only owner); they join once they accept.
+// Inviting a member or an already invited user is a no-op.
+func (s *groupStore) InviteMember(groupID, ownerID, userID string) error {
+	if err := s.checkOwner(groupID, ownerID); err != nil {
+		return err
+	}
+	return s.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.GroupMember{GroupID: groupID, UserID: userID, Status: models.GroupMemberInvited}).Error
+}
+
+// AcceptInvite makes an invited user an active member of a group.
+func (s *groupStore) AcceptInvite(groupID, userID string) error {
+	result := s.DB.Model(&models.GroupMember{}).
+		Where("group_id = ? AND user_id = ? AND status = ?", groupID, userID, models.GroupMemberInvited).
+		Update("status", models.GroupMemberActive)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invitation not found")
+	}
+	return nil
+}
+
+// RemoveMember removes a user or an invitation from a group. The owner may remove anyone
+// but themselves; members may only remove themselves (leave, or decline an invitation).
+func (s *groupStore) RemoveMember(groupID, actorID, userID string) error {
+	group, err := s.FindByID(groupID)
+	if err != nil {
+		return err
+	}
+	if userID == group.OwnerID {
+		return errors.New("the group owner cannot be removed")
+	}
+	if actorID != group.OwnerID && actorID != userID {
+		return errors.New("group not found or unauthorized")
+	}
+	result := s.DB.Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&models.GroupMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("member not found")
+	}
+	return nil
+}
+
+func (s *groupStore) checkOwner(groupID, ownerID string) error {
+	var count int64
+	if err := s.DB.Model(&models.Group{}).Where("id = ? AND owner_id = ?", groupID, ownerID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("group not found or unauthorized")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"testing"
+
+	"knowledge-capsule/app/models"
+
+	"gorm.io/gorm"
+)
+
+// shareFixture holds a group owned by "owner" and a capsule shared with it as viewer.
+type shareFixture struct {
+	db     *gorm.DB
+	groups GroupStore
+	group  *models.Group
+}
+
+func newShareFixture(t *testing.T) *shareFixture {
+	t.Helper()
+	db := newTestDB(t, &models.User{}, &models.Group{}, &models.GroupMember{}, &models.CapsuleShare{})
+	for _, u := range []models.User{{ID: "owner", Name: "Owner", Email: "owner@example.com"}, {ID: "bob", Name: "Bob", Email: "bob@example.com"}} {
+		if err := db.Create(&u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	groups := NewGroupStore(db)
+	group, err := groups.CreateGroup("owner", "Team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &shareFixture{db: db, groups: groups, group: group}
+	f.share(t, models.GranteeGroup, group.ID, models.PermissionViewer)
+	return f
+}
+
+func (f *shareFixture) share(t *testing.T, granteeType, granteeID, permission string) {
+	t.Helper()
+	share := models.CapsuleShare{ID: granteeType + "-" + granteeID, CapsuleID: "c1", GranteeType: granteeType, GranteeID: granteeID, Permission: permission}
+	if err := f.db.Create(&share).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *shareFixture) permission(t *testing.T, userID string) string {
+	t.Helper()
+	p, err := sharePermission(f.db, "c1", userID)
+	if err != nil {
+		t.Fatalf("sharePermission(%s) error = %v", userID, err)
+	}
+	return p
+}
+
+func TestSharePermissionNeedsAcceptedInvite(t *testing.T) {
+	f := newShareFixture(t)
+	if got := f.permission(t, "owner"); got != models.PermissionViewer {
+		t.Errorf("group owner permission = %q, want viewer", got)
+	}
+
+	if err := f.groups.InviteMember(f.group.ID, "owner", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.permission(t, "bob"); got != "" {
+		t.Errorf("invited user permission = %q, want none until they accept", got)
+	}
+	if member, _ := f.groups.IsMember(f.group.ID, "bob"); member {
+		t.Error("IsMember() counted a pending invitation")
+	}
+
+	if err := f.groups.AcceptInvite(f.group.ID, "bob"); err != nil {
+		t.Fatalf("AcceptInvite() error = %v", err)
+	}
+	if got := f.permission(t, "bob"); got != models.PermissionViewer {
+		t.Errorf("accepted member permission = %q, want viewer", got)
+	}
+	if err := f.groups.AcceptInvite(f.group.ID, "bob"); err == nil {
+		t.Error("AcceptInvite() accepted the same invitation twice")
+	}
+}
+
+func TestSharePermissionStrongestGrant(t *testing.T) {
+	f := newShareFixture(t)
+	f.groups.InviteMember(f.group.ID, "owner", "bob")
+	f.groups.AcceptInvite(f.group.ID, "bob")
+
+	f.share(t, models.GranteeUser, "bob", models.PermissionEditor)
+	if got := f.permission(t, "bob"); got != models.PermissionEditor {
+		t.Errorf("direct editor over group viewer = %q, want editor", got)
+	}
+	if got := f.permission(t, "stranger"); got != "" {
+		t.Errorf("stranger permission = %q, want none", got)
+	}
+	// A user ID equal to the group ID must not match the group's grant as a direct one.
+	if got := f.permission(t, f.group.ID); got != "" {
+		t.Errorf("group ID as user permission = %q, want none", got)
+	}
+}
+
+func TestGroupInvitations(t *testing.T) {
+	f := newShareFixture(t)
+	if err := f.groups.InviteMember(f.group.ID, "bob", "owner"); err == nil {
+		t.Error("InviteMember() allowed a non-owner to invite")
+	}
+	if err := f.groups.InviteMember(f.group.ID, "owner", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.groups.InviteMember(f.group.ID, "owner", "owner"); err != nil {
+		t.Errorf("InviteMember(existing member) error = %v, want no-op", err)
+	}
+
+	groups, err := f.groups.ListGroups("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Membership != models.GroupMemberInvited || groups[0].MemberCount != 1 {
+		t.Errorf("ListGroups(invited) = %+v, want one invitation to a group of 1", groups)
+	}
+	members, err := f.groups.ListMembers(f.group.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := map[string]string{}
+	for _, m := range members {
+		status[m.UserID] = m.Status
+	}
+	if len(members) != 2 || status["owner"] != models.GroupMemberActive || status["bob"] != models.GroupMemberInvited {
+		t.Errorf("ListMembers() = %+v", members)
+	}
+
+	// Declining removes the invitation.
+	if err := f.groups.RemoveMember(f.group.ID, "bob", "bob"); err != nil {
+		t.Fatalf("RemoveMember(decline) error = %v", err)
+	}
+	if err := f.groups.AcceptInvite(f.group.ID, "bob"); err == nil {
+		t.Error("AcceptInvite() accepted a declined invitation")
+	}
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+// unusablePasswordHash is stored for provisioned users; it never matches a password.
+// Such users sign in through their provider or set a password via password reset.
+const unusablePasswordHash = "!"
+
+// identityStore implements external identity storage with GORM.
+type identityStore struct {
+	DB *gorm.DB
+}
+
+// NewIdentityStore returns an IdentityStore backed by GORM.
+func NewIdentityStore(db *gorm.DB) IdentityStore {
+	return &identityStore{DB: db}
+}
+
+// FindIdentity returns the link for a provider subject.
+func (s *identityStore) FindIdentity(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := s.DB.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("identity not found")
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// LinkIdentity links a provider subject to an existing user.
+func (s *identityStore) LinkIdentity(userID, provider, subject, email string) error {
+	now := time.Now()
+	return s.DB.Create(&models.UserIdentity{
+		ID:        utils.GenerateUUID(),
+		UserID:    userID,
+		Provider:  provider,
+		Subject:   subject,
+		Email:     email,
+		LastLogin: &now,
+	}).Error
+}
+
+// ProvisionUser creates a user with a verified email and no usable password, linked to
+// the provider subject.
+func (s *identityStore) ProvisionUser(name, email, provider, subject string) (*models.User, error) {
+	now := time.Now()
+	user := models.User{
+		ID:              utils.GenerateUUID(),
+		Name:            name,
+		Email:           email,
+		PasswordHash:    unusablePasswordHash,
+		Role:            models.RoleUser,
+		EmailVerifiedAt: &now,
+	}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.User
+		if err := tx.Where("email = ?", email).First(&existing).Error; err == nil {
+			return errors.New("email already exists")
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserIdentity{
+			ID:        utils.GenerateUUID(),
+			UserID:    user.ID,
+			Provider:  provider,
+			Subject:   subject,
+			Email:     email,
+			LastLogin: &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// RecordLogin updates the last login time and the email last seen from the provider.
+func (s *identityStore) RecordLogin(id, email string) error {
+	return s.DB.Model(&models.UserIdentity{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"last_login": time.Now(), "email": email}).Error
+}
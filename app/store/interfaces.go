@@ -1,6 +1,10 @@
 package store
 
-import "knowledge-capsule/app/models"
+import (
+	"time"
+
+	"knowledge-capsule/app/models"
+)
 
 // UserStore defines user storage operations.
 type UserStore interface {
@@ -10,32 +14,170 @@ type UserStore interface {
 	ListUsers(q, role string, page, limit int) ([]models.User, int, error)
 	UpdateProfile(userID string, name, avatarURL string) error
 	UpdateUserRole(id, role string) error
+	UpdatePasswordHash(id, passwordHash string) error
+	MarkEmailVerified(id string) error
 	SearchUsers(query string, limit int) ([]models.User, error)
 	ListAdmins(page, limit int) ([]models.User, int, error)
 }
 
 // CapsuleStore defines capsule storage operations.
 type CapsuleStore interface {
-	AddCapsule(userID, title, content, topic string, tags []string, isPrivate bool) (*models.Capsule, error)
-	GetCapsulesByUser(userID string, filters *models.CapsuleFilters) ([]models.Capsule, error)
+	AddCapsule(userID string, input models.CapsuleInput) (*models.Capsule, error)
+	GetCapsulesByUser(userID string, filters *models.CapsuleFilters, opts models.ListOptions) ([]models.Capsule, models.PageInfo, error)
 	FindByID(id string) (*models.Capsule, error)
 	UpdateCapsule(id, userID string, updated models.Capsule) (*models.Capsule, error)
 	DeleteCapsule(id, userID string) error
-	SearchAllCapsules(query string, limit int) ([]models.Capsule, error)
+	SearchCapsules(userID string, filters *models.CapsuleFilters, page, limit int) ([]models.CapsuleSearchResult, int, error)
+	SearchAllCapsules(query string, limit int) ([]models.CapsuleSearchResult, error)
+	CapsuleFacets(userID string, filters *models.CapsuleFilters, interval string) (*models.CapsuleFacets, error)
+	SuggestTitles(userID, query string, limit int) ([]models.Suggestion, error)
+	SuggestTags(userID, query string, limit int) ([]models.Suggestion, error)
+	ListRevisions(capsuleID string) ([]models.CapsuleRevision, error)
+	GetRevision(capsuleID string, revision int) (*models.CapsuleRevision, error)
+	RestoreRevision(capsuleID, userID string, revision int) (*models.Capsule, error)
+	ListTrash(userID string, types []string, opts models.ListOptions) ([]models.TrashItem, models.PageInfo, error)
+	RestoreCapsule(id, userID string) (*models.Capsule, error)
+	PurgeCapsule(id, userID string) error
+	PurgeTrashedCapsules(userID string, deletedBefore time.Time) (int, error)
+	FindAccessible(id, userID string) (*models.Capsule, error)
+	ListSharedWithUser(userID, minPermission string, filters *models.CapsuleFilters, opts models.ListOptions) ([]models.Capsule, models.PageInfo, error)
+	ListShares(capsuleID string) ([]models.CapsuleShare, error)
+	ShareCapsule(capsuleID, ownerID, granteeType, granteeID, permission string) (*models.CapsuleShare, error)
+	UnshareCapsule(capsuleID, ownerID, shareID string) error
+	ListPublicCapsules(filters *models.ExploreFilters, page, limit int) ([]models.PublicCapsule, int, error)
+	GetPublicCapsule(id string) (*models.PublicCapsule, error)
+	ListOutlinks(capsuleID, userID string) ([]models.WikiLinkView, error)
+	ListBacklinks(capsuleID, userID string) ([]models.WikiLinkView, error)
+	GraphCapsules(userID string, limit int) ([]models.GraphCapsule, error)
+	GraphLinks(userID string) ([]models.WikiLink, error)
+	CachedRenders(ids []string) (map[string]models.CapsuleRender, error)
+	SaveRender(render *models.CapsuleRender) error
+	ImportCapsule(userID string, input models.CapsuleInput, createdAt, updatedAt *time.Time) (*models.Capsule, error)
+	ExportCapsules(userID string, filters *models.CapsuleFilters) ([]models.Capsule, error)
+	FindByTitles(userID string, titles []string) ([]models.Capsule, error)
+	BulkUpdateCapsules(userID string, input models.BulkInput, filters *models.CapsuleFilters) (*models.BulkResult, error)
+}
+
+// CapsuleLinkStore defines public capsule link operations.
+type CapsuleLinkStore interface {
+	CreateLink(capsuleID, ownerID, slug, passwordHash string, expiresAt *time.Time) (*models.CapsuleLink, error)
+	ListLinks(capsuleID string) ([]models.CapsuleLink, error)
+	DeleteLink(capsuleID, ownerID, linkID string) error
+	ResolveLink(slug string) (*models.CapsuleLink, *models.PublicCapsule, error)
+	RecordView(link *models.CapsuleLink) error
+}
+
+// GroupStore defines user group storage operations.
+type GroupStore interface {
+	CreateGroup(ownerID, name string) (*models.Group, error)
+	ListGroups(userID string) ([]models.Group, error)
+	FindByID(id string) (*models.Group, error)
+	RenameGroup(id, ownerID, name string) (*models.Group, error)
+	DeleteGroup(id, ownerID string) error
+	ListMembers(groupID string) ([]models.GroupMemberView, error)
+	IsMember(groupID, userID string) (bool, error)
+	InviteMember(groupID, ownerID, userID string) error
+	AcceptInvite(groupID, userID string) error
+	RemoveMember(groupID, actorID, userID string) error
 }
 
 // TopicStore defines topic storage operations.
 type TopicStore interface {
-	AddTopic(name, description string) (*models.Topic, error)
-	GetAllTopics(filters *models.TopicFilters) ([]models.Topic, error)
+	AddTopic(userID string, input models.TopicInput, approved bool) (*models.Topic, error)
+	GetAllTopics(filters *models.TopicFilters, opts models.ListOptions) ([]models.Topic, models.PageInfo, error)
 	FindByID(id string) (*models.Topic, error)
 	UpdateTopic(id, name, description string) (*models.Topic, error)
-	DeleteTopic(id string) error
+	DeleteTopic(id, userID string) error
+	RestoreTopic(id, userID string) (*models.Topic, error)
+	PurgeTopic(id, userID string) error
+	PurgeTrashedTopics(userID string, deletedBefore time.Time) (int, error)
 	SearchTopics(query string, limit int) ([]models.Topic, error)
+	SuggestTopics(query string, limit int) ([]models.Suggestion, error)
+	TopicTree(rootID string) ([]models.TopicNode, error)
+	TopicPaths(ids []string) (map[string][]models.TopicRef, error)
+	MoveTopic(id string, parentID *string) (*models.Topic, error)
+	IsMaintainer(topicID, userID string) (bool, error)
+	ListMaintainers(topicID string) ([]models.TopicMaintainerView, error)
+	AddMaintainer(topicID, userID, addedBy string) error
+	RemoveMaintainer(topicID, userID string) error
+	ListPendingTopics(page, limit int) ([]models.Topic, int, error)
+	ApproveTopic(id, reviewerID string) (*models.Topic, error)
+	RejectTopic(id string) error
 }
 
 // MessageStore defines message storage operations.
 type MessageStore interface {
 	SaveMessage(senderID, receiverID, content string, msgType models.MessageType, fileURL string) (*models.Message, error)
-	GetMessagesBetweenUsers(user1ID, user2ID string) ([]models.Message, error)
+	GetMessagesBetweenUsers(user1ID, user2ID string, opts models.ListOptions) ([]models.Message, models.PageInfo, error)
+}
+
+// SessionStore defines login session (refresh token) storage operations.
+type SessionStore interface {
+	CreateSession(userID, userAgent, ipAddress string, mfa bool, ttl time.Duration) (*models.Session, string, error)
+	RotateSession(refreshToken, userAgent, ipAddress string, ttl time.Duration) (*models.Session, string, error)
+	ListActiveSessions(userID string) ([]models.Session, error)
+	IsSessionActive(familyID string) (bool, error)
+	RevokeSession(familyID, userID string) error
+	RevokeUserSessions(userID, exceptFamilyID string) (int, error)
+}
+
+// OneTimeTokenStore defines single-use emailed token operations.
+type OneTimeTokenStore interface {
+	CreateToken(userID, purpose string, ttl time.Duration) (string, error)
+	ConsumeToken(token, purpose string) (string, error)
+}
+
+// APITokenStore defines personal access token storage operations.
+type APITokenStore interface {
+	CreateToken(userID, name string, scopes []string, expiresAt *time.Time) (*models.APIToken, string, error)
+	ListTokens(userID string) ([]models.APIToken, error)
+	FindByID(id, userID string) (*models.APIToken, error)
+	UpdateToken(id, userID, name string, scopes []string) (*models.APIToken, error)
+	DeleteToken(id, userID string) error
+	DeleteUserTokens(userID string) (int, error)
+	Authenticate(raw, ipAddress string) (*models.APIToken, error)
+}
+
+// TwoFactorStore defines TOTP and recovery code operations.
+type TwoFactorStore interface {
+	SetPendingSecret(userID, secret string) error
+	Enable(userID string, step int64) ([]string, error)
+	Disable(userID string) error
+	MarkStepUsed(userID string, step int64) (bool, error)
+	RegenerateRecoveryCodes(userID string) ([]string, error)
+	UseRecoveryCode(userID, code string) (bool, error)
+	CountRecoveryCodes(userID string) (int, error)
+}
+
+// SettingsStore defines global setting operations.
+type SettingsStore interface {
+	Get(key string) (string, error)
+	GetBool(key string) (bool, error)
+	Set(key, value string) error
+}
+
+// IdentityStore defines external (OIDC) identity link operations.
+type IdentityStore interface {
+	FindIdentity(provider, subject string) (*models.UserIdentity, error)
+	LinkIdentity(userID, provider, subject, email string) error
+	ProvisionUser(name, email, provider, subject string) (*models.User, error)
+	RecordLogin(id, email string) error
+}
+
+// ThrottleStore defines failed-attempt tracking and lockout operations.
+type ThrottleStore interface {
+	LockedUntil(keys ...string) (time.Time, error)
+	RecordFailure(scope, subject string, policy models.ThrottlePolicy) (*models.AuthThrottle, error)
+	Reset(key string) (bool, error)
+	ListThrottles(lockedOnly bool, page, limit int) ([]models.AuthThrottle, int, error)
+}
+
+// TagStore defines tag registry operations.
+type TagStore interface {
+	ListTags(q, sort string, page, limit int) ([]models.Tag, int, error)
+	RenameTags(renames []models.TagRename) (int, error)
+	MergeTags(sources []string, target string) (int, error)
+	ListAliases() ([]models.TagAliasView, error)
+	SetAlias(alias, tag string) (*models.TagAliasView, error)
+	DeleteAlias(alias string) error
 }
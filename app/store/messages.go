@@ -33,15 +33,19 @@ func (s *messageStore) SaveMessage(senderID, receiverID, content string, msgType
 	return &msg, nil
 }
 
-// GetMessagesBetweenUsers retrieves conversation history between two users.
-func (s *messageStore) GetMessagesBetweenUsers(user1ID, user2ID string) ([]models.Message, error) {
-	var messages []models.Message
-	err := s.DB.Where(
+// GetMessagesBetweenUsers returns one page of the conversation between two users. Only
+// created_at sorting is supported; opts.Order picks oldest or newest first.
+func (s *messageStore) GetMessagesBetweenUsers(user1ID, user2ID string, opts models.ListOptions) ([]models.Message, models.PageInfo, error) {
+	query := s.DB.Model(&models.Message{}).Where(
 		"(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
 		user1ID, user2ID, user2ID, user1ID,
-	).Order("created_at ASC").Find(&messages).Error
-	if err != nil {
-		return nil, err
-	}
-	return messages, nil
+	)
+	return pageQuery(query, listSpec[models.Message]{
+		id:   "messages.id",
+		idOf: func(m models.Message) string { return m.ID },
+		sorts: map[string]sortKey[models.Message]{
+			models.SortCreatedAt: {"messages.created_at", func(m models.Message) interface{} { return m.CreatedAt }},
+		},
+		defaultSort: models.SortCreatedAt,
+	}, opts)
 }
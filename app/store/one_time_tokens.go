@@ -0,0 +1,77 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+const oneTimeTokenBytes = 32
+
+// oneTimeTokenStore implements one-time token storage with GORM.
+type oneTimeTokenStore struct {
+	DB *gorm.DB
+}
+
+// NewOneTimeTokenStore returns a OneTimeTokenStore backed by GORM.
+func NewOneTimeTokenStore(db *gorm.DB) OneTimeTokenStore {
+	return &oneTimeTokenStore{DB: db}
+}
+
+// CreateToken issues a new token for userID and purpose, invalidating any unused earlier ones.
+// Returns the raw token; only its hash is stored.
+func (s *oneTimeTokenStore) CreateToken(userID, purpose string, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateSecureToken(oneTimeTokenBytes)
+	if err != nil {
+		return "", err
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.OneTimeToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OneTimeToken{
+			ID:        utils.GenerateUUID(),
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConsumeToken marks a valid token as used and returns the user it was issued to.
+func (s *oneTimeTokenStore) ConsumeToken(token, purpose string) (string, error) {
+	var record models.OneTimeToken
+	err := s.DB.Where("token_hash = ? AND purpose = ?", utils.HashToken(token), purpose).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", errors.New("invalid or expired token")
+		}
+		return "", err
+	}
+	if record.UsedAt != nil || time.Now().After(record.ExpiresAt) {
+		return "", errors.New("invalid or expired token")
+	}
+
+	// Guard on used_at so concurrent requests cannot both consume the token.
+	result := s.DB.Model(&models.OneTimeToken{}).
+		Where("id = ? AND used_at IS NULL", record.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", errors.New("invalid or expired token")
+	}
+	return record.UserID, nil
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"knowledge-capsule/app/models"
+)
+
+func TestConsumeToken(t *testing.T) {
+	s := NewOneTimeTokenStore(newTestDB(t, &models.OneTimeToken{}))
+	token, err := s.CreateToken("u1", models.TokenPurposePasswordReset, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.ConsumeToken(token, models.TokenPurposeEmailVerification); err == nil {
+		t.Error("ConsumeToken() accepted a token issued for another purpose")
+	}
+	userID, err := s.ConsumeToken(token, models.TokenPurposePasswordReset)
+	if err != nil || userID != "u1" {
+		t.Fatalf("ConsumeToken() = %q, %v; want u1", userID, err)
+	}
+	if _, err := s.ConsumeToken(token, models.TokenPurposePasswordReset); err == nil {
+		t.Error("ConsumeToken() accepted a token twice")
+	}
+	if _, err := s.ConsumeToken("unknown", models.TokenPurposePasswordReset); err == nil {
+		t.Error("ConsumeToken() accepted an unknown token")
+	}
+}
+
+func TestConsumeTokenExpired(t *testing.T) {
+	s := NewOneTimeTokenStore(newTestDB(t, &models.OneTimeToken{}))
+	token, err := s.CreateToken("u1", models.TokenPurposeEmailVerification, -time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ConsumeToken(token, models.TokenPurposeEmailVerification); err == nil {
+		t.Error("ConsumeToken() accepted an expired token")
+	}
+}
+
+func TestCreateTokenInvalidatesEarlier(t *testing.T) {
+	s := NewOneTimeTokenStore(newTestDB(t, &models.OneTimeToken{}))
+	earlier, err := s.CreateToken("u1", models.TokenPurposePasswordReset, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify, err := s.CreateToken("u1", models.TokenPurposeEmailVerification, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.CreateToken("u2", models.TokenPurposePasswordReset, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := s.CreateToken("u1", models.TokenPurposePasswordReset, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.ConsumeToken(earlier, models.TokenPurposePasswordReset); err == nil {
+		t.Error("ConsumeToken() accepted a token replaced by a newer one")
+	}
+	for name, tt := range map[string]struct{ token, purpose, user string }{
+		"latest":        {latest, models.TokenPurposePasswordReset, "u1"},
+		"other purpose": {verify, models.TokenPurposeEmailVerification, "u1"},
+		"other user":    {other, models.TokenPurposePasswordReset, "u2"},
+	} {
+		if userID, err := s.ConsumeToken(tt.token, tt.purpose); err != nil || userID != tt.user {
+			t.Errorf("ConsumeToken(%s) = %q, %v; want %s", name, userID, err, tt.user)
+		}
+	}
+}
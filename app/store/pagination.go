@@ -0,0 +1,167 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"knowledge-capsule/app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidCursor is returned for a cursor that is malformed or was issued for another sort.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// sortKey is a sortable column and how to read its value from a loaded row. Values are
+// time.Time or string.
+type sortKey[T any] struct {
+	column string
+	value  func(T) interface{}
+}
+
+// listSpec describes how a list can be sorted and paged.
+type listSpec[T any] struct {
+	id          string // tie-breaker column, e.g. "capsules.id"
+	idOf        func(T) string
+	sorts       map[string]sortKey[T]
+	defaultSort string
+	// relevance orders by match quality; nil when the list has no search query
+	relevance *clause.Expr
+}
+
+// cursor is the decoded form of an opaque next_cursor. Keyset cursors hold the last row's
+// sort value and ID; relevance cursors hold an offset, because rank is not stored.
+type cursor struct {
+	Sort   string     `json:"s"`
+	Order  string     `json:"o"`
+	Time   *time.Time `json:"t,omitempty"`
+	Text   *string    `json:"v,omitempty"`
+	ID     string     `json:"id,omitempty"`
+	Offset int        `json:"off,omitempty"`
+}
+
+func encodeCursor(c cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(raw, &c) != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// defaultOrder is newest or best first for dates and relevance, alphabetical otherwise.
+func defaultOrder(sort string) string {
+	if sort == models.SortTitle || sort == models.SortName {
+		return models.OrderAsc
+	}
+	return models.OrderDesc
+}
+
+// pageQuery loads one page of query into a slice of T using the sort, order and page or
+// cursor in opts, with the total row count and the cursor for the following page. Sort
+// keys are assumed valid for spec; relevance falls back to the default sort without a query.
+// A cursor issued for another sort or order, or holding the wrong kind of value for the sort
+// column, fails with ErrInvalidCursor.
+func pageQuery[T any](query *gorm.DB, spec listSpec[T], opts models.ListOptions) ([]T, models.PageInfo, error) {
+	sort := opts.Sort
+	if sort == "" || (sort == models.SortRelevance && spec.relevance == nil) {
+		sort = spec.defaultSort
+	}
+	order := opts.Order
+	if order == "" {
+		order = defaultOrder(sort)
+	}
+	direction, compare := " DESC", "<"
+	if order == models.OrderAsc {
+		direction, compare = " ASC", ">"
+	}
+
+	var info models.PageInfo
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, info, err
+	}
+	info.Total = int(total)
+
+	offset := (opts.Page - 1) * opts.Limit
+	if offset < 0 || opts.Cursor != "" {
+		offset = 0
+	}
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor)
+		if err != nil || c.Sort != sort || c.Order != order {
+			return nil, info, ErrInvalidCursor
+		}
+		if sort == models.SortRelevance {
+			if c.Offset < 0 || c.Time != nil || c.Text != nil {
+				return nil, info, ErrInvalidCursor
+			}
+			offset = c.Offset
+		} else {
+			key, ok := spec.sorts[sort]
+			if !ok || c.ID == "" {
+				return nil, info, ErrInvalidCursor
+			}
+			// The cursor must hold the kind of value the sort column has.
+			var zero T
+			var value interface{}
+			switch key.value(zero).(type) {
+			case time.Time:
+				if c.Time != nil && c.Text == nil {
+					value = *c.Time
+				}
+			case string:
+				if c.Text != nil && c.Time == nil {
+					value = *c.Text
+				}
+			}
+			if value == nil {
+				return nil, info, ErrInvalidCursor
+			}
+			query = query.Where("("+key.column+", "+spec.id+") "+compare+" (?, ?)", value, c.ID)
+		}
+	}
+
+	if sort == models.SortRelevance {
+		query = query.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  spec.relevance.SQL + direction + ", " + spec.id + direction,
+			Vars: spec.relevance.Vars,
+		}})
+	} else {
+		query = query.Order(spec.sorts[sort].column + direction + ", " + spec.id + direction)
+	}
+
+	// One extra row tells whether another page follows.
+	items := []T{}
+	if err := query.Offset(offset).Limit(opts.Limit + 1).Find(&items).Error; err != nil {
+		return nil, info, err
+	}
+	if len(items) <= opts.Limit {
+		return items, info, nil
+	}
+	items = items[:opts.Limit]
+
+	next := cursor{Sort: sort, Order: order}
+	if sort == models.SortRelevance {
+		next.Offset = offset + opts.Limit
+	} else {
+		last := items[len(items)-1]
+		switch v := spec.sorts[sort].value(last).(type) {
+		case time.Time:
+			next.Time = &v
+		case string:
+			next.Text = &v
+		}
+		next.ID = spec.idOf(last)
+	}
+	info.NextCursor = encodeCursor(next)
+	return items, info, nil
+}
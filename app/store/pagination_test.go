@@ -0,0 +1,89 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"knowledge-capsule/app/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	when := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	title := "Interfaces"
+	tests := []struct {
+		name string
+		in   cursor
+	}{
+		{"time", cursor{Sort: models.SortCreatedAt, Order: models.OrderDesc, Time: &when, ID: "c1"}},
+		{"text", cursor{Sort: models.SortTitle, Order: models.OrderAsc, Text: &title, ID: "c2"}},
+		{"offset", cursor{Sort: models.SortRelevance, Order: models.OrderDesc, Offset: 40}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(encodeCursor(tt.in))
+			if err != nil {
+				t.Fatalf("decodeCursor() error = %v", err)
+			}
+			if encodeCursor(got) != encodeCursor(tt.in) {
+				t.Errorf("decodeCursor() = %+v, want %+v", got, tt.in)
+			}
+		})
+	}
+
+	for _, bad := range []string{"not base64!", "bm90IGpzb24", ""} {
+		if _, err := decodeCursor(bad); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeCursor(%q) error = %v, want ErrInvalidCursor", bad, err)
+		}
+	}
+}
+
+func TestPageQueryCursorValidation(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := listSpec[models.Capsule]{
+		id:          "capsules.id",
+		idOf:        func(c models.Capsule) string { return c.ID },
+		sorts:       capsuleSorts,
+		defaultSort: models.SortUpdatedAt,
+		relevance:   &clause.Expr{SQL: "1"},
+	}
+	when, title := time.Now(), "Interfaces"
+
+	tests := []struct {
+		name    string
+		sort    string
+		c       cursor
+		wantErr bool
+	}{
+		{"time cursor for time sort", models.SortUpdatedAt, cursor{Sort: models.SortUpdatedAt, Order: models.OrderDesc, Time: &when, ID: "c1"}, false},
+		{"text cursor for text sort", models.SortTitle, cursor{Sort: models.SortTitle, Order: models.OrderAsc, Text: &title, ID: "c1"}, false},
+		{"offset cursor for relevance", models.SortRelevance, cursor{Sort: models.SortRelevance, Order: models.OrderDesc, Offset: 20}, false},
+		{"text cursor for time sort", models.SortUpdatedAt, cursor{Sort: models.SortUpdatedAt, Order: models.OrderDesc, Text: &title, ID: "c1"}, true},
+		{"time cursor for text sort", models.SortTitle, cursor{Sort: models.SortTitle, Order: models.OrderAsc, Time: &when, ID: "c1"}, true},
+		{"both values", models.SortUpdatedAt, cursor{Sort: models.SortUpdatedAt, Order: models.OrderDesc, Time: &when, Text: &title, ID: "c1"}, true},
+		{"no value", models.SortUpdatedAt, cursor{Sort: models.SortUpdatedAt, Order: models.OrderDesc, ID: "c1"}, true},
+		{"no id", models.SortUpdatedAt, cursor{Sort: models.SortUpdatedAt, Order: models.OrderDesc, Time: &when}, true},
+		{"other sort", models.SortCreatedAt, cursor{Sort: models.SortUpdatedAt, Order: models.OrderDesc, Time: &when, ID: "c1"}, true},
+		{"other order", models.SortUpdatedAt, cursor{Sort: models.SortUpdatedAt, Order: models.OrderAsc, Time: &when, ID: "c1"}, true},
+		{"negative offset", models.SortRelevance, cursor{Sort: models.SortRelevance, Order: models.OrderDesc, Offset: -1}, true},
+		{"keyset value for relevance", models.SortRelevance, cursor{Sort: models.SortRelevance, Order: models.OrderDesc, Time: &when, ID: "c1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := models.ListOptions{Page: 1, Limit: 10, Sort: tt.sort, Cursor: encodeCursor(tt.c)}
+			_, _, err := pageQuery(db.Model(&models.Capsule{}), spec, opts)
+			if got := errors.Is(err, ErrInvalidCursor); got != tt.wantErr {
+				t.Errorf("pageQuery() error = %v, want ErrInvalidCursor: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"errors"
+
+	"knowledge-capsule/app/models"
+
+	"gorm.io/gorm"
+)
+
+// publicCapsuleColumns selects a models.PublicCapsule from capsules joined with their author.
+const publicCapsuleColumns = "capsules.id, capsules.title, capsules.content_format, capsules.topic, capsules.tags, " +
+	"capsules.view_count, capsules.created_at, capsules.updated_at, users.name AS author"
+
+// excerptLength is how many characters of content explore listings include.
+const excerptLength = 280
+
+// ListPublicCapsules returns non-private capsules with optional topic, tag and full-text
+// filters, newest, most viewed or (for a query without explicit sort) most relevant first,
+// without content.
+func (s *capsuleStore) ListPublicCapsules(filters *models.ExploreFilters, page, limit int) ([]models.PublicCapsule, int, error) {
+	query := publicCapsules(s.DB)
+	var order interface{} = "capsules.updated_at DESC"
+	if filters != nil {
+		if filters.Topic != "" {
+			query = query.Where("LOWER(capsules.topic) = LOWER(?)", filters.Topic)
+		}
+		if filters.Tag != "" {
+			slug := models.TagSlug(filters.Tag)
+			query = query.Where(capsuleHasTag, slug, slug)
+		}
+		if filters.Q != "" {
+			query = query.Where(capsuleTextMatch, filters.Q, filters.Q)
+		}
+		switch {
+		case filters.Sort == "popular":
+			order = "capsules.view_count DESC, capsules.updated_at DESC"
+		case filters.Sort == "" && filters.Q != "":
+			order = rankOrder(filters.Q)
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+	capsules := []models.PublicCapsule{}
+	err := query.Select(publicCapsuleColumns+", LEFT(capsules.content, ?) AS excerpt", excerptLength).
+		Order(order).Offset(offset).Limit(limit).Scan(&capsules).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return capsules, int(total), nil
+}
+
+// GetPublicCapsule returns a non-private capsule for anonymous readers and counts the view.
+func (s *capsuleStore) GetPublicCapsule(id string) (*models.PublicCapsule, error) {
+	capsule, err := findPublicCapsule(publicCapsules(s.DB).Where("capsules.id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.DB.Model(&models.Capsule{}).Where("id = ?", id).
+		UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error; err != nil {
+		return nil, err
+	}
+	capsule.ViewCount++
+	return capsule, nil
+}
+
+// capsulesWithAuthor queries capsules outside the trash joined with their author.
+func capsulesWithAuthor(db *gorm.DB) *gorm.DB {
+	return db.Table("capsules").Joins("JOIN users ON users.id = capsules.user_id").
+		Where("capsules.deleted_at IS NULL")
+}
+
+// publicCapsules narrows capsulesWithAuthor to non-private capsules.
+func publicCapsules(db *gorm.DB) *gorm.DB {
+	return capsulesWithAuthor(db).Where("capsules.is_private = ?", false)
+}
+
+// findPublicCapsule loads the first capsule matched by a capsulesWithAuthor query, with content.
+func findPublicCapsule(query *gorm.DB) (*models.PublicCapsule, error) {
+	var capsule models.PublicCapsule
+	result := query.Select(publicCapsuleColumns + ", capsules.content").Limit(1).Scan(&capsule)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("capsule not found")
+	}
+	return &capsule, nil
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+const refreshTokenBytes = 32
+
+// ErrRefreshTokenReused is returned when an already-rotated refresh token is presented.
+// The whole session family is revoked before it is returned.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected; session revoked")
+
+// sessionStore implements session storage with GORM.
+type sessionStore struct {
+	DB *gorm.DB
+}
+
+// NewSessionStore returns a SessionStore backed by GORM.
+func NewSessionStore(db *gorm.DB) SessionStore {
+	return &sessionStore{DB: db}
+}
+
+// CreateSession starts a new session family and returns it with its raw refresh token.
+// mfa records whether the login was completed with a second factor.
+func (s *sessionStore) CreateSession(userID, userAgent, ipAddress string, mfa bool, ttl time.Duration) (*models.Session, string, error) {
+	familyID := utils.GenerateUUID()
+	return s.issue(s.DB, familyID, userID, userAgent, ipAddress, mfa, time.Now(), ttl)
+}
+
+// RotateSession exchanges a refresh token for a new one in the same family.
+// Presenting a token that was already rotated revokes the whole family.
+func (s *sessionStore) RotateSession(refreshToken, userAgent, ipAddress string, ttl time.Duration) (*models.Session, string, error) {
+	var current models.Session
+	err := s.DB.Where("token_hash = ?", utils.HashToken(refreshToken)).First(&current).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", errors.New("invalid refresh token")
+		}
+		return nil, "", err
+	}
+	if current.RevokedAt != nil {
+		return nil, "", errors.New("session revoked")
+	}
+	if current.RotatedAt != nil {
+		if err := s.revokeFamily(current.FamilyID); err != nil {
+			return nil, "", err
+		}
+		return nil, "", ErrRefreshTokenReused
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return nil, "", errors.New("refresh token expired")
+	}
+
+	var session *models.Session
+	var token string
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.Session{}).
+			Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", current.ID).
+			Update("rotated_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Lost a race with another refresh using the same token.
+			return ErrRefreshTokenReused
+		}
+		var err error
+		session, token, err = s.issue(tx, current.FamilyID, current.UserID, userAgent, ipAddress, current.MFA, current.StartedAt, ttl)
+		return err
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if rerr := s.revokeFamily(current.FamilyID); rerr != nil {
+			return nil, "", rerr
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return session, token, nil
+}
+
+// ListActiveSessions returns the current (unrotated, unrevoked, unexpired) token of each session family.
+func (s *sessionStore) ListActiveSessions(userID string) ([]models.Session, error) {
+	var sessions []models.Session
+	err := s.DB.Where("user_id = ? AND rotated_at IS NULL AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// IsSessionActive reports whether a session family has not been revoked.
+func (s *sessionStore) IsSessionActive(familyID string) (bool, error) {
+	var count int64
+	err := s.DB.Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// RevokeSession revokes a session family owned by userID.
+func (s *sessionStore) RevokeSession(familyID, userID string) error {
+	result := s.DB.Model(&models.Session{}).
+		Where("family_id = ? AND user_id = ? AND revoked_at IS NULL", familyID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every session of a user except exceptFamilyID (pass "" to revoke all).
+func (s *sessionStore) RevokeUserSessions(userID, exceptFamilyID string) (int, error) {
+	query := s.DB.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+	if exceptFamilyID != "" {
+		query = query.Where("family_id <> ?", exceptFamilyID)
+	}
+	result := query.Update("revoked_at", time.Now())
+	return int(result.RowsAffected), result.Error
+}
+
+func (s *sessionStore) revokeFamily(familyID string) error {
+	return s.DB.Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (s *sessionStore) issue(db *gorm.DB, familyID, userID, userAgent, ipAddress string, mfa bool, startedAt time.Time, ttl time.Duration) (*models.Session, string, error) {
+	token, err := utils.GenerateSecureToken(refreshTokenBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	session := models.Session{
+		ID:        utils.GenerateUUID(),
+		FamilyID:  familyID,
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		MFA:       mfa,
+		StartedAt: startedAt,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, "", err
+	}
+	return &session, token, nil
+}
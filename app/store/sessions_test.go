@@ -0,0 +1,99 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"knowledge-capsule/app/models"
+)
+
+func TestRotateSession(t *testing.T) {
+	s := NewSessionStore(newTestDB(t, &models.Session{}))
+	first, token, err := s.CreateSession("u1", "agent", "192.0.2.1", true, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, next, err := s.RotateSession(token, "agent 2", "192.0.2.2", time.Hour)
+	if err != nil {
+		t.Fatalf("RotateSession() error = %v", err)
+	}
+	if next == token || second.ID == first.ID {
+		t.Error("RotateSession() reissued the same token")
+	}
+	if second.FamilyID != first.FamilyID || second.UserID != "u1" || !second.MFA {
+		t.Errorf("RotateSession() = %+v, want family %s of u1 with mfa", second, first.FamilyID)
+	}
+	if !second.StartedAt.Equal(first.StartedAt) {
+		t.Errorf("StartedAt = %v, want %v", second.StartedAt, first.StartedAt)
+	}
+
+	sessions, err := s.ListActiveSessions("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != second.ID {
+		t.Errorf("ListActiveSessions() = %+v, want only the rotated token", sessions)
+	}
+}
+
+func TestRotateSessionReuseRevokesFamily(t *testing.T) {
+	s := NewSessionStore(newTestDB(t, &models.Session{}))
+	first, stolen, err := s.CreateSession("u1", "agent", "192.0.2.1", false, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, current, err := s.RotateSession(stolen, "agent", "192.0.2.1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := s.CreateSession("u1", "agent", "192.0.2.1", false, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := s.RotateSession(stolen, "attacker", "198.51.100.1", time.Hour); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("RotateSession(rotated token) error = %v, want ErrRefreshTokenReused", err)
+	}
+	if active, err := s.IsSessionActive(first.FamilyID); err != nil || active {
+		t.Errorf("IsSessionActive(reused family) = %v, %v; want false", active, err)
+	}
+	if _, _, err := s.RotateSession(current, "agent", "192.0.2.1", time.Hour); err == nil {
+		t.Error("RotateSession(latest token of a revoked family) succeeded")
+	}
+	if active, err := s.IsSessionActive(other.FamilyID); err != nil || !active {
+		t.Errorf("IsSessionActive(other family) = %v, %v; want true", active, err)
+	}
+}
+
+func TestRotateSessionRejects(t *testing.T) {
+	db := newTestDB(t, &models.Session{})
+	s := NewSessionStore(db)
+
+	if _, _, err := s.RotateSession("unknown", "agent", "192.0.2.1", time.Hour); err == nil {
+		t.Error("RotateSession(unknown token) succeeded")
+	}
+
+	_, expired, err := s.CreateSession("u1", "agent", "192.0.2.1", false, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.RotateSession(expired, "agent", "192.0.2.1", time.Hour); err == nil {
+		t.Error("RotateSession(expired token) succeeded")
+	}
+
+	revoked, token, err := s.CreateSession("u1", "agent", "192.0.2.1", false, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RevokeSession(revoked.FamilyID, "u2"); err == nil {
+		t.Error("RevokeSession() revoked another user's session")
+	}
+	if err := s.RevokeSession(revoked.FamilyID, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.RotateSession(token, "agent", "192.0.2.1", time.Hour); err == nil {
+		t.Error("RotateSession(revoked token) succeeded")
+	}
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"errors"
+	"strconv"
+
+	"knowledge-capsule/app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// settingsStore implements global setting storage with GORM.
+type settingsStore struct {
+	DB *gorm.DB
+}
+
+// NewSettingsStore returns a SettingsStore backed by GORM.
+func NewSettingsStore(db *gorm.DB) SettingsStore {
+	return &settingsStore{DB: db}
+}
+
+// Get returns the value for key, or "" when it has never been set.
+func (s *settingsStore) Get(key string) (string, error) {
+	var setting models.Setting
+	err := s.DB.First(&setting, "key = ?", key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return setting.Value, nil
+}
+
+// GetBool returns a boolean setting, false when unset.
+func (s *settingsStore) GetBool(key string) (bool, error) {
+	val, err := s.Get(key)
+	if err != nil || val == "" {
+		return false, err
+	}
+	return strconv.ParseBool(val)
+}
+
+// Set creates or updates a setting.
+func (s *settingsStore) Set(key, value string) error {
+	return s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&models.Setting{Key: key, Value: value}).Error
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDriver is SQLite with stand-ins for the Postgres functions stores call only for their
+// side effects.
+const testDriver = "sqlite3_store_test"
+
+func init() {
+	sql.Register(testDriver, &sqlite3.SQLiteDriver{ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+		// One connection serves each test database, so there is nothing to lock against.
+		return conn.RegisterFunc("pg_advisory_xact_lock", func(int64) int64 { return 0 }, true)
+	}})
+}
+
+// newTestDB opens an in-memory SQLite database with tables for the given models. It only
+// suits stores whose queries are portable SQL; Postgres-only features need a real server.
+func newTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: testDriver, DSN: ":memory:"}),
+		&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to ":memory:" opens its own empty database.
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// sqlCapture records the statements a dry-run database would have sent.
+type sqlCapture struct {
+	SQL  []string
+	Vars [][]interface{}
+}
+
+// last returns the most recent statement and its bound values.
+func (c *sqlCapture) last(t *testing.T) (string, []interface{}) {
+	t.Helper()
+	if len(c.SQL) == 0 {
+		t.Fatal("no statement was built")
+	}
+	return c.SQL[len(c.SQL)-1], c.Vars[len(c.Vars)-1]
+}
+
+// newDryRunDB returns a Postgres-dialect database that builds statements without a server,
+// for checking Postgres-only queries, and the capture the statements go to.
+func newDryRunDB(t *testing.T) (*gorm.DB, *sqlCapture) {
+	t.Helper()
+	db, err := gorm.Open(postgres.Open("host=localhost dbname=test"), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	capture := &sqlCapture{}
+	record := func(tx *gorm.DB) {
+		capture.SQL = append(capture.SQL, tx.Statement.SQL.String())
+		capture.Vars = append(capture.Vars, tx.Statement.Vars)
+		// A real run resets the statement so a reused chain builds afresh; DryRun does not.
+		tx.Statement.SQL.Reset()
+		tx.Statement.Vars = nil
+	}
+	db.Callback().Query().After("gorm:query").Register("test:capture", record)
+	db.Callback().Row().After("gorm:row").Register("test:capture", record)
+	db.Callback().Update().After("gorm:update").Register("test:capture", record)
+	db.Callback().Raw().After("gorm:raw").Register("test:capture", record)
+	return db, capture
+}
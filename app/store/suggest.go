@@ -0,0 +1,51 @@
+package store
+
+import "knowledge-capsule/app/models"
+
+// suggestMatch matches autocomplete candidates in column that contain the typed text or
+// are similar enough to it to survive a typo. Takes suggestMatchArgs.
+func suggestMatch(column string) string {
+	return "(" + column + " ILIKE ? OR ? <% " + column + ")"
+}
+
+func suggestMatchArgs(query string) []interface{} {
+	return []interface{}{"%" + query + "%", query}
+}
+
+// suggestScore ranks a candidate by trigram similarity to the typed text, plus one when
+// the candidate starts with it. Takes suggestScoreArgs.
+func suggestScore(column string) string {
+	return "word_similarity(?, " + column + ") + CASE WHEN " + column + " ILIKE ? THEN 1 ELSE 0 END"
+}
+
+func suggestScoreArgs(query string) []interface{} {
+	return []interface{}{query, query + "%"}
+}
+
+// SuggestTitles returns titles of the user's capsules matching a partly typed query, best first.
+func (s *capsuleStore) SuggestTitles(userID, query string, limit int) ([]models.Suggestion, error) {
+	suggestions := []models.Suggestion{}
+	err := s.DB.Table("capsules").
+		Select("capsules.id AS capsule_id, capsules.title AS text, "+suggestScore("capsules.title")+" AS score", suggestScoreArgs(query)...).
+		Where("capsules.user_id = ? AND capsules.deleted_at IS NULL", userID).
+		Where(suggestMatch("capsules.title"), suggestMatchArgs(query)...).
+		Order("score DESC").Limit(limit).Scan(&suggestions).Error
+	for i := range suggestions {
+		suggestions[i].Type = models.SuggestionTitle
+	}
+	return suggestions, err
+}
+
+// SuggestTags returns distinct tags on the user's capsules matching a partly typed query, best first.
+func (s *capsuleStore) SuggestTags(userID, query string, limit int) ([]models.Suggestion, error) {
+	suggestions := []models.Suggestion{}
+	err := s.DB.Table("capsules, jsonb_array_elements_text(capsules.tags) AS t(tag)").
+		Select("t.tag AS text, MAX("+suggestScore("t.tag")+") AS score", suggestScoreArgs(query)...).
+		Where("capsules.user_id = ? AND capsules.deleted_at IS NULL", userID).
+		Where(suggestMatch("t.tag"), suggestMatchArgs(query)...).
+		Group("t.tag").Order("score DESC").Limit(limit).Scan(&suggestions).Error
+	for i := range suggestions {
+		suggestions[i].Type = models.SuggestionTag
+	}
+	return suggestions, err
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestSuggestQueries(t *testing.T) {
+	db, capture := newDryRunDB(t)
+	capsules, topics := &capsuleStore{DB: db}, &topicStore{DB: db}
+
+	tests := []struct {
+		name    string
+		suggest func() error
+		want    []string
+	}{
+		{"titles", func() error { _, err := capsules.SuggestTitles("u1", "gola", 5); return err },
+			[]string{"capsules.title ILIKE $", "<% capsules.title", "word_similarity($1, capsules.title)", "capsules.user_id = $", "capsules.deleted_at IS NULL", "ORDER BY score DESC"}},
+		{"tags", func() error { _, err := capsules.SuggestTags("u1", "gola", 5); return err },
+			[]string{"jsonb_array_elements_text(capsules.tags)", "t.tag ILIKE $", "<% t.tag", "GROUP BY", "capsules.user_id = $"}},
+		{"topics", func() error { _, err := topics.SuggestTopics("gola", 5); return err },
+			[]string{"name ILIKE $", "<% name", "status = $", "deleted_at IS NULL"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Scan needs rows, so a dry run builds the statement and then fails.
+			if err := tt.suggest(); err != nil && !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+				t.Fatal(err)
+			}
+			sql, vars := capture.last(t)
+			for _, want := range tt.want {
+				if !strings.Contains(sql, want) {
+					t.Errorf("SQL lacks %q:\n%s", want, sql)
+				}
+			}
+			// Prefix matches score a bonus; substring matches and typos both qualify.
+			for _, want := range []interface{}{"gola", "gola%", "%gola%"} {
+				found := false
+				for _, v := range vars {
+					found = found || v == want
+				}
+				if !found {
+					t.Errorf("vars %v lack %q", vars, want)
+				}
+			}
+		})
+	}
+}
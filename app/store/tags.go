@@ -0,0 +1,363 @@
+package store
+
+import (
+	"errors"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrTagNotFound is returned when a tag slug is not in the registry.
+	ErrTagNotFound = errors.New("tag not found")
+	// ErrTagExists is returned when a new tag or alias slug is already used by a tag or alias.
+	ErrTagExists = errors.New("tag already exists; merge the tags instead")
+)
+
+// capsuleHasTag matches capsules carrying a tag slug, or the tag an alias slug stands for.
+// It takes the slug twice.
+const capsuleHasTag = "capsules.tags @> jsonb_build_array(COALESCE((SELECT tags.slug FROM tag_aliases " +
+	"JOIN tags ON tags.id = tag_aliases.tag_id WHERE tag_aliases.slug = ?), ?::text))"
+
+// tagUsage counts the capsules outside the trash that carry a tag.
+const tagUsage = "SELECT COUNT(*) FROM capsules WHERE capsules.deleted_at IS NULL AND capsules.tags @> jsonb_build_array(tags.slug)"
+
+// retagTags rewrites a capsule's tags, replacing the slugs in a list with a target slug and
+// keeping the first occurrence of each tag in order. It takes the list and the target.
+const retagTags = `(SELECT COALESCE(jsonb_agg(tag ORDER BY pos), '[]'::jsonb) FROM (
+	SELECT tag, MIN(pos) AS pos FROM (
+		SELECT CASE WHEN e.tag IN ? THEN ?::text ELSE e.tag END AS tag, e.pos
+		FROM jsonb_array_elements_text(capsules.tags) WITH ORDINALITY AS e(tag, pos)) mapped
+	GROUP BY tag) deduped)`
+
+// tagStore implements the tag registry with GORM.
+type tagStore struct {
+	DB *gorm.DB
+}
+
+// NewTagStore returns a TagStore backed by GORM.
+func NewTagStore(db *gorm.DB) TagStore {
+	return &tagStore{DB: db}
+}
+
+// ListTags returns one page of tags with their usage counts and aliases, most used first
+// or, with sort "name", by slug. q matches part of the slug.
+func (s *tagStore) ListTags(q, sort string, page, limit int) ([]models.Tag, int, error) {
+	tags := []models.Tag{}
+	query := s.DB.Model(&models.Tag{})
+	if q != "" {
+		query = query.Where("tags.slug ILIKE ?", "%"+models.TagSlug(q)+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "usage_count DESC, tags.slug"
+	if sort == models.SortName {
+		order = "tags.slug"
+	}
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+	if err := query.Select("tags.*, (" + tagUsage + ") AS usage_count").
+		Order(order).Offset(offset).Limit(limit).Find(&tags).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(tags) == 0 {
+		return tags, int(total), nil
+	}
+
+	ids := make([]string, len(tags))
+	for i, t := range tags {
+		ids[i] = t.ID
+	}
+	var aliases []models.TagAlias
+	if err := s.DB.Where("tag_id IN ?", ids).Order("slug").Find(&aliases).Error; err != nil {
+		return nil, 0, err
+	}
+	byTag := make(map[string][]string, len(tags))
+	for _, a := range aliases {
+		byTag[a.TagID] = append(byTag[a.TagID], a.Slug)
+	}
+	for i := range tags {
+		tags[i].Aliases = byTag[tags[i].ID]
+	}
+	return tags, int(total), nil
+}
+
+// RenameTags renames tags in order, in one transaction, and rewrites every capsule that
+// uses them, trashed ones included. Each old slug becomes an alias of its tag, so it keeps
+// working on new capsules. It returns the number of capsules changed.
+func (s *tagStore) RenameTags(renames []models.TagRename) (int, error) {
+	touched := make(map[string]bool)
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		for _, rename := range renames {
+			from, to := models.TagSlug(rename.From), models.TagSlug(rename.To)
+			if from == to {
+				continue
+			}
+			var tag models.Tag
+			if err := lockTag(tx, from, &tag); err != nil {
+				return err
+			}
+			taken, err := tagSlugTaken(tx, to, tag.ID)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return ErrTagExists
+			}
+			if err := tx.Where("slug = ?", to).Delete(&models.TagAlias{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&tag).Update("slug", to).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&models.TagAlias{Slug: from, TagID: tag.ID}).Error; err != nil {
+				return err
+			}
+			if err := retagCapsules(tx, []string{from}, to, touched); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(touched), nil
+}
+
+// MergeTags folds source tags into a target tag, which is created if needed, and rewrites
+// every capsule that uses them, trashed ones included. Source slugs and their aliases
+// become aliases of the target. It returns the number of capsules changed.
+func (s *tagStore) MergeTags(sources []string, target string) (int, error) {
+	touched := make(map[string]bool)
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		targetTag, err := canonicalTag(tx, models.TagSlug(target))
+		if err != nil {
+			return err
+		}
+		var slugs, ids []string
+		for _, slug := range models.NormalizeTags(sources) {
+			var tag models.Tag
+			if err := lockTag(tx, slug, &tag); err != nil {
+				return err
+			}
+			if tag.ID != targetTag.ID {
+				slugs = append(slugs, tag.Slug)
+				ids = append(ids, tag.ID)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := retagCapsules(tx, slugs, targetTag.Slug, touched); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.TagAlias{}).Where("tag_id IN ?", ids).Update("tag_id", targetTag.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&models.Tag{}).Error; err != nil {
+			return err
+		}
+		aliases := make([]models.TagAlias, len(slugs))
+		for i, slug := range slugs {
+			aliases[i] = models.TagAlias{Slug: slug, TagID: targetTag.ID}
+		}
+		return tx.Create(&aliases).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(touched), nil
+}
+
+// ListAliases returns every alias with the tag it stands for, by alias.
+func (s *tagStore) ListAliases() ([]models.TagAliasView, error) {
+	aliases := []models.TagAliasView{}
+	err := s.DB.Table("tag_aliases").
+		Select("tag_aliases.slug AS alias, tags.slug AS tag, tag_aliases.created_at").
+		Joins("JOIN tags ON tags.id = tag_aliases.tag_id").
+		Order("tag_aliases.slug").Scan(&aliases).Error
+	return aliases, err
+}
+
+// SetAlias makes alias stand for a tag, or points an existing alias at it. An alias cannot
+// be a tag in use; merge the tags instead.
+func (s *tagStore) SetAlias(alias, tag string) (*models.TagAliasView, error) {
+	var view models.TagAliasView
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var target models.Tag
+		if err := lockTag(tx, tag, &target); err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.Tag{}).Where("slug = ?", alias).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrTagExists
+		}
+		row := models.TagAlias{Slug: alias, TagID: target.ID}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "slug"}},
+			DoUpdates: clause.AssignmentColumns([]string{"tag_id"}),
+		}).Create(&row).Error; err != nil {
+			return err
+		}
+		view = models.TagAliasView{Alias: alias, Tag: target.Slug, CreatedAt: row.CreatedAt}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// DeleteAlias removes an alias. Capsules keep the tag it was saved as.
+func (s *tagStore) DeleteAlias(alias string) error {
+	result := s.DB.Where("slug = ?", alias).Delete(&models.TagAlias{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("alias not found")
+	}
+	return nil
+}
+
+// resolveTags replaces a capsule input's tags with their slugs, maps aliases to their tags,
+// and registers tags that are new.
+func resolveTags(tx *gorm.DB, input *models.CapsuleInput) error {
+	slugs, err := canonicalSlugs(tx, input.Tags)
+	if err != nil {
+		return err
+	}
+	if len(slugs) == 0 {
+		input.Tags = slugs
+		return nil
+	}
+
+	tags := make([]models.Tag, len(slugs))
+	for i, slug := range slugs {
+		tags[i] = models.Tag{ID: utils.GenerateUUID(), Slug: slug}
+	}
+	if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "slug"}}, DoNothing: true}).
+		Create(&tags).Error; err != nil {
+		return err
+	}
+	input.Tags = slugs
+	return nil
+}
+
+// canonicalSlugs returns the slugs of tags with aliases mapped to their tags, without
+// duplicates.
+func canonicalSlugs(tx *gorm.DB, tags models.Tags) (models.Tags, error) {
+	slugs := models.NormalizeTags(tags)
+	if len(slugs) == 0 {
+		return slugs, nil
+	}
+
+	var aliases []struct {
+		Alias string
+		Tag   string
+	}
+	if err := tx.Table("tag_aliases").Select("tag_aliases.slug AS alias, tags.slug AS tag").
+		Joins("JOIN tags ON tags.id = tag_aliases.tag_id").
+		Where("tag_aliases.slug IN ?", []string(slugs)).Scan(&aliases).Error; err != nil {
+		return nil, err
+	}
+	if len(aliases) == 0 {
+		return slugs, nil
+	}
+	canonical := make(map[string]string, len(aliases))
+	for _, a := range aliases {
+		canonical[a.Alias] = a.Tag
+	}
+	for i, slug := range slugs {
+		if tag, ok := canonical[slug]; ok {
+			slugs[i] = tag
+		}
+	}
+	return models.NormalizeTags(slugs), nil
+}
+
+// retagCapsules replaces the from slugs with to on every capsule that carries one of them,
+// trashed ones included, and adds the changed capsules to touched. Capsules get no new
+// revision and keep their updated_at.
+func retagCapsules(tx *gorm.DB, from []string, to string, touched map[string]bool) error {
+	var ids []string
+	if err := tx.Unscoped().Model(&models.Capsule{}).
+		Where("EXISTS (SELECT 1 FROM jsonb_array_elements_text(capsules.tags) AS t(tag) WHERE t.tag IN ?)", from).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Unscoped().Model(&models.Capsule{}).Where("id IN ?", ids).
+		UpdateColumn("tags", gorm.Expr(retagTags, from, to)).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		touched[id] = true
+	}
+	return nil
+}
+
+// lockTag loads the tag with a slug into tag and locks it for the transaction.
+func lockTag(tx *gorm.DB, slug string, tag *models.Tag) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(tag, "slug = ?", slug).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrTagNotFound
+	}
+	return err
+}
+
+// canonicalTag returns the tag a slug names, following an alias, and creates the tag when
+// the slug is unknown.
+func canonicalTag(tx *gorm.DB, slug string) (*models.Tag, error) {
+	var alias models.TagAlias
+	err := tx.First(&alias, "slug = ?", slug).Error
+	if err == nil {
+		var tag models.Tag
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tag, "id = ?", alias.TagID).Error
+		return &tag, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var tag models.Tag
+	err = lockTag(tx, slug, &tag)
+	if errors.Is(err, ErrTagNotFound) {
+		tag = models.Tag{ID: utils.GenerateUUID(), Slug: slug}
+		err = tx.Create(&tag).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// tagSlugTaken reports whether a slug is used by another tag, or by an alias of a tag other
+// than tagID.
+func tagSlugTaken(tx *gorm.DB, slug, tagID string) (bool, error) {
+	var count int64
+	if err := tx.Model(&models.Tag{}).Where("slug = ? AND id <> ?", slug, tagID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	err := tx.Model(&models.TagAlias{}).Where("slug = ? AND tag_id <> ?", slug, tagID).Count(&count).Error
+	return count > 0, err
+}
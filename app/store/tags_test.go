@@ -0,0 +1,120 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"knowledge-capsule/app/models"
+)
+
+// newTagStore returns a registry with the tags go and rust, and golang as an alias of go.
+func newTagStore(t *testing.T) *tagStore {
+	t.Helper()
+	db := newTestDB(t, &models.Tag{}, &models.TagAlias{})
+	for _, tag := range []models.Tag{{ID: "go", Slug: "go"}, {ID: "rust", Slug: "rust"}} {
+		if err := db.Create(&tag).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&models.TagAlias{Slug: "golang", TagID: "go"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &tagStore{DB: db}
+}
+
+func TestResolveTagsFollowsAliases(t *testing.T) {
+	s := newTagStore(t)
+	input := models.CapsuleInput{Tags: models.Tags{"GoLang", "go", "Rust", "New Tag", "golang"}}
+	if err := resolveTags(s.DB, &input); err != nil {
+		t.Fatal(err)
+	}
+	if want := (models.Tags{"go", "rust", "new-tag"}); !slices.Equal(input.Tags, want) {
+		t.Errorf("resolved tags = %v, want %v", input.Tags, want)
+	}
+	var tag models.Tag
+	if err := s.DB.First(&tag, "slug = ?", "new-tag").Error; err != nil {
+		t.Errorf("resolveTags() did not register the new tag: %v", err)
+	}
+	var count int64
+	s.DB.Model(&models.Tag{}).Where("slug = ?", "golang").Count(&count)
+	if count != 0 {
+		t.Error("resolveTags() registered an alias as a tag")
+	}
+}
+
+func TestSetAlias(t *testing.T) {
+	s := newTagStore(t)
+	view, err := s.SetAlias("rs", "rust")
+	if err != nil || view.Alias != "rs" || view.Tag != "rust" {
+		t.Fatalf("SetAlias(rs, rust) = %+v, %v", view, err)
+	}
+	// An existing alias is repointed.
+	if _, err := s.SetAlias("golang", "rust"); err != nil {
+		t.Fatalf("SetAlias(golang, rust) error = %v", err)
+	}
+	slugs, err := canonicalSlugs(s.DB, models.Tags{"golang", "rs"})
+	if err != nil || !slices.Equal(slugs, models.Tags{"rust"}) {
+		t.Errorf("canonicalSlugs() = %v, %v; want [rust]", slugs, err)
+	}
+
+	if _, err := s.SetAlias("go", "rust"); !errors.Is(err, ErrTagExists) {
+		t.Errorf("SetAlias(existing tag) error = %v, want ErrTagExists", err)
+	}
+	if _, err := s.SetAlias("zig-lang", "zig"); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("SetAlias(unknown tag) error = %v, want ErrTagNotFound", err)
+	}
+	if err := s.DeleteAlias("rs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteAlias("rs"); err == nil {
+		t.Error("DeleteAlias() removed a missing alias")
+	}
+}
+
+func TestCanonicalTag(t *testing.T) {
+	s := newTagStore(t)
+	for slug, want := range map[string]string{"go": "go", "golang": "go", "zig": "zig"} {
+		tag, err := canonicalTag(s.DB, slug)
+		if err != nil || tag.Slug != want {
+			t.Errorf("canonicalTag(%s) = %+v, %v; want %s", slug, tag, err, want)
+		}
+	}
+	var count int64
+	s.DB.Model(&models.Tag{}).Where("slug = ?", "zig").Count(&count)
+	if count != 1 {
+		t.Errorf("canonicalTag() created zig %d times, want once", count)
+	}
+}
+
+// Merges and renames that change capsules rewrite their jsonb tags, which needs Postgres;
+// these cases are decided before any capsule is touched.
+func TestMergeAndRenameTagChecks(t *testing.T) {
+	s := newTagStore(t)
+	if _, err := s.MergeTags([]string{"missing"}, "go"); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("MergeTags(unknown source) error = %v, want ErrTagNotFound", err)
+	}
+	// A source that is the target, spelled as the tag or through an alias, is skipped.
+	if n, err := s.MergeTags([]string{"Go"}, "golang"); err != nil || n != 0 {
+		t.Errorf("MergeTags(go into golang) = %d, %v; want a no-op", n, err)
+	}
+	if _, err := s.RenameTags([]models.TagRename{{From: "go", To: "rust"}}); !errors.Is(err, ErrTagExists) {
+		t.Errorf("RenameTags(onto a tag) error = %v, want ErrTagExists", err)
+	}
+	if _, err := s.RenameTags([]models.TagRename{{From: "rust", To: "golang"}}); !errors.Is(err, ErrTagExists) {
+		t.Errorf("RenameTags(onto another tag's alias) error = %v, want ErrTagExists", err)
+	}
+	if _, err := s.RenameTags([]models.TagRename{{From: "missing", To: "new"}}); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("RenameTags(unknown tag) error = %v, want ErrTagNotFound", err)
+	}
+	if n, err := s.RenameTags([]models.TagRename{{From: "Go", To: "go"}}); err != nil || n != 0 {
+		t.Errorf("RenameTags(same slug) = %d, %v; want a no-op", n, err)
+	}
+
+	// Rejected renames leave the registry as it was.
+	var tags []string
+	s.DB.Model(&models.Tag{}).Order("slug").Pluck("slug", &tags)
+	if !slices.Equal(tags, []string{"go", "rust"}) {
+		t.Errorf("tags after failed renames = %v, want [go rust]", tags)
+	}
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"time"
+
+	"knowledge-capsule/app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// throttleStore implements failed-attempt tracking with GORM.
+type throttleStore struct {
+	DB *gorm.DB
+}
+
+// NewThrottleStore returns a ThrottleStore backed by GORM.
+func NewThrottleStore(db *gorm.DB) ThrottleStore {
+	return &throttleStore{DB: db}
+}
+
+// LockedUntil returns the latest active lockout among keys, or the zero time if none is locked.
+func (s *throttleStore) LockedUntil(keys ...string) (time.Time, error) {
+	var throttles []models.AuthThrottle
+	err := s.DB.Where("key IN ? AND locked_until > ?", keys, time.Now()).Find(&throttles).Error
+	if err != nil {
+		return time.Time{}, err
+	}
+	var until time.Time
+	for _, t := range throttles {
+		if t.LockedUntil.After(until) {
+			until = *t.LockedUntil
+		}
+	}
+	return until, nil
+}
+
+// RecordFailure counts a failure for scope/subject and applies the policy's lockout.
+// The row is locked for the update so concurrent failures are all counted.
+func (s *throttleStore) RecordFailure(scope, subject string, policy models.ThrottlePolicy) (*models.AuthThrottle, error) {
+	key := models.ThrottleKey(scope, subject)
+	now := time.Now()
+	var throttle models.AuthThrottle
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.AuthThrottle{
+			Key:           key,
+			Scope:         scope,
+			Subject:       subject,
+			LastFailureAt: now,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&throttle, "key = ?", key).Error; err != nil {
+			return err
+		}
+
+		last := throttle.LastFailureAt
+		if throttle.LockedUntil != nil && throttle.LockedUntil.After(last) {
+			last = *throttle.LockedUntil
+		}
+		if now.Sub(last) > policy.Window {
+			throttle.Failures = 0
+			throttle.LockedUntil = nil
+		}
+		throttle.Failures++
+		throttle.LastFailureAt = now
+		if d := policy.LockoutFor(throttle.Failures); d > 0 {
+			until := now.Add(d)
+			throttle.LockedUntil = &until
+		}
+		return tx.Save(&throttle).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// Reset clears failures and any lockout for key. Reports whether anything was cleared.
+func (s *throttleStore) Reset(key string) (bool, error) {
+	result := s.DB.Where("key = ?", key).Delete(&models.AuthThrottle{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ListThrottles returns tracked IPs and accounts, most recent failure first.
+func (s *throttleStore) ListThrottles(lockedOnly bool, page, limit int) ([]models.AuthThrottle, int, error) {
+	var throttles []models.AuthThrottle
+	query := s.DB.Model(&models.AuthThrottle{})
+	if lockedOnly {
+		query = query.Where("locked_until > ?", time.Now())
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+	if err := query.Order("last_failure_at DESC").Offset(offset).Limit(limit).Find(&throttles).Error; err != nil {
+		return nil, 0, err
+	}
+	return throttles, int(total), nil
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"errors"
+
+	"knowledge-capsule/app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IsMaintainer reports whether a user maintains a topic.
+func (s *topicStore) IsMaintainer(topicID, userID string) (bool, error) {
+	var count int64
+	err := s.DB.Model(&models.TopicMaintainer{}).Where("topic_id = ? AND user_id = ?", topicID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// ListMaintainers returns a topic's maintainers by name.
+func (s *topicStore) ListMaintainers(topicID string) ([]models.TopicMaintainerView, error) {
+	maintainers := []models.TopicMaintainerView{}
+	err := s.DB.Table("topic_maintainers").
+		Select("topic_maintainers.user_id, users.name, users.email, topic_maintainers.added_by, topic_maintainers.created_at").
+		Joins("JOIN users ON users.id = topic_maintainers.user_id").
+		Where("topic_maintainers.topic_id = ?", topicID).
+		Order("users.name").Scan(&maintainers).Error
+	return maintainers, err
+}
+
+// AddMaintainer makes a user a maintainer of a topic. Adding an existing maintainer is a no-op.
+func (s *topicStore) AddMaintainer(topicID, userID, addedBy string) error {
+	if _, err := s.FindByID(topicID); err != nil {
+		return ErrTopicNotFound
+	}
+	return s.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.TopicMaintainer{TopicID: topicID, UserID: userID, AddedBy: addedBy}).Error
+}
+
+// RemoveMaintainer removes a user from a topic's maintainers.
+func (s *topicStore) RemoveMaintainer(topicID, userID string) error {
+	result := s.DB.Where("topic_id = ? AND user_id = ?", topicID, userID).Delete(&models.TopicMaintainer{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("maintainer not found")
+	}
+	return nil
+}
+
+// ListPendingTopics returns topic proposals awaiting review, oldest first.
+func (s *topicStore) ListPendingTopics(page, limit int) ([]models.Topic, int, error) {
+	var topics []models.Topic
+	query := s.DB.Model(&models.Topic{}).Where("status = ?", models.TopicStatusPending)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+	if err := query.Order("created_at").Offset(offset).Limit(limit).Find(&topics).Error; err != nil {
+		return nil, 0, err
+	}
+	return topics, int(total), nil
+}
+
+// ApproveTopic approves a pending topic. Its proposer becomes its first maintainer.
+func (s *topicStore) ApproveTopic(id, reviewerID string) (*models.Topic, error) {
+	var topic models.Topic
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockPendingTopic(tx, id, &topic); err != nil {
+			return err
+		}
+		if err := tx.Model(&topic).Update("status", models.TopicStatusApproved).Error; err != nil {
+			return err
+		}
+		if topic.CreatedBy == "" {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.TopicMaintainer{TopicID: id, UserID: topic.CreatedBy, AddedBy: reviewerID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &topic, nil
+}
+
+// RejectTopic deletes a pending topic. Capsules filed under it, including trashed ones,
+// are left without a topic.
+func (s *topicStore) RejectTopic(id string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var topic models.Topic
+		if err := lockPendingTopic(tx, id, &topic); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Capsule{}).Where("topic_id = ?", id).
+			UpdateColumns(map[string]interface{}{"topic_id": nil, "topic": ""}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("topic_id = ?", id).Delete(&models.TopicMaintainer{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&topic).Error
+	})
+}
+
+// lockPendingTopic loads a pending topic into topic and locks it for the transaction.
+func lockPendingTopic(tx *gorm.DB, id string, topic *models.Topic) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(topic, "id = ? AND status = ?", id, models.TopicStatusPending).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrTopicNotFound
+	}
+	return err
+}
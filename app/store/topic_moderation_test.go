@@ -0,0 +1,75 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"knowledge-capsule/app/models"
+)
+
+func TestTopicApprovalQueue(t *testing.T) {
+	db := newTestDB(t, &models.Topic{}, &models.TopicMaintainer{}, &models.Capsule{})
+	s := &topicStore{DB: db}
+
+	approved, err := s.AddTopic("admin", models.TopicInput{Name: "Go"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.IsMaintainer(approved.ID, "admin"); !ok || approved.Status != models.TopicStatusApproved {
+		t.Errorf("approved topic = %+v, maintained by its creator = %v", approved, ok)
+	}
+	proposed, err := s.AddTopic("u1", models.TopicInput{Name: "Zig"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.IsMaintainer(proposed.ID, "u1"); ok || proposed.Status != models.TopicStatusPending {
+		t.Errorf("proposal = %+v, maintained by its proposer = %v; want pending with no maintainer", proposed, ok)
+	}
+	rejected, err := s.AddTopic("u2", models.TopicInput{Name: "Spam"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddTopic("u3", models.TopicInput{Name: "Zig"}, false); !errors.Is(err, ErrTopicExists) {
+		t.Errorf("AddTopic(name of a pending proposal) error = %v, want ErrTopicExists", err)
+	}
+
+	pending, total, err := s.ListPendingTopics(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(pending) != 2 || pending[0].ID != proposed.ID {
+		t.Errorf("ListPendingTopics() = %+v (total %d), want the two proposals oldest first", pending, total)
+	}
+
+	if _, err := s.ApproveTopic(proposed.ID, "admin"); err != nil {
+		t.Fatalf("ApproveTopic() error = %v", err)
+	}
+	if ok, _ := s.IsMaintainer(proposed.ID, "u1"); !ok {
+		t.Error("ApproveTopic() did not make the proposer a maintainer")
+	}
+	if _, err := s.ApproveTopic(proposed.ID, "admin"); !errors.Is(err, ErrTopicNotFound) {
+		t.Errorf("ApproveTopic(approved) error = %v, want ErrTopicNotFound", err)
+	}
+	if err := s.RejectTopic(approved.ID); !errors.Is(err, ErrTopicNotFound) {
+		t.Errorf("RejectTopic(approved) error = %v, want ErrTopicNotFound", err)
+	}
+
+	capsule := models.Capsule{ID: "c1", UserID: "u2", CapsuleInput: models.CapsuleInput{Title: "A", Topic: "Spam", TopicID: &rejected.ID}}
+	if err := db.Create(&capsule).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RejectTopic(rejected.ID); err != nil {
+		t.Fatalf("RejectTopic() error = %v", err)
+	}
+	var row struct {
+		Topic   string
+		TopicID *string
+	}
+	db.Model(&models.Capsule{}).Select("topic, topic_id").Where("id = ?", "c1").Scan(&row)
+	if row.Topic != "" || row.TopicID != nil {
+		t.Errorf("capsule after rejection = %+v, want no topic", row)
+	}
+	if _, err := s.FindByID(rejected.ID); err == nil {
+		t.Error("RejectTopic() left the topic behind")
+	}
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"errors"
+
+	"knowledge-capsule/app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// topicTreeLock is the advisory lock key that serializes topic moves, so two concurrent
+// moves cannot each pass the cycle check and together form a cycle.
+const topicTreeLock = 0x746f706963
+
+// topicAncestors is a CTE named ancestors holding the topic with the given ID and every
+// topic above it.
+const topicAncestors = `WITH RECURSIVE ancestors AS (
+	SELECT id, parent_id FROM topics WHERE id = ?
+	UNION SELECT t.id, t.parent_id FROM topics t JOIN ancestors a ON t.id = a.parent_id)`
+
+// topicPaths selects the breadcrumb of each given topic, root first.
+const topicPaths = `WITH RECURSIVE path AS (
+	SELECT id AS topic_id, id, name, parent_id, 0 AS depth FROM topics WHERE id IN ?
+	UNION ALL SELECT p.topic_id, t.id, t.name, t.parent_id, p.depth + 1 FROM topics t JOIN path p ON t.id = p.parent_id)
+SELECT topic_id, id, name FROM path ORDER BY topic_id, depth DESC`
+
+// topicSubtree returns a query selecting the IDs of the active topics matching cond and of
+// all their active descendants.
+func topicSubtree(cond string) string {
+	return `WITH RECURSIVE subtree AS (SELECT id FROM topics WHERE deleted_at IS NULL AND ` + cond + `
+	UNION SELECT t.id FROM topics t JOIN subtree s ON t.parent_id = s.id WHERE t.deleted_at IS NULL)
+	SELECT id FROM subtree`
+}
+
+// TopicTree returns the approved topics as a tree, each level sorted by name. With a rootID
+// only that topic's subtree is returned.
+func (s *topicStore) TopicTree(rootID string) ([]models.TopicNode, error) {
+	var topics []models.Topic
+	if err := s.DB.Where("status = ?", models.TopicStatusApproved).Order("name").Find(&topics).Error; err != nil {
+		return nil, err
+	}
+	children := make(map[string][]models.Topic)
+	for _, t := range topics {
+		parent := ""
+		if t.ParentID != nil {
+			parent = *t.ParentID
+		}
+		children[parent] = append(children[parent], t)
+	}
+	var build func(parent string) []models.TopicNode
+	build = func(parent string) []models.TopicNode {
+		nodes := []models.TopicNode{}
+		for _, t := range children[parent] {
+			nodes = append(nodes, models.TopicNode{Topic: t, Children: build(t.ID)})
+		}
+		return nodes
+	}
+
+	if rootID == "" {
+		return build(""), nil
+	}
+	for _, t := range topics {
+		if t.ID == rootID {
+			return []models.TopicNode{{Topic: t, Children: build(t.ID)}}, nil
+		}
+	}
+	return nil, ErrTopicNotFound
+}
+
+// TopicPaths returns the breadcrumb of each given topic, from its root topic down to the
+// topic itself. Unknown IDs are left out of the map.
+func (s *topicStore) TopicPaths(ids []string) (map[string][]models.TopicRef, error) {
+	paths := make(map[string][]models.TopicRef, len(ids))
+	if len(ids) == 0 {
+		return paths, nil
+	}
+	var rows []struct {
+		TopicID string
+		ID      string
+		Name    string
+	}
+	if err := s.DB.Raw(topicPaths, ids).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		paths[row.TopicID] = append(paths[row.TopicID], models.TopicRef{ID: row.ID, Name: row.Name})
+	}
+	return paths, nil
+}
+
+// MoveTopic moves a topic with its whole subtree under another topic, or to the root when
+// parentID is nil. A topic cannot be moved under itself or one of its descendants.
+func (s *topicStore) MoveTopic(id string, parentID *string) (*models.Topic, error) {
+	var topic models.Topic
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", topicTreeLock).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&topic, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTopicNotFound
+			}
+			return err
+		}
+		if parentID != nil {
+			if err := lockParentTopic(tx, *parentID); err != nil {
+				return err
+			}
+			var count int64
+			if err := tx.Raw(topicAncestors+" SELECT COUNT(*) FROM ancestors WHERE id = ?", *parentID, id).Scan(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrTopicCycle
+			}
+		}
+		topic.ParentID = parentID
+		return tx.Model(&topic).Update("parent_id", parentID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &topic, nil
+}
+
+// lockParentTopic checks that a parent topic exists, is active and approved, and holds it
+// until the transaction ends so it cannot be deleted while a child is added under it.
+func lockParentTopic(tx *gorm.DB, id string) error {
+	var parent models.Topic
+	err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Select("id").
+		First(&parent, "id = ? AND status = ?", id, models.TopicStatusApproved).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrParentTopicNotFound
+	}
+	return err
+}
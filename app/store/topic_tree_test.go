@@ -0,0 +1,113 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"knowledge-capsule/app/models"
+)
+
+// newTopicTree returns a store over the tree lang > go > generics, with a separate root db
+// and a pending proposal under lang.
+func newTopicTree(t *testing.T) *topicStore {
+	t.Helper()
+	db := newTestDB(t, &models.Topic{})
+	for _, topic := range []models.Topic{
+		{ID: "lang", TopicInput: models.TopicInput{Name: "Languages"}},
+		{ID: "go", TopicInput: models.TopicInput{Name: "Go", ParentID: strPtr("lang")}},
+		{ID: "generics", TopicInput: models.TopicInput{Name: "Generics", ParentID: strPtr("go")}},
+		{ID: "db", TopicInput: models.TopicInput{Name: "Databases"}},
+		{ID: "proposed", TopicInput: models.TopicInput{Name: "Zig", ParentID: strPtr("lang")}, Status: models.TopicStatusPending},
+	} {
+		if err := db.Create(&topic).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &topicStore{DB: db}
+}
+
+func TestMoveTopicPreventsCycles(t *testing.T) {
+	s := newTopicTree(t)
+	tests := []struct {
+		name, id string
+		parent   *string
+		wantErr  error
+	}{
+		{"under itself", "go", strPtr("go"), ErrTopicCycle},
+		{"under its child", "go", strPtr("generics"), ErrTopicCycle},
+		{"under its grandchild", "lang", strPtr("generics"), ErrTopicCycle},
+		{"under a pending topic", "db", strPtr("proposed"), ErrParentTopicNotFound},
+		{"under a missing topic", "db", strPtr("missing"), ErrParentTopicNotFound},
+		{"missing topic", "missing", nil, ErrTopicNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.MoveTopic(tt.id, tt.parent); !errors.Is(err, tt.wantErr) {
+				t.Errorf("MoveTopic(%s) error = %v, want %v", tt.id, err, tt.wantErr)
+			}
+		})
+	}
+
+	paths, err := s.TopicPaths([]string{"generics"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := refNames(paths["generics"]); got != "Languages/Go/Generics" {
+		t.Errorf("path after rejected moves = %s, want the tree unchanged", got)
+	}
+}
+
+func TestMoveTopicSubtree(t *testing.T) {
+	s := newTopicTree(t)
+	if _, err := s.MoveTopic("go", strPtr("db")); err != nil {
+		t.Fatalf("MoveTopic(go under db) error = %v", err)
+	}
+	paths, err := s.TopicPaths([]string{"generics", "go", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := refNames(paths["generics"]); got != "Databases/Go/Generics" {
+		t.Errorf("generics path = %s, want it to move with its parent", got)
+	}
+	if _, ok := paths["missing"]; ok {
+		t.Error("TopicPaths() returned a path for an unknown topic")
+	}
+
+	// A former ancestor may now go under the moved subtree.
+	if _, err := s.MoveTopic("lang", strPtr("generics")); err != nil {
+		t.Errorf("MoveTopic(lang under generics) error = %v", err)
+	}
+	if _, err := s.MoveTopic("lang", nil); err != nil {
+		t.Fatalf("MoveTopic(to root) error = %v", err)
+	}
+	tree, err := s.TopicTree("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree) != 2 || tree[0].Name != "Databases" || tree[1].Name != "Languages" || len(tree[1].Children) != 0 {
+		t.Errorf("TopicTree() roots = %+v, want Databases and an empty Languages (pending topics hidden)", tree)
+	}
+}
+
+func TestTopicTreeSubtree(t *testing.T) {
+	s := newTopicTree(t)
+	tree, err := s.TopicTree("go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree) != 1 || tree[0].ID != "go" || len(tree[0].Children) != 1 || tree[0].Children[0].ID != "generics" {
+		t.Errorf("TopicTree(go) = %+v", tree)
+	}
+	if _, err := s.TopicTree("proposed"); !errors.Is(err, ErrTopicNotFound) {
+		t.Errorf("TopicTree(pending) error = %v, want ErrTopicNotFound", err)
+	}
+}
+
+func refNames(refs []models.TopicRef) string {
+	names := make([]string, len(refs))
+	for i, r := range refs {
+		names[i] = r.Name
+	}
+	return strings.Join(names, "/")
+}
@@ -2,11 +2,28 @@ package store
 
 import (
 	"errors"
+	"time"
 
 	"knowledge-capsule/app/models"
 	"knowledge-capsule/pkg/utils"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrTopicExists is returned when a topic name is already taken by an active topic.
+	ErrTopicExists = errors.New("topic already exists")
+	// ErrTopicNotFound is returned when a capsule refers to a topic_id that does not exist.
+	ErrTopicNotFound = errors.New("topic not found")
+	// ErrTopicInUse is returned when deleting a topic that capsules still belong to.
+	ErrTopicInUse = errors.New("topic is still used by capsules")
+	// ErrTopicHasChildren is returned when deleting a topic that still has subtopics.
+	ErrTopicHasChildren = errors.New("topic still has subtopics")
+	// ErrParentTopicNotFound is returned when a parent_id does not name an active topic.
+	ErrParentTopicNotFound = errors.New("parent topic not found")
+	// ErrTopicCycle is returned when moving a topic under itself or one of its subtopics.
+	ErrTopicCycle = errors.New("topic cannot be moved under itself or its subtopics")
 )
 
 // topicStore implements topic storage with GORM.
@@ -19,40 +36,67 @@ func NewTopicStore(db *gorm.DB) TopicStore {
 	return &topicStore{DB: db}
 }
 
-// AddTopic creates a new topic.
-func (s *topicStore) AddTopic(name, description string) (*models.Topic, error) {
+// AddTopic creates a new topic, under input.ParentID when it is set. An approved topic
+// starts with its creator as maintainer; otherwise it is a pending proposal.
+func (s *topicStore) AddTopic(userID string, input models.TopicInput, approved bool) (*models.Topic, error) {
 	var existing models.Topic
-	if err := s.DB.Where("name = ?", name).First(&existing).Error; err == nil {
-		return nil, errors.New("topic already exists")
+	if err := s.DB.Where("name = ?", input.Name).First(&existing).Error; err == nil {
+		return nil, ErrTopicExists
 	}
 
 	topic := models.Topic{
-		ID: utils.GenerateUUID(),
-		TopicInput: models.TopicInput{
-			Name:        name,
-			Description: description,
-		},
+		ID:         utils.GenerateUUID(),
+		TopicInput: input,
+		CreatedBy:  userID,
+		Status:     models.TopicStatusPending,
 	}
-	if err := s.DB.Create(&topic).Error; err != nil {
+	if approved {
+		topic.Status = models.TopicStatusApproved
+	}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if input.ParentID != nil {
+			if err := lockParentTopic(tx, *input.ParentID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&topic).Error; err != nil {
+			return err
+		}
+		if !approved {
+			return nil
+		}
+		return tx.Create(&models.TopicMaintainer{TopicID: topic.ID, UserID: userID, AddedBy: userID}).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &topic, nil
 }
 
-// GetAllTopics returns topics with optional search filter.
-func (s *topicStore) GetAllTopics(filters *models.TopicFilters) ([]models.Topic, error) {
-	query := s.DB.Model(&models.Topic{})
+// topicSorts are the sort keys for topic lists.
+var topicSorts = map[string]sortKey[models.Topic]{
+	models.SortCreatedAt: {"topics.created_at", func(t models.Topic) interface{} { return t.CreatedAt }},
+	models.SortUpdatedAt: {"topics.updated_at", func(t models.Topic) interface{} { return t.UpdatedAt }},
+	models.SortName:      {"topics.name", func(t models.Topic) interface{} { return t.Name }},
+}
 
+// GetAllTopics returns one page of approved topics with optional search filter. Lists with
+// a search query default to relevance order (name similarity), others to name.
+func (s *topicStore) GetAllTopics(filters *models.TopicFilters, opts models.ListOptions) ([]models.Topic, models.PageInfo, error) {
+	query := s.DB.Model(&models.Topic{}).Where("topics.status = ?", models.TopicStatusApproved)
+	spec := listSpec[models.Topic]{
+		id:          "topics.id",
+		idOf:        func(t models.Topic) string { return t.ID },
+		sorts:       topicSorts,
+		defaultSort: models.SortName,
+	}
 	if filters != nil && filters.Q != "" {
 		pattern := "%" + filters.Q + "%"
-		query = query.Where("name ILIKE ? OR description ILIKE ?", pattern, pattern)
-	}
-
-	var topics []models.Topic
-	if err := query.Find(&topics).Error; err != nil {
-		return nil, err
+		query = query.Where("topics.name ILIKE ? OR topics.description ILIKE ?", pattern, pattern)
+		spec.relevance = &clause.Expr{SQL: "word_similarity(?, topics.name)", Vars: []interface{}{filters.Q}}
+		spec.defaultSort = models.SortRelevance
 	}
-	return topics, nil
+	return pageQuery(query, spec, opts)
 }
 
 // FindByID returns a topic by its ID.
@@ -68,42 +112,188 @@ func (s *topicStore) FindByID(id string) (*models.Topic, error) {
 	return &topic, nil
 }
 
-// UpdateTopic updates a topic by ID.
+// UpdateTopic updates a topic by ID. A rename is carried over to the topic's capsules,
+// including trashed ones.
 func (s *topicStore) UpdateTopic(id, name, description string) (*models.Topic, error) {
-	result := s.DB.Model(&models.Topic{}).Where("id = ?", id).Updates(map[string]interface{}{
-		"name":        name,
-		"description": description,
+	var topic models.Topic
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&topic, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTopicNotFound
+			}
+			return err
+		}
+		if name != topic.Name {
+			var count int64
+			if err := tx.Model(&models.Topic{}).Where("name = ? AND id <> ?", name, id).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrTopicExists
+			}
+			if err := tx.Unscoped().Model(&models.Capsule{}).Where("topic_id = ?", id).
+				UpdateColumn("topic", name).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&topic).Updates(map[string]interface{}{
+			"name":        name,
+			"description": description,
+		}).Error
 	})
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	if result.RowsAffected == 0 {
-		return nil, errors.New("topic not found")
+	if err != nil {
+		return nil, err
 	}
-	var topic models.Topic
-	s.DB.First(&topic, "id = ?", id)
 	return &topic, nil
 }
 
-// DeleteTopic removes a topic by ID.
-func (s *topicStore) DeleteTopic(id string) error {
-	result := s.DB.Where("id = ?", id).Delete(&models.Topic{})
-	if result.Error != nil {
-		return result.Error
+// DeleteTopic moves a topic to the trash of the user who deleted it. Topics with subtopics
+// or that capsules belong to, including capsules in a trash, cannot be deleted; move or
+// purge those first.
+func (s *topicStore) DeleteTopic(id, userID string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var topic models.Topic
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&topic, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTopicNotFound
+			}
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.Topic{}).Where("parent_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrTopicHasChildren
+		}
+		if err := tx.Unscoped().Model(&models.Capsule{}).Where("topic_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrTopicInUse
+		}
+		return tx.Model(&topic).UpdateColumns(map[string]interface{}{
+			"deleted_at": time.Now(),
+			"deleted_by": userID,
+		}).Error
+	})
+}
+
+// RestoreTopic moves a topic the user deleted out of the trash. It fails if another
+// topic with the same name was created in the meantime, and becomes a root topic if its
+// parent is gone.
+func (s *topicStore) RestoreTopic(id, userID string) (*models.Topic, error) {
+	var topic models.Topic
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND deleted_by = ? AND deleted_at IS NOT NULL", id, userID).First(&topic).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("topic not found in trash")
+			}
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.Topic{}).Where("name = ?", topic.Name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrTopicExists
+		}
+		if topic.ParentID != nil {
+			if err := lockParentTopic(tx, *topic.ParentID); errors.Is(err, ErrParentTopicNotFound) {
+				topic.ParentID = nil
+			} else if err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Model(&topic).UpdateColumns(map[string]interface{}{
+			"deleted_at": nil,
+			"deleted_by": "",
+			"parent_id":  topic.ParentID,
+		}).Error; err != nil {
+			return err
+		}
+		topic.DeletedAt = gorm.DeletedAt{}
+		topic.DeletedBy = ""
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	if result.RowsAffected == 0 {
-		return errors.New("topic not found")
+	return &topic, nil
+}
+
+// PurgeTopic permanently deletes a topic the user moved to the trash, with its maintainers.
+func (s *topicStore) PurgeTopic(id, userID string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("id = ? AND deleted_by = ? AND deleted_at IS NOT NULL", id, userID).Delete(&models.Topic{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("topic not found in trash")
+		}
+		return tx.Where("topic_id = ?", id).Delete(&models.TopicMaintainer{}).Error
+	})
+}
+
+// PurgeTrashedTopics permanently deletes topics trashed before the given time, with their
+// maintainers. An empty userID purges every user's trash. Like PurgeTrashedCapsules, it works
+// in batches of purgeBatchSize.
+func (s *topicStore) PurgeTrashedTopics(userID string, deletedBefore time.Time) (int, error) {
+	purged := 0
+	for {
+		var ids []string
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			query := tx.Unscoped().Model(&models.Topic{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore)
+			if userID != "" {
+				query = query.Where("deleted_by = ?", userID)
+			}
+			if err := query.Order("id").Limit(purgeBatchSize).Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+			if err := tx.Where("topic_id IN ?", ids).Delete(&models.TopicMaintainer{}).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Where("id IN ?", ids).Delete(&models.Topic{}).Error
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged += len(ids)
+		if len(ids) < purgeBatchSize {
+			return purged, nil
+		}
 	}
-	return nil
 }
 
-// SearchTopics searches topics by name or description.
+// SearchTopics searches topics by name or description, tolerating typos in the name.
+// Closest matches come first.
 func (s *topicStore) SearchTopics(query string, limit int) ([]models.Topic, error) {
 	if limit <= 0 {
 		limit = 20
 	}
 	pattern := "%" + query + "%"
 	var topics []models.Topic
-	err := s.DB.Where("name ILIKE ? OR description ILIKE ?", pattern, pattern).Limit(limit).Find(&topics).Error
+	err := s.DB.Where("name ILIKE ? OR description ILIKE ? OR ? <% name", pattern, pattern, query).
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: "word_similarity(?, name) DESC", Vars: []interface{}{query}}}).
+		Limit(limit).Find(&topics).Error
 	return topics, err
 }
+
+// SuggestTopics returns approved topic names matching a partly typed query, best first.
+func (s *topicStore) SuggestTopics(query string, limit int) ([]models.Suggestion, error) {
+	suggestions := []models.Suggestion{}
+	err := s.DB.Table("topics").Select("name AS text, "+suggestScore("name")+" AS score", suggestScoreArgs(query)...).
+		Where("deleted_at IS NULL AND status = ?", models.TopicStatusApproved).Where(suggestMatch("name"), suggestMatchArgs(query)...).
+		Order("score DESC").Limit(limit).Scan(&suggestions).Error
+	for i := range suggestions {
+		suggestions[i].Type = models.SuggestionTopic
+	}
+	return suggestions, err
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"knowledge-capsule/app/models"
+
+	"gorm.io/gorm"
+)
+
+// newTopicDB returns a database with the topic "Golang" (ID t1) and the given capsules.
+func newTopicDB(t *testing.T, capsules ...models.Capsule) *gorm.DB {
+	t.Helper()
+	db := newTestDB(t, &models.Topic{}, &models.Capsule{})
+	if err := db.Create(&models.Topic{ID: "t1", TopicInput: models.TopicInput{Name: "Golang"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range capsules {
+		if err := db.Create(&c).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestResolveTopic(t *testing.T) {
+	db := newTopicDB(t)
+	tests := []struct {
+		name      string
+		input     models.CapsuleInput
+		wantTopic string
+		wantErr   error
+	}{
+		{"by ID", models.CapsuleInput{TopicID: strPtr("t1"), Topic: "stale name"}, "Golang", nil},
+		{"by name, any case", models.CapsuleInput{Topic: "  golang "}, "Golang", nil},
+		{"unknown ID falls back to name", models.CapsuleInput{TopicID: strPtr("gone"), Topic: "GOLANG"}, "Golang", nil},
+		{"unknown ID without name", models.CapsuleInput{TopicID: strPtr("gone")}, "", ErrTopicNotFound},
+		{"no topic", models.CapsuleInput{Topic: "  "}, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := tt.input
+			err := resolveTopic(db, "u1", &input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resolveTopic() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if input.Topic != tt.wantTopic {
+				t.Errorf("topic = %q, want %q", input.Topic, tt.wantTopic)
+			}
+			if tt.wantTopic == "" && input.TopicID != nil {
+				t.Errorf("topic_id = %q, want none", *input.TopicID)
+			}
+			if tt.wantTopic != "" && (input.TopicID == nil || *input.TopicID != "t1") {
+				t.Errorf("topic_id = %v, want t1", input.TopicID)
+			}
+		})
+	}
+
+	input := models.CapsuleInput{Topic: "Rust"}
+	if err := resolveTopic(db, "u1", &input); err != nil || input.TopicID == nil {
+		t.Fatalf("resolveTopic(new name) = %v, %v", input.TopicID, err)
+	}
+	var created models.Topic
+	if err := db.First(&created, "id = ?", *input.TopicID).Error; err != nil ||
+		created.Name != "Rust" || created.CreatedBy != "u1" || created.Status != models.TopicStatusPending {
+		t.Errorf("created topic = %+v, %v; want a pending proposal by u1", created, err)
+	}
+}
+
+func TestUpdateTopicRenameCascades(t *testing.T) {
+	trashed := gorm.DeletedAt{Time: time.Now(), Valid: true}
+	db := newTopicDB(t,
+		models.Capsule{ID: "c1", UserID: "u1", CapsuleInput: models.CapsuleInput{Title: "A", Topic: "Golang", TopicID: strPtr("t1")}},
+		models.Capsule{ID: "c2", UserID: "u1", CapsuleInput: models.CapsuleInput{Title: "B", Topic: "Golang", TopicID: strPtr("t1")}, DeletedAt: trashed},
+		models.Capsule{ID: "c3", UserID: "u1", CapsuleInput: models.CapsuleInput{Title: "C", Topic: "Golang"}},
+	)
+	db.Create(&models.Topic{ID: "t2", TopicInput: models.TopicInput{Name: "Rust"}})
+	s := NewTopicStore(db)
+
+	if _, err := s.UpdateTopic("t1", "Rust", ""); !errors.Is(err, ErrTopicExists) {
+		t.Errorf("UpdateTopic(taken name) error = %v, want ErrTopicExists", err)
+	}
+	if _, err := s.UpdateTopic("t1", "Go", "The Go language"); err != nil {
+		t.Fatalf("UpdateTopic() error = %v", err)
+	}
+	topics := map[string]string{}
+	var rows []struct{ ID, Topic string }
+	db.Unscoped().Model(&models.Capsule{}).Select("id, topic").Scan(&rows)
+	for _, r := range rows {
+		topics[r.ID] = r.Topic
+	}
+	// Linked capsules follow the rename, trashed ones too; an unlinked capsule keeps its name.
+	if topics["c1"] != "Go" || topics["c2"] != "Go" || topics["c3"] != "Golang" {
+		t.Errorf("capsule topics after rename = %v", topics)
+	}
+}
+
+func TestDeleteTopicGuards(t *testing.T) {
+	trashed := gorm.DeletedAt{Time: time.Now(), Valid: true}
+	db := newTopicDB(t, models.Capsule{ID: "c1", UserID: "u1", CapsuleInput: models.CapsuleInput{Title: "A", Topic: "Golang", TopicID: strPtr("t1")}, DeletedAt: trashed})
+	db.Create(&models.Topic{ID: "parent", TopicInput: models.TopicInput{Name: "Languages"}})
+	db.Create(&models.Topic{ID: "child", TopicInput: models.TopicInput{Name: "Zig", ParentID: strPtr("parent")}})
+	s := NewTopicStore(db)
+
+	if err := s.DeleteTopic("t1", "u1"); !errors.Is(err, ErrTopicInUse) {
+		t.Errorf("DeleteTopic(used by a trashed capsule) error = %v, want ErrTopicInUse", err)
+	}
+	if err := s.DeleteTopic("parent", "u1"); !errors.Is(err, ErrTopicHasChildren) {
+		t.Errorf("DeleteTopic(with subtopics) error = %v, want ErrTopicHasChildren", err)
+	}
+	if err := s.DeleteTopic("missing", "u1"); !errors.Is(err, ErrTopicNotFound) {
+		t.Errorf("DeleteTopic(missing) error = %v, want ErrTopicNotFound", err)
+	}
+
+	db.Unscoped().Delete(&models.Capsule{}, "id = ?", "c1")
+	if err := s.DeleteTopic("t1", "u1"); err != nil {
+		t.Fatalf("DeleteTopic(unused) error = %v", err)
+	}
+	// A trashed topic no longer matches by name, so new capsules get a fresh topic.
+	input := models.CapsuleInput{Topic: "golang"}
+	if err := resolveTopic(db, "u1", &input); err != nil || input.TopicID == nil || *input.TopicID == "t1" {
+		t.Errorf("resolveTopic(trashed topic's name) = %v, %v; want a new topic", input.TopicID, err)
+	}
+}
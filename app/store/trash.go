@@ -0,0 +1,43 @@
+package store
+
+import (
+	"slices"
+
+	"knowledge-capsule/app/models"
+)
+
+// trashSorts are the sort keys for the trash.
+var trashSorts = map[string]sortKey[models.TrashItem]{
+	models.SortDeletedAt: {"trash.deleted_at", func(t models.TrashItem) interface{} { return t.DeletedAt }},
+}
+
+// ListTrash returns one page of a user's trash: their trashed capsules and the topics they
+// deleted, limited to types, most recently deleted first by default. PurgeAt is left to the
+// caller.
+func (s *capsuleStore) ListTrash(userID string, types []string, opts models.ListOptions) ([]models.TrashItem, models.PageInfo, error) {
+	var parts []interface{}
+	if slices.Contains(types, models.TrashCapsule) {
+		parts = append(parts, s.DB.Unscoped().Model(&models.Capsule{}).
+			Select("'"+models.TrashCapsule+"' AS type, id, title, deleted_at").
+			Where("user_id = ? AND deleted_at IS NOT NULL", userID))
+	}
+	if slices.Contains(types, models.TrashTopic) {
+		parts = append(parts, s.DB.Unscoped().Model(&models.Topic{}).
+			Select("'"+models.TrashTopic+"' AS type, id, name AS title, deleted_at").
+			Where("deleted_by = ? AND deleted_at IS NOT NULL", userID))
+	}
+	if len(parts) == 0 {
+		return []models.TrashItem{}, models.PageInfo{}, nil
+	}
+	union := "?"
+	for range parts[1:] {
+		union += " UNION ALL ?"
+	}
+	query := s.DB.Table("("+union+") AS trash", parts...)
+	return pageQuery(query, listSpec[models.TrashItem]{
+		id:          "trash.id",
+		idOf:        func(t models.TrashItem) string { return t.ID },
+		sorts:       trashSorts,
+		defaultSort: models.SortDeletedAt,
+	}, opts)
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+const recoveryCodeCount = 10
+
+// twoFactorStore implements TOTP and recovery code storage with GORM.
+type twoFactorStore struct {
+	DB *gorm.DB
+}
+
+// NewTwoFactorStore returns a TwoFactorStore backed by GORM.
+func NewTwoFactorStore(db *gorm.DB) TwoFactorStore {
+	return &twoFactorStore{DB: db}
+}
+
+// SetPendingSecret stores a new TOTP secret for a user who has not enabled 2FA yet.
+func (s *twoFactorStore) SetPendingSecret(userID, secret string) error {
+	result := s.DB.Model(&models.User{}).Where("id = ? AND two_factor_enabled = ?", userID, false).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("two-factor authentication is already enabled")
+	}
+	return nil
+}
+
+// Enable turns on 2FA, records the step used to confirm it and issues fresh recovery codes.
+func (s *twoFactorStore) Enable(userID string, step int64) ([]string, error) {
+	var codes []string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).Where("id = ? AND two_factor_enabled = ? AND totp_secret <> ''", userID, false).
+			Updates(map[string]interface{}{"two_factor_enabled": true, "totp_last_step": step})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("two-factor setup not started or already enabled")
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	return codes, err
+}
+
+// Disable turns off 2FA and deletes the secret and recovery codes.
+func (s *twoFactorStore) Disable(userID string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Updates(map[string]interface{}{"two_factor_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// MarkStepUsed records an accepted TOTP step. It fails if the step (or a later one) was
+// already used, which prevents replaying a code within its validity window.
+func (s *twoFactorStore) MarkStepUsed(userID string, step int64) (bool, error) {
+	result := s.DB.Model(&models.User{}).Where("id = ? AND totp_last_step < ?", userID, step).Update("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of a user.
+func (s *twoFactorStore) RegenerateRecoveryCodes(userID string) ([]string, error) {
+	var codes []string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	return codes, err
+}
+
+// UseRecoveryCode consumes a matching unused recovery code.
+func (s *twoFactorStore) UseRecoveryCode(userID, code string) (bool, error) {
+	result := s.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, utils.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRecoveryCodes returns the number of unused recovery codes.
+func (s *twoFactorStore) CountRecoveryCodes(userID string) (int, error) {
+	var count int64
+	err := s.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return int(count), err
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID string) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		secret, err := utils.GenerateTOTPSecret()
+		if err != nil {
+			return nil, err
+		}
+		// 10 base32 characters (50 bits), shown as xxxxx-xxxxx
+		code := strings.ToLower(secret[:5] + "-" + secret[5:10])
+		codes = append(codes, code)
+		rows = append(rows, models.RecoveryCode{
+			ID:       utils.GenerateUUID(),
+			UserID:   userID,
+			CodeHash: utils.HashToken(normalizeRecoveryCode(code)),
+		})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -2,11 +2,13 @@ package store
 
 import (
 	"errors"
+	"time"
 
 	"knowledge-capsule/app/models"
 	"knowledge-capsule/pkg/utils"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // userStore implements user storage with GORM.
@@ -137,14 +139,39 @@ func (s *userStore) UpdateUserRole(id, role string) error {
 	return nil
 }
 
-// SearchUsers searches users by name or email.
+// UpdatePasswordHash replaces a user's stored password hash (e.g. after upgrading its format).
+func (s *userStore) UpdatePasswordHash(id, passwordHash string) error {
+	result := s.DB.Model(&models.User{}).Where("id = ?", id).Update("password_hash", passwordHash)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// MarkEmailVerified records that the user proved ownership of their email.
+func (s *userStore) MarkEmailVerified(id string) error {
+	result := s.DB.Model(&models.User{}).Where("id = ? AND email_verified_at IS NULL", id).Update("email_verified_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// SearchUsers searches users by name or email, tolerating typos. Closest matches come first.
 func (s *userStore) SearchUsers(query string, limit int) ([]models.User, error) {
 	if limit <= 0 {
 		limit = 20
 	}
 	pattern := "%" + query + "%"
 	var users []models.User
-	err := s.DB.Where("name ILIKE ? OR email ILIKE ?", pattern, pattern).Limit(limit).Find(&users).Error
+	err := s.DB.Where("name ILIKE ? OR email ILIKE ? OR ? <% name OR ? <% email", pattern, pattern, query, query).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "GREATEST(word_similarity(?, name), word_similarity(?, email)) DESC",
+			Vars: []interface{}{query, query},
+		}}).Limit(limit).Find(&users).Error
 	return users, err
 }
 
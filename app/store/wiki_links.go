@@ -0,0 +1,129 @@
+package store
+
+import (
+	"knowledge-capsule/app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// wikiLinkTarget picks the capsule a wiki link resolves to among the active capsules of the
+// link source's owner: the one whose ID is the link text, else the oldest with that title.
+const wikiLinkTarget = `(SELECT c.id FROM capsules c JOIN capsules s ON s.id = wiki_links.source_id
+	WHERE c.user_id = s.user_id AND c.deleted_at IS NULL
+		AND (c.id = wiki_links.target OR LOWER(c.title) = LOWER(wiki_links.target))
+	ORDER BY c.id = wiki_links.target DESC, c.created_at LIMIT 1)`
+
+// linkedCapsuleVisible matches linked capsules v that a user owns or that are shared with
+// them. It takes the user ID followed by granteeArgs.
+const linkedCapsuleVisible = "(v.user_id = ? OR EXISTS (SELECT 1 FROM capsule_shares " +
+	"WHERE capsule_shares.capsule_id = v.id AND " + granteeCondition + "))"
+
+func linkedCapsuleArgs(userID string) []interface{} {
+	return append([]interface{}{userID}, granteeArgs(userID)...)
+}
+
+// ListOutlinks returns the wiki links in a capsule's content in order of appearance. Links to
+// capsules the user cannot open are listed by their text only.
+func (s *capsuleStore) ListOutlinks(capsuleID, userID string) ([]models.WikiLinkView, error) {
+	links := []models.WikiLinkView{}
+	err := s.DB.Table("wiki_links").
+		Select("wiki_links.target, v.id AS capsule_id, COALESCE(v.title, '') AS title, c.id IS NULL AS broken").
+		Joins("LEFT JOIN capsules c ON c.id = wiki_links.target_id AND c.deleted_at IS NULL").
+		Joins("LEFT JOIN capsules v ON v.id = c.id AND "+linkedCapsuleVisible, linkedCapsuleArgs(userID)...).
+		Where("wiki_links.source_id = ?", capsuleID).
+		Order("wiki_links.position").Scan(&links).Error
+	return links, err
+}
+
+// ListBacklinks returns the capsules outside the trash whose content links to a capsule,
+// by title, limited to those the user can open.
+func (s *capsuleStore) ListBacklinks(capsuleID, userID string) ([]models.WikiLinkView, error) {
+	links := []models.WikiLinkView{}
+	err := s.DB.Table("wiki_links").
+		Select("wiki_links.target, v.id AS capsule_id, v.title").
+		Joins("JOIN capsules v ON v.id = wiki_links.source_id AND v.deleted_at IS NULL AND "+linkedCapsuleVisible, linkedCapsuleArgs(userID)...).
+		Where("wiki_links.target_id = ?", capsuleID).
+		Order("v.title, v.id").Scan(&links).Error
+	return links, err
+}
+
+// syncWikiLinks replaces a capsule's stored links with those parsed from its content and
+// resolves them.
+func syncWikiLinks(tx *gorm.DB, capsule *models.Capsule) error {
+	if err := tx.Where("source_id = ?", capsule.ID).Delete(&models.WikiLink{}).Error; err != nil {
+		return err
+	}
+	targets := models.ParseWikiLinks(capsule.Content)
+	if len(targets) == 0 {
+		return nil
+	}
+	links := make([]models.WikiLink, len(targets))
+	for i, target := range targets {
+		links[i] = models.WikiLink{SourceID: capsule.ID, Target: target, Position: i}
+	}
+	if err := tx.Create(&links).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.WikiLink{}).Where("source_id = ?", capsule.ID).
+		Update("target_id", gorm.Expr(wikiLinkTarget)).Error
+}
+
+// resolveWikiLinksTo points the owner's broken links that name a capsule, by title or ID,
+// at it.
+func resolveWikiLinksTo(tx *gorm.DB, capsule *models.Capsule) error {
+	return tx.Model(&models.WikiLink{}).
+		Where("target_id IS NULL AND (target = ? OR LOWER(target) = LOWER(?))", capsule.ID, capsule.Title).
+		Where("source_id IN (SELECT id FROM capsules WHERE user_id = ?)", capsule.UserID).
+		Update("target_id", capsule.ID).Error
+}
+
+// retitleWikiLinks rewrites links that name a renamed capsule by its old title to the new
+// one, in every other capsule linking to it, trashed ones included. When the new title
+// cannot be linked, or an older capsule of the owner has it, links use the capsule ID. The
+// rewrite records no revision.
+func retitleWikiLinks(tx *gorm.DB, capsule *models.Capsule, oldTitle string) error {
+	var sourceIDs []string
+	if err := tx.Model(&models.WikiLink{}).
+		Where("target_id = ? AND LOWER(target) = LOWER(?) AND source_id <> ?", capsule.ID, oldTitle, capsule.ID).
+		Pluck("source_id", &sourceIDs).Error; err != nil || len(sourceIDs) == 0 {
+		return err
+	}
+
+	to := capsule.Title
+	var older int64
+	if err := tx.Model(&models.Capsule{}).
+		Where("user_id = ? AND id <> ? AND LOWER(title) = LOWER(?) AND created_at < ?", capsule.UserID, capsule.ID, to, capsule.CreatedAt).
+		Count(&older).Error; err != nil {
+		return err
+	}
+	if older > 0 || !models.WikiLinkable(to) {
+		to = capsule.ID
+	}
+
+	var sources []models.Capsule
+	if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "content").
+		Where("id IN ?", sourceIDs).Find(&sources).Error; err != nil {
+		return err
+	}
+	for i := range sources {
+		sources[i].Content = models.RewriteWikiLinks(sources[i].Content, oldTitle, to)
+		if err := tx.Unscoped().Model(&sources[i]).UpdateColumn("content", sources[i].Content).Error; err != nil {
+			return err
+		}
+		if err := syncWikiLinks(tx, &sources[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unlinkCapsules drops the links of purged capsules and re-resolves the links that pointed
+// at them; those stay broken unless another capsule matches.
+func unlinkCapsules(tx *gorm.DB, ids []string) error {
+	if err := tx.Where("source_id IN ?", ids).Delete(&models.WikiLink{}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.WikiLink{}).Where("target_id IN ?", ids).
+		Update("target_id", gorm.Expr(wikiLinkTarget)).Error
+}
@@ -8,9 +8,12 @@ import (
 
 	"knowledge-capsule/app/handlers"
 	"knowledge-capsule/app/middleware"
+	"knowledge-capsule/app/models"
 	_ "knowledge-capsule/docs"
 	"knowledge-capsule/pkg/config"
 	"knowledge-capsule/pkg/db"
+	"knowledge-capsule/pkg/mailer"
+	"knowledge-capsule/pkg/oidc"
 	"knowledge-capsule/pkg/utils"
 
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -31,7 +34,7 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
-// @description Enter your JWT token (or "Bearer &lt;token&gt;" for clarity). Get token from POST /api/auth/login.
+// @description Enter your JWT token or personal access token (or "Bearer &lt;token&gt;" for clarity). Get a JWT from POST /api/auth/login or create an access token with POST /api/users/me/tokens.
 
 // @BasePath /
 func main() {
@@ -51,13 +54,56 @@ func main() {
 	}
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level})))
 
-	database, err := db.Open(cfg.DatabaseURL)
+	database, err := db.Open(cfg.DatabaseURL, cfg.SearchSimilarity)
 	if err != nil {
 		slog.Error("Failed to connect to database", "error", err)
 		os.Exit(1)
 	}
 
+	if err := utils.InitJWT(utils.JWTSettings{
+		Algorithm:       cfg.JWTAlgorithm,
+		Secret:          cfg.JWTSecret,
+		PreviousSecrets: cfg.JWTPreviousSecrets,
+		PrivateKeyFile:  cfg.JWTPrivateKeyFile,
+		VerifyKeyFiles:  cfg.JWTVerifyKeyFiles,
+		Issuer:          cfg.JWTIssuer,
+		Audience:        cfg.JWTAudience,
+	}); err != nil {
+		slog.Error("Failed to load JWT keys", "error", err)
+		os.Exit(1)
+	}
+
+	utils.InitPasswordParams(utils.PasswordParams{
+		Memory:      uint32(cfg.PasswordHashMemory),
+		Iterations:  uint32(cfg.PasswordHashIterations),
+		Parallelism: uint8(cfg.PasswordHashParallelism),
+	})
+	utils.InitTrustedProxies(cfg.TrustedProxies)
+
 	handlers.InitStores(database)
+	handlers.InitAuth(handlers.AuthSettings{
+		AccessTokenTTL:           cfg.AccessTokenTTL,
+		RefreshTokenTTL:          cfg.RefreshTokenTTL,
+		RequireEmailVerification: cfg.RequireEmailVerification,
+	})
+	handlers.InitThrottle(handlers.ThrottleSettings{
+		Account:  cfg.LoginAccountPolicy,
+		IP:       cfg.LoginIPPolicy,
+		Register: cfg.RegisterIPPolicy,
+		Mail:     cfg.MailPolicy,
+	})
+	if cfg.Mailer == "smtp" {
+		handlers.InitMailer(mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), cfg.AppBaseURL)
+	} else {
+		handlers.InitMailer(mailer.NewFileMailer(cfg.MailDir, cfg.MailFrom), cfg.AppBaseURL)
+	}
+	providers := make([]*oidc.Provider, 0, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		providers = append(providers, oidc.NewProvider(p))
+	}
+	handlers.InitOIDC(providers, cfg.OIDCSuccessRedirect)
+	middleware.InitAuth(handlers.SessionStore, handlers.APITokenStore, handlers.UserStore)
+	middleware.InitRBAC(handlers.SettingsStore)
 
 	if err := db.SeedSuperAdmin(database, cfg.SuperAdminEmail, cfg.SuperAdminPassword, cfg.SuperAdminName); err != nil {
 		slog.Error("Failed to seed superadmin", "error", err)
@@ -65,6 +111,7 @@ func main() {
 	}
 
 	handlers.InitChat(cfg.CORSOrigins)
+	handlers.InitTrash(cfg.TrashRetention, cfg.TrashPurgeInterval)
 
 	mux := http.NewServeMux()
 
@@ -73,40 +120,72 @@ func main() {
 	mux.HandleFunc("/api", handlers.ApiRootHandler)
 
 	mux.HandleFunc("/health", handlers.HealthHandler)
+	mux.HandleFunc("/.well-known/jwks.json", handlers.JWKSHandler)
 	mux.HandleFunc("/test-ws", handlers.TestChatHandler)
 
+	// Public read-only capsules (no auth)
+	mux.HandleFunc("/p/", handlers.PublicCapsulePage)
+	mux.HandleFunc("/api/explore", handlers.ExploreHandler)
+	mux.HandleFunc("/api/explore/", handlers.ExploreHandler)
+
 	// Swagger
 	mux.HandleFunc("/docs/", httpSwagger.WrapHandler)
 
 	// Public routes
 	mux.HandleFunc("/api/auth/register", handlers.RegisterHandler)
 	mux.HandleFunc("/api/auth/login", handlers.LoginHandler)
-
-	// User routes
-	mux.Handle("/api/users", middleware.AuthMiddleware(middleware.RequireAdmin(http.HandlerFunc(handlers.ListUsers))))
-	mux.Handle("/api/users/", middleware.AuthMiddleware(http.HandlerFunc(handlers.UserHandler)))
+	mux.HandleFunc("/api/auth/login/2fa", handlers.LoginTwoFactorHandler)
+	mux.HandleFunc("/api/auth/refresh", handlers.RefreshHandler)
+	mux.Handle("/api/auth/logout", middleware.AuthMiddleware(middleware.RejectAPITokens(http.HandlerFunc(handlers.LogoutHandler))))
+	mux.HandleFunc("/api/auth/forgot-password", handlers.ForgotPasswordHandler)
+	mux.HandleFunc("/api/auth/reset-password", handlers.ResetPasswordHandler)
+	mux.HandleFunc("/api/auth/verify-email", handlers.VerifyEmailHandler)
+	mux.HandleFunc("/api/auth/oidc", handlers.OIDCHandler)
+	mux.HandleFunc("/api/auth/oidc/", handlers.OIDCHandler)
+
+	// Personal access token scopes (session JWTs are not restricted)
+	adminScope := middleware.RequireScope(models.ScopeAdmin, models.ScopeAdmin)
+	topicScope := middleware.RequireScope(models.ScopeTopicsRead, models.ScopeTopicsWrite)
+	capsuleScope := middleware.RequireScope(models.ScopeCapsulesRead, models.ScopeCapsulesWrite)
+	chatScope := middleware.RequireScope(models.ScopeChat, models.ScopeChat)
+
+	// User routes (profile, sessions and API tokens need an interactive login)
+	mux.Handle("/api/users", middleware.AuthMiddleware(adminScope(middleware.RequireAdmin(http.HandlerFunc(handlers.ListUsers)))))
+	mux.Handle("/api/users/", middleware.AuthMiddleware(middleware.RejectAPITokens(http.HandlerFunc(handlers.UserHandler))))
 
 	// Admin routes
-	mux.Handle("/api/admin/search", middleware.AuthMiddleware(middleware.RequireAdmin(http.HandlerFunc(handlers.GlobalSearch))))
-	mux.Handle("/api/admin/admins", middleware.AuthMiddleware(middleware.RequireSuperAdmin(http.HandlerFunc(handlers.ListAdmins))))
-	mux.Handle("/api/admin/users/", middleware.AuthMiddleware(middleware.RequireSuperAdmin(http.HandlerFunc(handlers.AdminUsersHandler))))
+	mux.Handle("/api/admin/search", middleware.AuthMiddleware(adminScope(middleware.RequireAdmin(http.HandlerFunc(handlers.GlobalSearch)))))
+	mux.Handle("/api/admin/admins", middleware.AuthMiddleware(adminScope(middleware.RequireSuperAdmin(http.HandlerFunc(handlers.ListAdmins)))))
+	mux.Handle("/api/admin/security", middleware.AuthMiddleware(adminScope(middleware.RequireSuperAdmin(http.HandlerFunc(handlers.SecuritySettingsHandler)))))
+	mux.Handle("/api/admin/lockouts", middleware.AuthMiddleware(adminScope(middleware.RequireAdmin(http.HandlerFunc(handlers.LockoutsHandler)))))
+	mux.Handle("/api/admin/lockouts/", middleware.AuthMiddleware(adminScope(middleware.RequireAdmin(http.HandlerFunc(handlers.LockoutsHandler)))))
+	mux.Handle("/api/admin/topics", middleware.AuthMiddleware(adminScope(middleware.RequireAdmin(http.HandlerFunc(handlers.AdminTopicsHandler)))))
+	mux.Handle("/api/admin/topics/", middleware.AuthMiddleware(adminScope(middleware.RequireAdmin(http.HandlerFunc(handlers.AdminTopicsHandler)))))
+	mux.Handle("/api/admin/tags/", middleware.AuthMiddleware(adminScope(middleware.RequireAdmin(http.HandlerFunc(handlers.AdminTagsHandler)))))
+	mux.Handle("/api/admin/users/", middleware.AuthMiddleware(adminScope(middleware.RequireSuperAdmin(http.HandlerFunc(handlers.AdminUsersHandler)))))
 
 	// Protected routes
-	mux.Handle("/api/topics", middleware.AuthMiddleware(http.HandlerFunc(handlers.TopicHandler)))
-	mux.Handle("/api/topics/", middleware.AuthMiddleware(http.HandlerFunc(handlers.TopicByIDHandler)))
-	mux.Handle("/api/capsules", middleware.AuthMiddleware(http.HandlerFunc(handlers.CapsuleHandler)))
-	mux.Handle("/api/capsules/", middleware.AuthMiddleware(http.HandlerFunc(handlers.CapsuleByIDHandler)))
+	mux.Handle("/api/topics", middleware.AuthMiddleware(topicScope(http.HandlerFunc(handlers.TopicHandler))))
+	mux.Handle("/api/topics/", middleware.AuthMiddleware(topicScope(http.HandlerFunc(handlers.TopicByIDHandler))))
+	mux.Handle("/api/capsules", middleware.AuthMiddleware(capsuleScope(http.HandlerFunc(handlers.CapsuleHandler))))
+	mux.Handle("/api/capsules/", middleware.AuthMiddleware(capsuleScope(http.HandlerFunc(handlers.CapsuleByIDHandler))))
+	mux.Handle("/api/search/suggest", middleware.AuthMiddleware(capsuleScope(http.HandlerFunc(handlers.SearchSuggest))))
+	mux.Handle("/api/tags", middleware.AuthMiddleware(capsuleScope(http.HandlerFunc(handlers.ListTags))))
+	mux.Handle("/api/graph", middleware.AuthMiddleware(capsuleScope(http.HandlerFunc(handlers.GetGraph))))
+	mux.Handle("/api/groups", middleware.AuthMiddleware(capsuleScope(http.HandlerFunc(handlers.GroupsHandler))))
+	mux.Handle("/api/groups/", middleware.AuthMiddleware(capsuleScope(http.HandlerFunc(handlers.GroupsHandler))))
+	// Trash checks token scopes per item type
+	mux.Handle("/api/trash", middleware.AuthMiddleware(http.HandlerFunc(handlers.TrashHandler)))
+	mux.Handle("/api/trash/", middleware.AuthMiddleware(http.HandlerFunc(handlers.TrashHandler)))
 
 	// Chat & File Upload
-	mux.Handle("/ws/chat", middleware.AuthMiddleware(http.HandlerFunc(handlers.ChatWebSocketHandler)))
-	mux.Handle("/api/upload", middleware.AuthMiddleware(http.HandlerFunc(handlers.UploadHandler)))
+	mux.Handle("/ws/chat", middleware.AuthMiddleware(chatScope(http.HandlerFunc(handlers.ChatWebSocketHandler))))
+	mux.Handle("/api/upload", middleware.AuthMiddleware(chatScope(http.HandlerFunc(handlers.UploadHandler))))
 	mux.Handle("/uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir("uploads"))))
 
 	// Wrap with CORS, logger, recover
 	handler := middleware.CORS(cfg.CORSOrigins)(middleware.Recover(middleware.Logger(mux)))
 
-	utils.InitJWTSecret(cfg.JWTSecret)
-
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
 		Handler:      handler,
@@ -3,8 +3,14 @@ package config
 import (
 	"bufio"
 	"fmt"
+	"net/netip"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/oidc"
 )
 
 type Config struct {
@@ -12,10 +18,47 @@ type Config struct {
 	Env                string
 	JWTSecret          string
 	CORSOrigins        []string
+	TrustedProxies     []netip.Prefix
 	DatabaseURL        string
 	SuperAdminEmail    string
 	SuperAdminPassword string
 	SuperAdminName     string
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+	// Access token signing (see utils.JWTSettings)
+	JWTAlgorithm       string
+	JWTPrivateKeyFile  string
+	JWTVerifyKeyFiles  []string
+	JWTPreviousSecrets []string
+	JWTIssuer          string
+	JWTAudience        string
+	// Argon2id password hashing cost
+	PasswordHashMemory      int
+	PasswordHashIterations  int
+	PasswordHashParallelism int
+	// Email delivery
+	AppBaseURL               string
+	Mailer                   string // "smtp" or "file"
+	SMTPHost                 string
+	SMTPPort                 string
+	SMTPUsername             string
+	SMTPPassword             string
+	MailFrom                 string
+	MailDir                  string
+	RequireEmailVerification bool
+	// Brute-force protection
+	LoginAccountPolicy models.ThrottlePolicy
+	LoginIPPolicy      models.ThrottlePolicy
+	RegisterIPPolicy   models.ThrottlePolicy
+	MailPolicy         models.ThrottlePolicy
+	// Trash: how long soft-deleted items are kept and how often the purge runs
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+	// Fuzzy search: minimum pg_trgm word similarity (0-1] for typo-tolerant matches
+	SearchSimilarity float64
+	// Single sign-on
+	OIDCProviders       []oidc.Config
+	OIDCSuccessRedirect string
 }
 
 // loadEnv reads .env file and sets environment variables. Ignores if file does not exist.
@@ -64,23 +107,276 @@ func Load() (Config, error) {
 
 	corsOrigins := parseCORSOrigins(os.Getenv("CORS_ORIGINS"), env)
 
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return Config{}, err
+	}
+
 	databaseURL := os.Getenv("DATABASE_URL")
 	if databaseURL == "" {
 		return Config{}, fmt.Errorf("missing required environment variable: DATABASE_URL")
 	}
 
+	accessTokenTTL, err := parseDuration("ACCESS_TOKEN_TTL", 15*time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
+
+	refreshTokenTTL, err := parseDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	if err != nil {
+		return Config{}, err
+	}
+
+	hashMemory, err := parseInt("PASSWORD_HASH_MEMORY_KB", 64*1024)
+	if err != nil {
+		return Config{}, err
+	}
+
+	hashIterations, err := parseInt("PASSWORD_HASH_ITERATIONS", 3)
+	if err != nil {
+		return Config{}, err
+	}
+
+	hashParallelism, err := parseInt("PASSWORD_HASH_PARALLELISM", 2)
+	if err != nil {
+		return Config{}, err
+	}
+	if hashParallelism > 255 {
+		return Config{}, fmt.Errorf("PASSWORD_HASH_PARALLELISM must be at most 255")
+	}
+
+	mailer := os.Getenv("MAILER")
+	if mailer == "" {
+		mailer = "file"
+	}
+	if mailer != "smtp" && mailer != "file" {
+		return Config{}, fmt.Errorf("invalid MAILER %q: must be smtp or file", mailer)
+	}
+	if mailer == "smtp" && os.Getenv("SMTP_HOST") == "" {
+		return Config{}, fmt.Errorf("missing required environment variable: SMTP_HOST")
+	}
+
+	appBaseURL := getEnv("APP_BASE_URL", "http://localhost:"+port)
+
+	jwtAlgorithm := getEnv("JWT_ALG", "HS256")
+	switch jwtAlgorithm {
+	case "HS256":
+	case "RS256", "EdDSA":
+		if os.Getenv("JWT_PRIVATE_KEY_FILE") == "" {
+			return Config{}, fmt.Errorf("missing required environment variable: JWT_PRIVATE_KEY_FILE")
+		}
+	default:
+		return Config{}, fmt.Errorf("invalid JWT_ALG %q: must be HS256, RS256 or EdDSA", jwtAlgorithm)
+	}
+
+	accountPolicy, ipPolicy, registerPolicy, mailPolicy, err := parseThrottlePolicies()
+	if err != nil {
+		return Config{}, err
+	}
+
+	trashRetention, err := parseDuration("TRASH_RETENTION", 30*24*time.Hour)
+	if err != nil {
+		return Config{}, err
+	}
+
+	trashPurgeInterval, err := parseDuration("TRASH_PURGE_INTERVAL", time.Hour)
+	if err != nil {
+		return Config{}, err
+	}
+
+	searchSimilarity, err := parseFraction("SEARCH_SIMILARITY", 0.4)
+	if err != nil {
+		return Config{}, err
+	}
+
+	oidcProviders, err := parseOIDCProviders(appBaseURL)
+	if err != nil {
+		return Config{}, err
+	}
+
 	return Config{
 		Port:               port,
 		Env:                env,
 		JWTSecret:          jwtSecret,
 		CORSOrigins:        corsOrigins,
+		TrustedProxies:     trustedProxies,
 		DatabaseURL:        databaseURL,
 		SuperAdminEmail:    os.Getenv("SUPERADMIN_EMAIL"),
 		SuperAdminPassword: os.Getenv("SUPERADMIN_PASSWORD"),
 		SuperAdminName:     os.Getenv("SUPERADMIN_NAME"),
+		AccessTokenTTL:     accessTokenTTL,
+		RefreshTokenTTL:    refreshTokenTTL,
+		JWTAlgorithm:       jwtAlgorithm,
+		JWTPrivateKeyFile:  os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		JWTVerifyKeyFiles:  splitList(os.Getenv("JWT_VERIFY_KEY_FILES")),
+		JWTPreviousSecrets: splitList(os.Getenv("JWT_PREVIOUS_SECRETS")),
+		JWTIssuer:          getEnv("JWT_ISSUER", appBaseURL),
+		JWTAudience:        getEnv("JWT_AUDIENCE", "knowledge-capsule"),
+
+		PasswordHashMemory:      hashMemory,
+		PasswordHashIterations:  hashIterations,
+		PasswordHashParallelism: hashParallelism,
+
+		AppBaseURL:               appBaseURL,
+		Mailer:                   mailer,
+		SMTPHost:                 os.Getenv("SMTP_HOST"),
+		SMTPPort:                 getEnv("SMTP_PORT", "587"),
+		SMTPUsername:             os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:             os.Getenv("SMTP_PASSWORD"),
+		MailFrom:                 getEnv("MAIL_FROM", "no-reply@knowledge-capsule.local"),
+		MailDir:                  getEnv("MAIL_DIR", "tmp/mail"),
+		RequireEmailVerification: os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true",
+
+		LoginAccountPolicy: accountPolicy,
+		LoginIPPolicy:      ipPolicy,
+		RegisterIPPolicy:   registerPolicy,
+		MailPolicy:         mailPolicy,
+
+		TrashRetention:     trashRetention,
+		TrashPurgeInterval: trashPurgeInterval,
+
+		SearchSimilarity: searchSimilarity,
+
+		OIDCProviders:       oidcProviders,
+		OIDCSuccessRedirect: os.Getenv("OIDC_SUCCESS_REDIRECT"),
 	}, nil
 }
 
+// parseThrottlePolicies reads the login, registration and email lockout settings. All the
+// policies share the backoff durations and differ only in their attempt limits.
+func parseThrottlePolicies() (account, ip, register, mail models.ThrottlePolicy, err error) {
+	base := models.ThrottlePolicy{}
+	if base.BaseLockout, err = parseDuration("LOCKOUT_BASE", time.Minute); err != nil {
+		return
+	}
+	if base.MaxLockout, err = parseDuration("LOCKOUT_MAX", time.Hour); err != nil {
+		return
+	}
+	if base.Window, err = parseDuration("LOCKOUT_WINDOW", 15*time.Minute); err != nil {
+		return
+	}
+
+	account, ip, register, mail = base, base, base, base
+	if account.MaxFailures, err = parseInt("LOGIN_MAX_FAILURES", 5); err != nil {
+		return
+	}
+	if ip.MaxFailures, err = parseInt("LOGIN_IP_MAX_FAILURES", 20); err != nil {
+		return
+	}
+	if register.MaxFailures, err = parseInt("REGISTER_IP_MAX_ATTEMPTS", 10); err != nil {
+		return
+	}
+	mail.MaxFailures, err = parseInt("MAIL_MAX_REQUESTS", 5)
+	return
+}
+
+// parseOIDCProviders reads OIDC_PROVIDERS (comma-separated names) and the
+// OIDC_<NAME>_* variables for each provider.
+func parseOIDCProviders(appBaseURL string) ([]oidc.Config, error) {
+	var providers []oidc.Config
+	for _, name := range splitList(os.Getenv("OIDC_PROVIDERS")) {
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+		issuer := os.Getenv(prefix + "ISSUER")
+		if issuer == "" {
+			return nil, fmt.Errorf("missing required environment variable: %sISSUER", prefix)
+		}
+		clientID := os.Getenv(prefix + "CLIENT_ID")
+		if clientID == "" {
+			return nil, fmt.Errorf("missing required environment variable: %sCLIENT_ID", prefix)
+		}
+
+		providers = append(providers, oidc.Config{
+			Name:          name,
+			Issuer:        issuer,
+			ClientID:      clientID,
+			ClientSecret:  os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:   getEnv(prefix+"REDIRECT_URL", strings.TrimRight(appBaseURL, "/")+"/api/auth/oidc/"+name+"/callback"),
+			Scopes:        strings.Fields(os.Getenv(prefix + "SCOPES")),
+			GroupsClaim:   os.Getenv(prefix + "GROUPS_CLAIM"),
+			AdminGroups:   splitList(os.Getenv(prefix + "ADMIN_GROUPS")),
+			AutoProvision: os.Getenv(prefix+"AUTO_PROVISION") == "true",
+		})
+	}
+	return providers, nil
+}
+
+// splitList splits a comma-separated value, dropping empty entries.
+func splitList(val string) []string {
+	var out []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// parseTrustedProxies reads a comma-separated list of proxy IPs and CIDR ranges.
+func parseTrustedProxies(val string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range splitList(val) {
+		if addr, err := netip.ParseAddr(item); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: must be an IP or CIDR range", item)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// getEnv returns the env value for key, or def when unset.
+func getEnv(key, def string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return def
+}
+
+// parseInt reads a positive integer from env, falling back to def when unset.
+func parseInt(key string, def int) (int, error) {
+	val := os.Getenv(key)
+	if val == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid integer for environment variable %s: %q", key, val)
+	}
+	return n, nil
+}
+
+// parseFraction reads a number in (0, 1] from env, falling back to def when unset.
+func parseFraction(key string, def float64) (float64, error) {
+	val := os.Getenv(key)
+	if val == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil || f <= 0 || f > 1 {
+		return 0, fmt.Errorf("invalid value for environment variable %s: %q (must be between 0 and 1)", key, val)
+	}
+	return f, nil
+}
+
+// parseDuration reads a Go duration (e.g. "15m", "720h") from env, falling back to def when unset.
+func parseDuration(key string, def time.Duration) (time.Duration, error) {
+	val := os.Getenv(key)
+	if val == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration for environment variable %s: %q", key, val)
+	}
+	return d, nil
+}
+
 func parseCORSOrigins(envVal, goEnv string) []string {
 	if envVal != "" {
 		origins := strings.Split(envVal, ",")
@@ -3,6 +3,9 @@ package contextkeys
 type contextKey string
 
 const (
-	UserContextKey = contextKey("user_id")
-	RoleContextKey = contextKey("user_role")
+	UserContextKey    = contextKey("user_id")
+	RoleContextKey    = contextKey("user_role")
+	SessionContextKey = contextKey("session_id")
+	ScopesContextKey  = contextKey("token_scopes")
+	MFAContextKey     = contextKey("mfa")
 )
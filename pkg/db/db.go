@@ -2,16 +2,30 @@ package db
 
 import (
 	"log/slog"
+	"slices"
+	"strconv"
 
 	"knowledge-capsule/app/models"
+	"knowledge-capsule/pkg/utils"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-// Open connects to PostgreSQL and runs auto-migrations.
-func Open(dsn string) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// Open connects to PostgreSQL and runs auto-migrations. searchSimilarity is the pg_trgm
+// threshold every connection uses for the fuzzy match operators (% and <%).
+func Open(dsn string, searchSimilarity float64) (*gorm.DB, error) {
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	threshold := strconv.FormatFloat(searchSimilarity, 'f', -1, 64)
+	connConfig.RuntimeParams["pg_trgm.similarity_threshold"] = threshold
+	connConfig.RuntimeParams["pg_trgm.word_similarity_threshold"] = threshold
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: stdlib.OpenDB(*connConfig)}), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
@@ -19,12 +33,180 @@ func Open(dsn string) (*gorm.DB, error) {
 	if err := db.AutoMigrate(
 		&models.User{},
 		&models.Topic{},
+		&models.TopicMaintainer{},
 		&models.Capsule{},
+		&models.CapsuleRevision{},
+		&models.Tag{},
+		&models.TagAlias{},
+		&models.Group{},
+		&models.GroupMember{},
+		&models.CapsuleShare{},
+		&models.CapsuleLink{},
+		&models.WikiLink{},
+		&models.CapsuleRender{},
 		&models.Message{},
+		&models.Session{},
+		&models.OneTimeToken{},
+		&models.APIToken{},
+		&models.RecoveryCode{},
+		&models.Setting{},
+		&models.UserIdentity{},
+		&models.AuthThrottle{},
 	); err != nil {
 		return nil, err
 	}
+	if err := migrateCapsuleTopics(db); err != nil {
+		return nil, err
+	}
+	if err := migrateTopicTree(db); err != nil {
+		return nil, err
+	}
+	if err := migrateTags(db); err != nil {
+		return nil, err
+	}
+	if err := migrateWikiLinks(db); err != nil {
+		return nil, err
+	}
+	if err := migrateSearch(db); err != nil {
+		return nil, err
+	}
 
 	slog.Info("Database connected and migrated")
 	return db, nil
 }
+
+// migrateCapsuleTopics links capsules that only carry a topic name to a topic row, creating
+// topics for names that have none, and adds the capsules.topic_id foreign key. Names match
+// active topics case-insensitively; linked capsules take the topic's spelling.
+func migrateCapsuleTopics(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			`INSERT INTO topics (id, name, description, created_at, updated_at)
+			SELECT gen_random_uuid()::text, MIN(c.topic), '', NOW(), NOW() FROM capsules c
+			WHERE c.topic_id IS NULL AND TRIM(c.topic) <> ''
+			AND NOT EXISTS (SELECT 1 FROM topics t WHERE t.deleted_at IS NULL AND LOWER(t.name) = LOWER(c.topic))
+			GROUP BY LOWER(c.topic)`,
+			`UPDATE capsules c SET topic_id = t.id, topic = t.name FROM topics t
+			WHERE c.topic_id IS NULL AND TRIM(c.topic) <> ''
+			AND t.deleted_at IS NULL AND LOWER(t.name) = LOWER(c.topic)`,
+			`DO $$ BEGIN
+				IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_capsules_topic') THEN
+					ALTER TABLE capsules ADD CONSTRAINT fk_capsules_topic FOREIGN KEY (topic_id) REFERENCES topics (id);
+				END IF;
+			END $$`,
+		}
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migrateTopicTree adds the topics.parent_id foreign key. Purging a parent from the trash
+// turns its trashed subtopics into root topics.
+func migrateTopicTree(db *gorm.DB) error {
+	return db.Exec(`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_topics_parent') THEN
+			ALTER TABLE topics ADD CONSTRAINT fk_topics_parent FOREIGN KEY (parent_id) REFERENCES topics (id) ON DELETE SET NULL;
+		END IF;
+	END $$`).Error
+}
+
+// migrateTags rewrites the tags of existing capsules, trashed ones included, as normalized
+// slugs and registers them. It runs while the tag registry is empty.
+func migrateTags(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.Tag{}).Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		seen := make(map[string]bool)
+		var capsules []models.Capsule
+		return tx.Unscoped().Select("id", "tags").Where("jsonb_array_length(tags) > 0").
+			FindInBatches(&capsules, 500, func(_ *gorm.DB, _ int) error {
+				var tags []models.Tag
+				for _, c := range capsules {
+					slugs := models.NormalizeTags(c.Tags)
+					if !slices.Equal(slugs, c.Tags) {
+						if err := tx.Unscoped().Model(&models.Capsule{}).Where("id = ?", c.ID).
+							UpdateColumn("tags", slugs).Error; err != nil {
+							return err
+						}
+					}
+					for _, slug := range slugs {
+						if !seen[slug] {
+							seen[slug] = true
+							tags = append(tags, models.Tag{ID: utils.GenerateUUID(), Slug: slug})
+						}
+					}
+				}
+				if len(tags) == 0 {
+					return nil
+				}
+				return tx.Create(&tags).Error
+			}).Error
+	})
+}
+
+// migrateWikiLinks stores the wiki links of existing capsules while the wiki_links table is
+// empty, resolving them like the capsule store does: to the source owner's active capsule
+// with that ID, else the oldest one with that title.
+func migrateWikiLinks(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.WikiLink{}).Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var capsules []models.Capsule
+		err := tx.Unscoped().Select("id", "content").Where("content LIKE ?", "%[[%").
+			FindInBatches(&capsules, 500, func(_ *gorm.DB, _ int) error {
+				var links []models.WikiLink
+				for _, c := range capsules {
+					for i, target := range models.ParseWikiLinks(c.Content) {
+						links = append(links, models.WikiLink{SourceID: c.ID, Target: target, Position: i})
+					}
+				}
+				if len(links) == 0 {
+					return nil
+				}
+				return tx.Create(&links).Error
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE wiki_links SET target_id = (SELECT c.id FROM capsules c
+			JOIN capsules s ON s.id = wiki_links.source_id
+			WHERE c.user_id = s.user_id AND c.deleted_at IS NULL
+				AND (c.id = wiki_links.target OR LOWER(c.title) = LOWER(wiki_links.target))
+			ORDER BY c.id = wiki_links.target DESC, c.created_at LIMIT 1)`).Error
+	})
+}
+
+// capsuleSearchVector weights title over tags over content for full-text ranking.
+const capsuleSearchVector = `setweight(to_tsvector('english', coalesce(title, '')), 'A') || ` +
+	`setweight(jsonb_to_tsvector('english', coalesce(tags, '[]'::jsonb), '["string"]'), 'B') || ` +
+	`setweight(to_tsvector('english', coalesce(content, '')), 'C')`
+
+// migrateSearch adds the capsules.search_vector column with its GIN index, and trigram
+// indexes for fuzzy matching. The column is generated, so Postgres keeps it current on
+// every insert and update.
+func migrateSearch(db *gorm.DB) error {
+	statements := []string{
+		"ALTER TABLE capsules ADD COLUMN IF NOT EXISTS search_vector tsvector " +
+			"GENERATED ALWAYS AS (" + capsuleSearchVector + ") STORED",
+		"CREATE INDEX IF NOT EXISTS idx_capsules_search_vector ON capsules USING GIN (search_vector)",
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"CREATE INDEX IF NOT EXISTS idx_capsules_title_trgm ON capsules USING GIN (title gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_topics_name_trgm ON topics USING GIN (name gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING GIN (name gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING GIN (email gin_trgm_ops)",
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
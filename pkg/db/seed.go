@@ -2,6 +2,7 @@ package db
 
 import (
 	"errors"
+	"time"
 
 	"knowledge-capsule/app/models"
 	"knowledge-capsule/pkg/logger"
@@ -21,21 +22,27 @@ func SeedSuperAdmin(db *gorm.DB, email, password, name string) error {
 		name = "Super Admin"
 	}
 
-	hash, err := utils.HashPassword(password)
-	if err != nil {
-		logger.Error(logger.EventSeed, err, logger.Attr("action", "hash_password"), logger.Attr("email", email))
-		return err
-	}
-
 	var existing models.User
-	err = db.Where("email = ?", email).First(&existing).Error
+	err := db.Where("email = ?", email).First(&existing).Error
 	if err == nil {
-		// Update existing user to superadmin
-		if err := db.Model(&existing).Updates(map[string]interface{}{
-			"name":          name,
-			"password_hash": hash,
-			"role":          models.RoleSuperAdmin,
-		}).Error; err != nil {
+		// Update existing user to superadmin; keep the stored hash unless the password
+		// changed or the hash uses a legacy format or outdated cost.
+		updates := map[string]interface{}{
+			"name": name,
+			"role": models.RoleSuperAdmin,
+		}
+		if existing.EmailVerifiedAt == nil {
+			updates["email_verified_at"] = time.Now()
+		}
+		if !utils.CheckPassword(password, existing.PasswordHash) || utils.NeedsRehash(existing.PasswordHash) {
+			hash, err := utils.HashPassword(password)
+			if err != nil {
+				logger.Error(logger.EventSeed, err, logger.Attr("action", "hash_password"), logger.Attr("email", email))
+				return err
+			}
+			updates["password_hash"] = hash
+		}
+		if err := db.Model(&existing).Updates(updates).Error; err != nil {
 			logger.Error(logger.EventSeed, err, logger.Attr("action", "update_superadmin"), logger.Attr("email", email))
 			return err
 		}
@@ -49,12 +56,19 @@ func SeedSuperAdmin(db *gorm.DB, email, password, name string) error {
 	}
 
 	// Create new superadmin
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		logger.Error(logger.EventSeed, err, logger.Attr("action", "hash_password"), logger.Attr("email", email))
+		return err
+	}
+	now := time.Now()
 	user := models.User{
-		ID:           utils.GenerateUUID(),
-		Name:         name,
-		Email:        email,
-		PasswordHash: hash,
-		Role:         models.RoleSuperAdmin,
+		ID:              utils.GenerateUUID(),
+		Name:            name,
+		Email:           email,
+		PasswordHash:    hash,
+		Role:            models.RoleSuperAdmin,
+		EmailVerifiedAt: &now,
 	}
 	if err := db.Create(&user).Error; err != nil {
 		logger.Error(logger.EventSeed, err, logger.Attr("action", "create_superadmin"), logger.Attr("email", email))
@@ -11,18 +11,20 @@ import (
 
 // Event types for structured logging
 const (
-	EventRequest   = "request"
-	EventAuth      = "auth"
-	EventUser      = "user"
-	EventCapsule   = "capsule"
-	EventTopic     = "topic"
-	EventAdmin     = "admin"
-	EventSearch    = "search"
-	EventUpload    = "upload"
-	EventChat      = "chat"
-	EventSeed      = "seed"
-	EventError     = "error"
-	EventPanic    = "panic"
+	EventRequest = "request"
+	EventAuth    = "auth"
+	EventUser    = "user"
+	EventCapsule = "capsule"
+	EventTopic   = "topic"
+	EventTrash   = "trash"
+	EventAdmin   = "admin"
+	EventSearch  = "search"
+	EventUpload  = "upload"
+	EventChat    = "chat"
+	EventSeed    = "seed"
+	EventMail    = "mail"
+	EventError   = "error"
+	EventPanic   = "panic"
 )
 
 // FromRequest extracts common request context for logging.
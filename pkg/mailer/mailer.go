@@ -0,0 +1,110 @@
+package mailer
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"knowledge-capsule/pkg/logger"
+)
+
+// Mailer sends plain-text emails.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPMailer sends mail through an SMTP server using PLAIN auth when a username is set.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// NewSMTPMailer returns a Mailer that delivers through host:port.
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{Host: host, Port: port, Username: username, Password: password, From: from}
+}
+
+// Send delivers the message over SMTP.
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	msg := buildMessage(m.From, to, subject, body)
+	if err := smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{to}, msg); err != nil {
+		return err
+	}
+	logger.Info(logger.EventMail, logger.Attr("action", "sent"), logger.Attr("to", to), logger.Attr("subject", subject))
+	return nil
+}
+
+// FileMailer writes each message as an .eml file into Dir instead of sending it.
+// Useful in development and tests; the latest message for an address can be read back with LastMessage.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// NewFileMailer returns a Mailer that stores messages in dir.
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{Dir: dir, From: from}
+}
+
+// Send writes the message to disk and logs it.
+func (m *FileMailer) Send(to, subject, body string) error {
+	if err := os.MkdirAll(m.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), sanitizeFilename(to))
+	path := filepath.Join(m.Dir, name)
+	if err := os.WriteFile(path, buildMessage(m.From, to, subject, body), 0o600); err != nil {
+		return err
+	}
+	logger.Info(logger.EventMail, logger.Attr("action", "written"), logger.Attr("to", to), logger.Attr("subject", subject), logger.Attr("path", path))
+	return nil
+}
+
+// LastMessage returns the most recent message written for to, or "" if none.
+func (m *FileMailer) LastMessage(to string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(m.Dir, "*_"+sanitizeFilename(to)+".eml"))
+	if err != nil || len(matches) == 0 {
+		return "", err
+	}
+	// Names start with a nanosecond timestamp, so lexical order is chronological.
+	latest := matches[0]
+	for _, p := range matches[1:] {
+		if p > latest {
+			latest = p
+		}
+	}
+	b, err := os.ReadFile(latest)
+	return string(b), err
+}
+
+func buildMessage(from, to, subject, body string) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func sanitizeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,211 @@
+package markdown
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"knowledge-capsule/app/models"
+)
+
+// Archive size bounds against zip bombs: the largest note, the same as the largest capsule
+// content, and the most bytes the archives of one import may unpack to.
+const (
+	MaxNoteBytes    = 1 << 20
+	MaxArchiveBytes = 100 << 20
+)
+
+// ErrArchiveTooLarge is returned when archives unpack to more than their budget.
+var ErrArchiveTooLarge = errors.New("archives unpack to more than 100 MB")
+
+// maxFileNameLen is the most characters of a title kept in an exported file name.
+const maxFileNameLen = 100
+
+// WriteArchive writes capsules as a zip of Markdown files with front matter, one folder per
+// topic. Files are named after the title, so [[Title]] links also work in Obsidian.
+func WriteArchive(w io.Writer, capsules []models.CapsuleExport) error {
+	zw := zip.NewWriter(w)
+	used := make(map[string]bool, len(capsules))
+	for _, c := range capsules {
+		name := fileName(c.Title)
+		dir := ""
+		if c.Topic != "" {
+			dir = fileName(c.Topic) + "/"
+		}
+		file := dir + name + ".md"
+		for n := 2; used[strings.ToLower(file)]; n++ {
+			file = fmt.Sprintf("%s%s %d.md", dir, name, n)
+		}
+		used[strings.ToLower(file)] = true
+
+		doc, err := EncodeDocument(c)
+		if err != nil {
+			return err
+		}
+		header := &zip.FileHeader{Name: file, Method: zip.Deflate}
+		if c.UpdatedAt != nil {
+			header.Modified = *c.UpdatedAt
+		}
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(doc); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// fileName makes a title safe to use as a file name on common file systems.
+func fileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || unicode.IsControl(r) {
+			return '-'
+		}
+		return r
+	}, title)
+	if runes := []rune(name); len(runes) > maxFileNameLen {
+		name = string(runes[:maxFileNameLen])
+	}
+	name = strings.Trim(name, " .")
+	if name == "" {
+		return "untitled"
+	}
+	return name
+}
+
+// ReadArchive reads the Markdown notes of a zip archive, as written by WriteArchive or
+// zipped from an Obsidian vault when obsidian is set. Hidden files and folders (like
+// .obsidian), macOS metadata and files other than notes are left out. When every note is
+// in the same top-level folder, that folder is dropped from the paths. The notes read are
+// taken out of budget, the bytes left to unpack, shared by all archives of an import.
+func ReadArchive(data []byte, obsidian bool, budget *int64) ([]models.ImportItem, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+	var files []*zip.File
+	for _, f := range zr.File {
+		if isNote(f.Name) && !f.FileInfo().IsDir() {
+			files = append(files, f)
+		}
+	}
+	root := commonRoot(files)
+
+	items := make([]models.ImportItem, 0, len(files))
+	for _, f := range files {
+		name := strings.TrimPrefix(f.Name, root)
+		rc, err := f.Open()
+		if err != nil {
+			items = append(items, models.ImportItem{Source: name, Err: err})
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, MaxNoteBytes+1))
+		rc.Close()
+		if err != nil {
+			items = append(items, models.ImportItem{Source: name, Err: err})
+			continue
+		}
+		if len(data) > MaxNoteBytes {
+			items = append(items, models.ImportItem{Source: name, Err: errors.New("note is larger than 1 MB")})
+			continue
+		}
+		if *budget -= int64(len(data)); *budget < 0 {
+			return nil, ErrArchiveTooLarge
+		}
+		items = append(items, ReadNote(name, data, obsidian))
+	}
+	return items, nil
+}
+
+// isNote reports whether an archive path is a Markdown note outside hidden folders.
+func isNote(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return false
+		}
+	}
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// commonRoot returns "dir/" when every file is inside the same top-level folder.
+func commonRoot(files []*zip.File) string {
+	root := ""
+	for i, f := range files {
+		dir, _, ok := strings.Cut(f.Name, "/")
+		if !ok || (i > 0 && dir != root) {
+			return ""
+		}
+		root = dir
+	}
+	if root == "" {
+		return ""
+	}
+	return root + "/"
+}
+
+// ReadNote reads one Markdown note. The title defaults to the file name and the topic to the
+// folder it is in; the format defaults to markdown. Obsidian notes also get their inline
+// #tags, and links to headings, blocks or paths ([[Folder/Note#Heading|label]]) are reduced to
+// links to the note ([[Note|label]]).
+func ReadNote(name string, data []byte, obsidian bool) models.ImportItem {
+	c, err := DecodeDocument(data)
+	if err != nil {
+		return models.ImportItem{Source: name, Err: err}
+	}
+	if c.Title == "" {
+		c.Title = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+	if dir := path.Dir(name); c.Topic == "" && dir != "." {
+		c.Topic = path.Base(dir)
+	}
+	if c.ContentFormat == "" {
+		c.ContentFormat = models.ContentMarkdown
+	}
+	if obsidian {
+		c.Tags = append(c.Tags, inlineTags(c.Content)...)
+		c.Content = obsidianLinks.ReplaceAllStringFunc(c.Content, obsidianLink)
+	}
+	return models.ImportItem{Source: name, Capsule: c}
+}
+
+var (
+	// codeSpans are fenced code blocks and inline code, where # is not a tag.
+	codeSpans = regexp.MustCompile("(?s)```.*?```|~~~.*?~~~|`[^`\n]*`")
+	// inlineTag is an Obsidian #tag: it starts a word and has a letter or _ before any digit.
+	inlineTag = regexp.MustCompile(`(?:^|\s)#([\p{L}_][\p{L}\p{N}_/-]*)`)
+	// obsidianLinks are wiki links with a path, heading or block reference.
+	obsidianLinks = regexp.MustCompile(`\[\[([^\[\]|#^]*(?:/[^\[\]|#^]*|[#^][^\[\]|]*))(\|[^\[\]]*)?\]\]`)
+)
+
+// inlineTags returns the #tags in note text, outside code.
+func inlineTags(content string) models.Tags {
+	tags := models.Tags{}
+	for _, m := range inlineTag.FindAllStringSubmatch(codeSpans.ReplaceAllString(content, " "), -1) {
+		tags = append(tags, m[1])
+	}
+	return tags
+}
+
+// obsidianLink rewrites [[Folder/Note.md#Heading|label]] to [[Note|label]]. Links within the
+// note itself ([[#Heading]]) are kept as they are.
+func obsidianLink(link string) string {
+	m := obsidianLinks.FindStringSubmatch(link)
+	target := m[1]
+	if i := strings.IndexAny(target, "#^"); i >= 0 {
+		target = target[:i]
+	}
+	target = strings.TrimSuffix(path.Base(strings.TrimSpace(target)), ".md")
+	if target == "" || target == "." || target == "/" {
+		return link
+	}
+	return "[[" + target + m[2] + "]]"
+}
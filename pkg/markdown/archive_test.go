@@ -0,0 +1,67 @@
+package markdown
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// zipNotes returns a zip archive of the named notes.
+func zipNotes(t *testing.T, notes map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range notes {
+		fw, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadArchiveNoteLimit(t *testing.T) {
+	data := zipNotes(t, map[string]string{
+		"big.md":     strings.Repeat("a", MaxNoteBytes+1),
+		"fits.md":    strings.Repeat("a", MaxNoteBytes),
+		"skip.txt":   "not a note",
+		".hidden.md": "hidden",
+	})
+	budget := int64(MaxArchiveBytes)
+	items, err := ReadArchive(data, false, &budget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("ReadArchive() read %d items, want the two notes", len(items))
+	}
+	for _, item := range items {
+		if failed := item.Err != nil; failed != (item.Source == "big.md") {
+			t.Errorf("%s: error = %v", item.Source, item.Err)
+		}
+	}
+	if want := int64(MaxArchiveBytes - MaxNoteBytes); budget != want {
+		t.Errorf("budget left = %d, want %d (only the note read)", budget, want)
+	}
+}
+
+func TestReadArchiveSharesBudget(t *testing.T) {
+	data := zipNotes(t, map[string]string{"a.md": strings.Repeat("a", 600), "b.md": strings.Repeat("b", 600)})
+	budget := int64(2000)
+	if _, err := ReadArchive(data, false, &budget); err != nil {
+		t.Fatalf("first archive: error = %v", err)
+	}
+	if budget != 800 {
+		t.Errorf("budget left = %d, want 800", budget)
+	}
+	// The second archive alone is within the budget, but not together with the first.
+	if _, err := ReadArchive(data, false, &budget); !errors.Is(err, ErrArchiveTooLarge) {
+		t.Errorf("second archive: error = %v, want ErrArchiveTooLarge", err)
+	}
+}
@@ -0,0 +1,45 @@
+package markdown
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// languageRules are checked in order; the first language whose pattern matches the code wins.
+// More specific languages come before those they resemble (TypeScript before JavaScript).
+var languageRules = []struct {
+	lang    string
+	pattern *regexp.Regexp
+}{
+	{"go", regexp.MustCompile(`(?m)^package \w+$|\bfunc (\(\w+ \*?\w+\) )?\w+\(|:= |\bchan \w+|\bgo func\(`)},
+	{"rust", regexp.MustCompile(`\bfn \w+\(|\blet mut \w+|\bimpl\b.*\{|println!\(`)},
+	{"python", regexp.MustCompile(`(?m)^\s*def \w+\(.*\):\s*$|^\s*(from \w+(\.\w+)* )?import \w+$|^\s*class \w+(\(.*\))?:\s*$|\bprint\(|\belif\b`)},
+	{"typescript", regexp.MustCompile(`\binterface \w+ \{|:\s*(string|number|boolean)\b[;,)=]|\btype \w+ = `)},
+	{"javascript", regexp.MustCompile(`\b(const|let|var) \w+ = |\bfunction\s*\w*\(|=> |console\.log\(|\brequire\(`)},
+	{"java", regexp.MustCompile(`\bpublic (static )?(class|void|final)\b|System\.out\.println`)},
+	{"c", regexp.MustCompile(`(?m)^#include\s*[<"]|\bint main\(`)},
+	{"sql", regexp.MustCompile(`(?i)^\s*(SELECT\s.+\sFROM\s|INSERT INTO\s|UPDATE \w+ SET\s|DELETE FROM\s|CREATE (TABLE|INDEX)\s|ALTER TABLE\s)`)},
+	{"html", regexp.MustCompile(`(?i)^\s*(<!DOCTYPE html|<html|<div|<p>|<span|<a href)`)},
+	{"css", regexp.MustCompile(`(?m)^[.#]?[\w-]+(\s*[.#]?[\w-]+)*\s*\{\s*$|^\s*[\w-]+:\s*[^;]+;\s*$`)},
+	{"bash", regexp.MustCompile(`(?m)^#!/bin/(ba)?sh|^\s*\$ \w+|^\s*(sudo|apt(-get)?|brew|npm|go|git|docker|kubectl|curl|export|echo) `)},
+	{"yaml", regexp.MustCompile(`(?m)\A(---\s*\n)?(\s*[\w.-]+:( .*)?\n)+\s*[\w.-]+:( .*)?\s*\z`)},
+}
+
+// DetectLanguage guesses the language of a code block from its content. It returns "" when
+// no rule matches.
+func DetectLanguage(code string) string {
+	trimmed := strings.TrimSpace(code)
+	if trimmed == "" {
+		return ""
+	}
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "json"
+	}
+	for _, rule := range languageRules {
+		if rule.pattern.MatchString(code) {
+			return rule.lang
+		}
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"knowledge-capsule/app/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterFence opens and closes the YAML front matter of a Markdown file.
+const frontMatterFence = "---"
+
+// frontMatter is the front matter as read from a file. Tags and timestamps are loose so notes
+// written by hand or by other tools (tags as a string, dates without a time) still import.
+type frontMatter struct {
+	Title         string      `yaml:"title"`
+	Topic         string      `yaml:"topic"`
+	Tags          interface{} `yaml:"tags"`
+	IsPrivate     bool        `yaml:"is_private"`
+	ContentFormat string      `yaml:"content_format"`
+	CreatedAt     interface{} `yaml:"created_at"`
+	UpdatedAt     interface{} `yaml:"updated_at"`
+}
+
+// EncodeDocument writes a capsule as a Markdown file: YAML front matter with its title, topic,
+// tags, privacy, format and timestamps, followed by its content.
+func EncodeDocument(c models.CapsuleExport) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(frontMatterFence + "\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString(frontMatterFence + "\n")
+	buf.WriteString(c.Content)
+	return buf.Bytes(), nil
+}
+
+// DecodeDocument reads a Markdown file with optional YAML front matter. Fields missing from
+// the front matter are left empty; the content is what follows it, without leading blank lines.
+func DecodeDocument(data []byte) (models.CapsuleExport, error) {
+	text := strings.TrimPrefix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\ufeff")
+	var c models.CapsuleExport
+	if !strings.HasPrefix(text, frontMatterFence+"\n") {
+		c.Content = strings.TrimLeft(text, "\n")
+		return c, nil
+	}
+	rest := text[len(frontMatterFence)+1:]
+	var head, body string
+	switch {
+	case strings.HasPrefix(rest, frontMatterFence+"\n") || rest == frontMatterFence:
+		body = strings.TrimPrefix(rest, frontMatterFence)
+	default:
+		end := strings.Index(rest, "\n"+frontMatterFence+"\n")
+		if end < 0 {
+			if !strings.HasSuffix(rest, "\n"+frontMatterFence) {
+				return c, errors.New("front matter is not closed")
+			}
+			end = len(rest) - len(frontMatterFence) - 1
+		}
+		head, body = rest[:end], rest[end+len(frontMatterFence)+1:]
+	}
+
+	var fm frontMatter
+	if err := yaml.Unmarshal([]byte(head), &fm); err != nil {
+		return c, fmt.Errorf("invalid front matter: %w", err)
+	}
+	c.Title = strings.TrimSpace(fm.Title)
+	c.Topic = strings.TrimSpace(fm.Topic)
+	c.Tags = frontMatterTags(fm.Tags)
+	c.IsPrivate = fm.IsPrivate
+	c.ContentFormat = fm.ContentFormat
+	c.CreatedAt = frontMatterTime(fm.CreatedAt)
+	c.UpdatedAt = frontMatterTime(fm.UpdatedAt)
+	c.Content = strings.TrimLeft(body, "\n")
+	return c, nil
+}
+
+// frontMatterTags reads tags given as a list or as one string separated by commas or spaces,
+// with or without a leading #.
+func frontMatterTags(v interface{}) models.Tags {
+	var raw []string
+	switch t := v.(type) {
+	case string:
+		raw = strings.FieldsFunc(t, func(r rune) bool { return r == ',' || r == ' ' })
+	case []interface{}:
+		for _, item := range t {
+			if item != nil {
+				raw = append(raw, fmt.Sprint(item))
+			}
+		}
+	}
+	tags := models.Tags{}
+	for _, tag := range raw {
+		if tag = strings.TrimPrefix(strings.TrimSpace(tag), "#"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// frontMatterTime reads a timestamp or date, ignoring values that are neither.
+func frontMatterTime(v interface{}) *time.Time {
+	switch t := v.(type) {
+	case time.Time:
+		return &t
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+			if parsed, err := time.Parse(layout, strings.TrimSpace(t)); err == nil {
+				return &parsed
+			}
+		}
+	}
+	return nil
+}
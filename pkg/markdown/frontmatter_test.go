@@ -0,0 +1,121 @@
+package markdown
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"knowledge-capsule/app/models"
+)
+
+func TestDecodeDocument(t *testing.T) {
+	created := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		doc     string
+		want    models.CapsuleExport
+		wantErr bool
+	}{
+		{"no front matter", "\n\n# Heading\nbody", models.CapsuleExport{Content: "# Heading\nbody"}, false},
+		{"all fields", "---\ntitle: Interfaces\ntopic: Golang\ntags: [go, types]\nis_private: true\ncontent_format: markdown\ncreated_at: 2026-03-01T09:30:00Z\n---\n\nbody\n",
+			models.CapsuleExport{Title: "Interfaces", Topic: "Golang", Tags: models.Tags{"go", "types"}, IsPrivate: true,
+				ContentFormat: "markdown", CreatedAt: &created, Content: "body\n"}, false},
+		{"crlf and bom", "\ufeff---\r\ntitle: Windows\r\n---\r\nbody", models.CapsuleExport{Title: "Windows", Content: "body"}, false},
+		{"empty front matter", "---\n---\nbody", models.CapsuleExport{Content: "body"}, false},
+		{"closed at end of file", "---\ntitle: Only\n---", models.CapsuleExport{Title: "Only"}, false},
+		{"tags as string", "---\ntags: \"#go, web  api\"\n---\n", models.CapsuleExport{Tags: models.Tags{"go", "web", "api"}}, false},
+		{"date only", "---\ncreated_at: 2026-03-01\n---\n", models.CapsuleExport{CreatedAt: ptr(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))}, false},
+		{"unreadable date ignored", "---\ncreated_at: someday\n---\n", models.CapsuleExport{}, false},
+		{"title trimmed", "---\ntitle: \"  Spaced  \"\n---\n", models.CapsuleExport{Title: "Spaced"}, false},
+		{"fence inside content", "---\ntitle: T\n---\nabove\n---\nbelow", models.CapsuleExport{Title: "T", Content: "above\n---\nbelow"}, false},
+		{"not closed", "---\ntitle: Open\nbody", models.CapsuleExport{}, true},
+		{"invalid yaml", "---\ntitle: [unclosed\n---\n", models.CapsuleExport{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeDocument([]byte(tt.doc))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeDocument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !sameExport(got, tt.want) {
+				t.Errorf("DecodeDocument() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeDocumentRoundTrip(t *testing.T) {
+	created := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	updated := created.Add(48 * time.Hour)
+	tests := []models.CapsuleExport{
+		{Title: "Interfaces", Topic: "Golang", Tags: models.Tags{"go", "types"}, IsPrivate: true, ContentFormat: "markdown",
+			CreatedAt: &created, UpdatedAt: &updated, Content: "# Interfaces\n\n---\n\nbody\n"},
+		{Title: "title: with colon", ContentFormat: "plain", Content: "plain text"},
+		{Title: "Empty", ContentFormat: "plain"},
+	}
+	for _, want := range tests {
+		t.Run(want.Title, func(t *testing.T) {
+			doc, err := EncodeDocument(want)
+			if err != nil {
+				t.Fatalf("EncodeDocument() error = %v", err)
+			}
+			got, err := DecodeDocument(doc)
+			if err != nil {
+				t.Fatalf("DecodeDocument() error = %v\n%s", err, doc)
+			}
+			if !sameExport(got, want) {
+				t.Errorf("round trip = %+v, want %+v\n%s", got, want, doc)
+			}
+		})
+	}
+}
+
+func TestReadNote(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		doc       string
+		obsidian  bool
+		wantTitle string
+		wantTopic string
+		wantTags  models.Tags
+		wantBody  string
+	}{
+		{"defaults from path", "Golang/Interfaces.md", "body", false, "Interfaces", "Golang", nil, "body"},
+		{"front matter wins", "Golang/file.md", "---\ntitle: Real\ntopic: Go\n---\nbody", false, "Real", "Go", nil, "body"},
+		{"obsidian tags and links", "Note.md", "#idea see [[Folder/Other.md#Part|label]] `#code`", true,
+			"Note", "", models.Tags{"idea"}, "#idea see [[Other|label]] `#code`"},
+		{"obsidian self link kept", "Note.md", "[[#Heading]]", true, "Note", "", models.Tags{}, "[[#Heading]]"},
+		{"plain markdown keeps links", "Note.md", "[[Folder/Other]]", false, "Note", "", nil, "[[Folder/Other]]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := ReadNote(tt.path, []byte(tt.doc), tt.obsidian)
+			if item.Err != nil {
+				t.Fatalf("ReadNote() error = %v", item.Err)
+			}
+			c := item.Capsule
+			if c.Title != tt.wantTitle || c.Topic != tt.wantTopic || !slices.Equal(c.Tags, tt.wantTags) || c.Content != tt.wantBody {
+				t.Errorf("ReadNote() = %+v, want title %q, topic %q, tags %q, content %q", c, tt.wantTitle, tt.wantTopic, tt.wantTags, tt.wantBody)
+			}
+			if c.ContentFormat != models.ContentMarkdown {
+				t.Errorf("ReadNote() content_format = %q, want markdown", c.ContentFormat)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// sameExport compares decoded capsules, treating nil and empty tags alike.
+func sameExport(a, b models.CapsuleExport) bool {
+	return a.Title == b.Title && a.Topic == b.Topic && slices.Equal(a.Tags, b.Tags) && a.IsPrivate == b.IsPrivate &&
+		a.ContentFormat == b.ContentFormat && a.Content == b.Content && sameTime(a.CreatedAt, b.CreatedAt) && sameTime(a.UpdatedAt, b.UpdatedAt)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
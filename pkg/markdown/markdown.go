@@ -0,0 +1,194 @@
+// Package markdown renders capsule content to sanitized HTML and reads and writes capsules
+// as Markdown files with YAML front matter.
+package markdown
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	stdhtml "html"
+	"regexp"
+	"strings"
+
+	"knowledge-capsule/app/models"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// version changes whenever rendering output changes, so cached renders are redone.
+const version = "1"
+
+// languageAttr carries a code block's language from the AST walk to codeRenderer.
+const languageAttr = "data-language"
+
+// md parses GitHub Flavored Markdown and passes raw HTML through to the sanitizer.
+var md = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	goldmark.WithRendererOptions(
+		html.WithUnsafe(),
+		renderer.WithNodeRenderers(util.Prioritized(codeRenderer{}, 100)),
+	),
+)
+
+// policy is the HTML allowlist: user-generated content elements, heading anchors, code
+// block languages and task list checkboxes. Links get rel="nofollow noopener".
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[a-z0-9+#.-]+$`)).OnElements("code")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}()
+
+// Hash identifies content in a format as rendered by this version of the renderer.
+func Hash(format, content string) string {
+	sum := sha256.Sum256([]byte(version + "\x00" + format + "\x00" + content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Render renders content in a format to sanitized HTML. Markdown gets a table of contents
+// from its headings and a language for each code block, from its info string or detected.
+// Plain text is escaped, with blank lines separating paragraphs.
+func Render(format, content string) (*models.RenderedContent, error) {
+	out := &models.RenderedContent{TOC: []models.TOCEntry{}, CodeLanguages: []string{}}
+	if format != models.ContentMarkdown {
+		out.HTML = renderPlain(content)
+		return out, nil
+	}
+
+	source := []byte(content)
+	doc := md.Parser().Parse(text.NewReader(source))
+	seen := make(map[string]bool)
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Heading:
+			entry := models.TOCEntry{Level: node.Level, Text: strings.TrimSpace(plainText(node, source))}
+			if id, ok := node.AttributeString("id"); ok {
+				if b, ok := id.([]byte); ok {
+					entry.ID = string(b)
+				}
+			}
+			out.TOC = append(out.TOC, entry)
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			lang := codeLanguage(n, source)
+			if lang == "" {
+				return ast.WalkSkipChildren, nil
+			}
+			n.SetAttributeString(languageAttr, []byte(lang))
+			if !seen[lang] {
+				seen[lang] = true
+				out.CodeLanguages = append(out.CodeLanguages, lang)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, source, doc); err != nil {
+		return nil, err
+	}
+	out.HTML = policy.Sanitize(buf.String())
+	return out, nil
+}
+
+// renderPlain escapes plain text into paragraphs, keeping its line breaks.
+func renderPlain(content string) string {
+	var b strings.Builder
+	for _, para := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n") {
+		para = strings.Trim(para, "\n")
+		if strings.TrimSpace(para) == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(stdhtml.EscapeString(para), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+// plainText returns the text of an inline tree, without markup.
+func plainText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch t := c.(type) {
+		case *ast.Text:
+			b.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(t.Value)
+		default:
+			b.WriteString(plainText(c, source))
+		}
+	}
+	return b.String()
+}
+
+// infoLanguage keeps the characters of a language name that the sanitizer allows.
+var infoLanguage = regexp.MustCompile(`[^a-z0-9+#.-]`)
+
+// codeLanguage returns a code block's language: the first word of a fenced block's info
+// string, or else one detected from the code.
+func codeLanguage(n ast.Node, source []byte) string {
+	if fenced, ok := n.(*ast.FencedCodeBlock); ok {
+		if lang := infoLanguage.ReplaceAllString(strings.ToLower(string(fenced.Language(source))), ""); lang != "" {
+			return lang
+		}
+	}
+	var code strings.Builder
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(source))
+	}
+	return DetectLanguage(code.String())
+}
+
+// codeRenderer renders code blocks as <pre><code class="language-x"> with the language set by
+// Render, escaping the code.
+type codeRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (r codeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderCode)
+	reg.Register(ast.KindCodeBlock, r.renderCode)
+}
+
+func (codeRenderer) renderCode(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</code></pre>\n")
+		return ast.WalkContinue, nil
+	}
+	_, _ = w.WriteString("<pre><code")
+	if lang, ok := n.AttributeString(languageAttr); ok {
+		_, _ = w.WriteString(` class="language-`)
+		_, _ = w.Write(lang.([]byte))
+		_, _ = w.WriteString(`"`)
+	}
+	_ = w.WriteByte('>')
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		html.DefaultWriter.RawWrite(w, line.Value(source))
+	}
+	return ast.WalkContinue, nil
+}
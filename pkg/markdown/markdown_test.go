@@ -0,0 +1,101 @@
+package markdown
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"knowledge-capsule/app/models"
+)
+
+func TestRenderSanitizes(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		banned  []string
+		want    []string
+	}{
+		{"script", "before\n\n<script>alert(1)</script>\n\nafter", []string{"<script", "alert(1)"}, []string{"before", "after"}},
+		{"inline script", "text <script>alert(1)</script> text", []string{"<script"}, nil},
+		{"img onerror", `<img src="x.png" onerror="alert(1)">`, []string{"onerror", "alert"}, []string{`<img src="x.png"`}},
+		{"javascript link", "[x](javascript:alert(1))", []string{"javascript:", `href=`}, []string{"x"}},
+		{"javascript autolink", "<javascript:alert(1)>", []string{`href="javascript:`}, nil},
+		{"data href", `<a href="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">x</a>`, []string{"data:", "href="}, []string{"x"}},
+		{"style and iframe", `<iframe src="https://evil.example"></iframe><p style="color:red">x</p>`, []string{"<iframe", "style="}, nil},
+		{"links get nofollow", "[x](https://example.com)", nil, []string{`href="https://example.com"`, `rel="nofollow noopener"`, `target="_blank"`}},
+		{"hostile fence info", "```\"><script>alert(1)</script>\nx := 1\n```", []string{"<script", "alert(1)", `"><`}, []string{`<code class="language-scriptalert1script">`}},
+		{"fence info with quotes", "```go\" onclick=\"alert(1)\nx := 1\n```", []string{"onclick"}, []string{`<code class="language-go">`}},
+		{"code escaped", "```html\n<script>alert(1)</script>\n```", []string{"<script"}, []string{"&lt;script&gt;"}},
+		{"task list", "- [x] done\n- [ ] todo", []string{"<script"}, []string{`<input checked="" disabled="" type="checkbox"`, `<input disabled="" type="checkbox"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(models.ContentMarkdown, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.banned {
+				if strings.Contains(got.HTML, s) {
+					t.Errorf("HTML contains %q:\n%s", s, got.HTML)
+				}
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(got.HTML, s) {
+					t.Errorf("HTML lacks %q:\n%s", s, got.HTML)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderTOC(t *testing.T) {
+	got, err := Render(models.ContentMarkdown, "# Intro\n\ntext\n\n## Set *up*\n\n## Set up\n\n### `code` & more\n\n# <script>x</script>Tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.TOCEntry{
+		{Level: 1, ID: "intro", Text: "Intro"},
+		{Level: 2, ID: "set-up", Text: "Set up"},
+		{Level: 2, ID: "set-up-1", Text: "Set up"},
+		{Level: 3, ID: "code--more", Text: "code & more"},
+	}
+	if len(got.TOC) != len(want)+1 || !slices.Equal(got.TOC[:len(want)], want) {
+		t.Fatalf("TOC = %+v, want %+v and the tail heading", got.TOC, want)
+	}
+	for _, e := range want {
+		if !strings.Contains(got.HTML, `id="`+e.ID+`"`) {
+			t.Errorf("HTML lacks heading id %s:\n%s", e.ID, got.HTML)
+		}
+	}
+	if strings.Contains(got.HTML, "<script") {
+		t.Errorf("HTML contains a script from a heading:\n%s", got.HTML)
+	}
+}
+
+func TestRenderCodeLanguages(t *testing.T) {
+	content := "```Go\nfmt.Println()\n```\n\n```python extra words\nprint(1)\n```\n\n" +
+		"```\npackage main\n\nfunc main() {}\n```\n\n```\njust words\n```\n\n    indented := 1\n"
+	got, err := Render(models.ContentMarkdown, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go", "python"}; !slices.Equal(got.CodeLanguages, want) {
+		t.Errorf("CodeLanguages = %v, want %v", got.CodeLanguages, want)
+	}
+	for _, s := range []string{`<code class="language-go">fmt.Println()`, `<code class="language-python">`, `<code class="language-go">package main`, "<code>just words", `<code class="language-go">indented`} {
+		if !strings.Contains(got.HTML, s) {
+			t.Errorf("HTML lacks %q:\n%s", s, got.HTML)
+		}
+	}
+}
+
+func TestRenderPlain(t *testing.T) {
+	got, err := Render(models.ContentPlain, "line <b>one</b>\nline two\r\n\r\n\n\nsecond")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<p>line &lt;b&gt;one&lt;/b&gt;<br>\nline two</p>\n<p>second</p>\n"
+	if got.HTML != want || len(got.TOC) != 0 || len(got.CodeLanguages) != 0 {
+		t.Errorf("Render(plain) = %+v, want HTML %q", got, want)
+	}
+}
@@ -0,0 +1,271 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID = "kc-client"
+	testNonce    = "nonce-1"
+)
+
+// testIssuer is an OIDC issuer serving discovery, a JWKS with one RSA and one EC key, and a
+// token endpoint that returns idToken for the code "good-code".
+type testIssuer struct {
+	*httptest.Server
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	idToken string
+	form    map[string]string
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := &testIssuer{rsaKey: rsaKey, ecKey: ecKey}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 iss.URL,
+			"authorization_endpoint": iss.URL + "/authorize",
+			"token_endpoint":         iss.URL + "/token",
+			"jwks_uri":               iss.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		ecPoint, _ := ecKey.PublicKey.Bytes()
+		json.NewEncoder(w).Encode(map[string][]map[string]string{"keys": {
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecPoint[1:33]), "y": b64(ecPoint[33:])},
+			{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": b64(rsaKey.N.Bytes()), "e": "AQAB"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		iss.form = map[string]string{"code_verifier": r.PostForm.Get("code_verifier"), "grant_type": r.PostForm.Get("grant_type")}
+		if r.PostForm.Get("code") != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "id_token": iss.idToken})
+	})
+	iss.Server = httptest.NewServer(mux)
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+func (iss *testIssuer) provider() *Provider {
+	return NewProvider(Config{Name: "test", Issuer: iss.URL, ClientID: testClientID, RedirectURL: "http://localhost/callback"})
+}
+
+// claims returns valid ID token claims for the test client.
+func (iss *testIssuer) claims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss":            iss.URL,
+		"sub":            "user-1",
+		"aud":            testClientID,
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          testNonce,
+		"email":          "ada@example.com",
+		"email_verified": true,
+		"name":           "Ada",
+		"groups":         []string{"kc-admins"},
+	}
+}
+
+// sign builds a compact JWT with the given header and claims, signed according to alg.
+func (iss *testIssuer) sign(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	signed := segment(t, header) + "." + segment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch alg {
+	case "RS256":
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, iss.rsaKey, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, iss.ecKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case "HS256":
+		// The public key as an HMAC secret, as in the classic algorithm confusion attack.
+		pub, err := x509.MarshalPKIXPublicKey(&iss.rsaKey.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mac := hmac.New(sha256.New, pub)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	}
+	return signed + "." + b64(sig)
+}
+
+func segment(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b64(b)
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func TestVerifyIDToken(t *testing.T) {
+	iss := newTestIssuer(t)
+	with := func(key string, value any) map[string]any {
+		c := iss.claims()
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		nonce   string
+		wantErr string
+	}{
+		{"RS256", iss.sign(t, "RS256", "rsa-1", iss.claims()), testNonce, ""},
+		{"ES256", iss.sign(t, "ES256", "ec-1", iss.claims()), testNonce, ""},
+		{"audience list without azp", iss.sign(t, "RS256", "rsa-1", with("aud", []string{testClientID, "other"})), testNonce, "authorized party"},
+		{"wrong issuer", iss.sign(t, "RS256", "rsa-1", with("iss", "https://evil.example.com")), testNonce, "issuer mismatch"},
+		{"wrong audience", iss.sign(t, "RS256", "rsa-1", with("aud", "other-client")), testNonce, "audience mismatch"},
+		{"expired", iss.sign(t, "RS256", "rsa-1", with("exp", time.Now().Add(-2*clockSkew).Unix())), testNonce, "expired"},
+		{"issued in the future", iss.sign(t, "RS256", "rsa-1", with("iat", time.Now().Add(2*clockSkew).Unix())), testNonce, "future"},
+		{"nonce mismatch", iss.sign(t, "RS256", "rsa-1", iss.claims()), "other-nonce", "nonce mismatch"},
+		{"no nonce", iss.sign(t, "RS256", "rsa-1", with("nonce", nil)), testNonce, "nonce mismatch"},
+		{"no subject", iss.sign(t, "RS256", "rsa-1", with("sub", nil)), testNonce, "no subject"},
+		{"unknown kid", iss.sign(t, "RS256", "rsa-2", iss.claims()), testNonce, "unknown key"},
+		{"encryption key", iss.sign(t, "RS256", "enc-1", iss.claims()), testNonce, "unknown key"},
+		{"alg none", iss.sign(t, "none", "rsa-1", iss.claims()), testNonce, "unsupported"},
+		{"HS256 with RSA key", iss.sign(t, "HS256", "rsa-1", iss.claims()), testNonce, "unsupported"},
+		{"RS256 with EC key", iss.sign(t, "RS256", "ec-1", iss.claims()), testNonce, "signature"},
+		{"ES256 with RSA key", iss.sign(t, "ES256", "rsa-1", iss.claims()), testNonce, "signature"},
+		{"tampered payload", tamper(iss.sign(t, "RS256", "rsa-1", iss.claims()), segment(t, with("sub", "admin"))), testNonce, "signature"},
+		{"not a JWT", "abc.def", testNonce, "format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := iss.provider().VerifyIDToken(context.Background(), tt.token, tt.nonce)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("VerifyIDToken() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyIDToken() error = %v", err)
+			}
+			if claims.Subject != "user-1" || claims.Email != "ada@example.com" || !claims.EmailVerified ||
+				claims.Name != "Ada" || len(claims.Groups) != 1 || claims.Groups[0] != "kc-admins" {
+				t.Errorf("VerifyIDToken() = %+v", claims)
+			}
+		})
+	}
+}
+
+// tamper replaces the payload of a signed token, keeping its signature.
+func tamper(token, payload string) string {
+	parts := strings.Split(token, ".")
+	return parts[0] + "." + payload + "." + parts[2]
+}
+
+func TestVerifyIDTokenEmailVerified(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := iss.provider()
+	for _, tt := range []struct {
+		value any
+		want  bool
+	}{{true, true}, {"true", true}, {false, false}, {"false", false}, {nil, false}} {
+		claims := iss.claims()
+		claims["email_verified"] = tt.value
+		if tt.value == nil {
+			delete(claims, "email_verified")
+		}
+		got, err := p.VerifyIDToken(context.Background(), iss.sign(t, "RS256", "rsa-1", claims), testNonce)
+		if err != nil {
+			t.Fatalf("VerifyIDToken(email_verified=%v) error = %v", tt.value, err)
+		}
+		if got.EmailVerified != tt.want {
+			t.Errorf("email_verified=%v: EmailVerified = %v, want %v", tt.value, got.EmailVerified, tt.want)
+		}
+	}
+}
+
+func TestExchange(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := iss.provider()
+	iss.idToken = iss.sign(t, "RS256", "rsa-1", iss.claims())
+
+	claims, err := p.Exchange(context.Background(), "good-code", "verifier-1", testNonce)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if claims.Subject != "user-1" {
+		t.Errorf("Exchange() subject = %q, want user-1", claims.Subject)
+	}
+	if iss.form["code_verifier"] != "verifier-1" || iss.form["grant_type"] != "authorization_code" {
+		t.Errorf("token request form = %v", iss.form)
+	}
+
+	if _, err := p.Exchange(context.Background(), "bad-code", "verifier-1", testNonce); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("Exchange(bad code) error = %v, want invalid_grant", err)
+	}
+	if _, err := p.Exchange(context.Background(), "good-code", "verifier-1", "other-nonce"); err == nil {
+		t.Error("Exchange() accepted an ID token with another nonce")
+	}
+
+	iss.idToken = iss.sign(t, "RS256", "rsa-1", map[string]any{"iss": iss.URL, "sub": "user-1", "aud": "other-client",
+		"exp": time.Now().Add(time.Hour).Unix(), "nonce": testNonce})
+	if _, err := p.Exchange(context.Background(), "good-code", "verifier-1", testNonce); err == nil {
+		t.Error("Exchange() accepted an ID token for another client")
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := NewProvider(Config{Name: "test", Issuer: iss.URL + "/", ClientID: testClientID})
+	if _, err := p.AuthCodeURL(context.Background(), "state", testNonce, "challenge"); err == nil {
+		t.Error("AuthCodeURL() accepted a discovery document for another issuer")
+	}
+}
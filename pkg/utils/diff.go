@@ -0,0 +1,222 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"knowledge-capsule/app/models"
+)
+
+// Diff size bounds: the most changed lines DiffLines compares, and the most edits it searches
+// for. Backtracking keeps a snapshot per edit, so memory grows with the square of the edits.
+const (
+	MaxDiffLines = 20000
+	MaxDiffEdits = 1000
+)
+
+// ErrDiffTooLarge is returned when two texts differ in more lines than DiffLines compares.
+var ErrDiffTooLarge = errors.New("texts differ too much to diff")
+
+// DiffLines returns the shortest line edit script turning a into b (Myers' algorithm). Lines
+// both texts start or end with are matched up front, so the bounds only apply to the part in
+// between; beyond them it returns ErrDiffTooLarge.
+func DiffLines(a, b string) ([]models.DiffLine, error) {
+	x, y := splitLines(a), splitLines(b)
+	pre := 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+	if len(x)+len(y)-2*(pre+suf) > MaxDiffLines {
+		return nil, ErrDiffTooLarge
+	}
+	middle, err := diffMiddle(x[pre:len(x)-suf], y[pre:len(y)-suf], pre)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]models.DiffLine, 0, pre+len(middle)+suf)
+	for i := 0; i < pre; i++ {
+		lines = append(lines, models.DiffLine{Op: models.DiffEqual, Text: x[i], OldLine: i + 1, NewLine: i + 1})
+	}
+	lines = append(lines, middle...)
+	for i, j := len(x)-suf, len(y)-suf; i < len(x); i, j = i+1, j+1 {
+		lines = append(lines, models.DiffLine{Op: models.DiffEqual, Text: x[i], OldLine: i + 1, NewLine: j + 1})
+	}
+	return lines, nil
+}
+
+// diffMiddle runs the Myers search on x and y, which start after skip equal lines.
+func diffMiddle(x, y []string, skip int) ([]models.DiffLine, error) {
+	n, m := len(x), len(y)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+search:
+	for d := 0; d <= max; d++ {
+		if d > MaxDiffEdits {
+			return nil, ErrDiffTooLarge
+		}
+		// Snapshot diagonals -d..d; backtracking never looks further out.
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				i = v[offset+k+1]
+			} else {
+				i = v[offset+k-1] + 1
+			}
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+			v[offset+k] = i
+			if i >= n && j >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards to recover the edit script.
+	var lines []models.DiffLine
+	i, j := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		vd := trace[d]
+		k := i - j
+		var prevK int
+		if k == -d || (k != d && vd[d+k-1] < vd[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevI := 0
+		if d > 0 {
+			prevI = vd[d+prevK]
+		}
+		prevJ := prevI - prevK
+		for i > prevI && j > prevJ {
+			i--
+			j--
+			lines = append(lines, models.DiffLine{Op: models.DiffEqual, Text: x[i], OldLine: skip + i + 1, NewLine: skip + j + 1})
+		}
+		if d > 0 {
+			if i == prevI {
+				j--
+				lines = append(lines, models.DiffLine{Op: models.DiffInsert, Text: y[j], NewLine: skip + j + 1})
+			} else {
+				i--
+				lines = append(lines, models.DiffLine{Op: models.DiffDelete, Text: x[i], OldLine: skip + i + 1})
+			}
+		}
+	}
+
+	for l, r := 0, len(lines)-1; l < r; l, r = l+1, r-1 {
+		lines[l], lines[r] = lines[r], lines[l]
+	}
+	return lines, nil
+}
+
+// UnifiedDiff renders a diff in unified format with the given lines of context.
+func UnifiedDiff(lines []models.DiffLine, fromName, toName string, context int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	for start := 0; start < len(lines); {
+		// Find the next change.
+		for start < len(lines) && lines[start].Op == models.DiffEqual {
+			start++
+		}
+		if start == len(lines) {
+			break
+		}
+		// Extend the hunk until a run of unchanged lines longer than 2*context.
+		end := start
+		for end < len(lines) {
+			if lines[end].Op != models.DiffEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].Op == models.DiffEqual {
+				run++
+			}
+			if run == len(lines) || run-end > 2*context {
+				break
+			}
+			end = run
+		}
+		from := start - context
+		if from < 0 {
+			from = 0
+		}
+		to := end + context
+		if to > len(lines) {
+			to = len(lines)
+		}
+
+		oldStart, newStart, oldCount, newCount := 0, 0, 0, 0
+		for _, l := range lines[from:to] {
+			if l.Op != models.DiffInsert {
+				if oldCount == 0 {
+					oldStart = l.OldLine
+				}
+				oldCount++
+			}
+			if l.Op != models.DiffDelete {
+				if newCount == 0 {
+					newStart = l.NewLine
+				}
+				newCount++
+			}
+		}
+		if oldCount == 0 {
+			oldStart = precedingLine(lines[:from], true)
+		}
+		if newCount == 0 {
+			newStart = precedingLine(lines[:from], false)
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, l := range lines[from:to] {
+			switch l.Op {
+			case models.DiffInsert:
+				sb.WriteString("+")
+			case models.DiffDelete:
+				sb.WriteString("-")
+			default:
+				sb.WriteString(" ")
+			}
+			sb.WriteString(l.Text)
+			sb.WriteString("\n")
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+// precedingLine returns the last old (or new) line number before a hunk, which
+// unified diff uses as the start of an empty range.
+func precedingLine(lines []models.DiffLine, old bool) int {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if old && lines[i].OldLine > 0 {
+			return lines[i].OldLine
+		}
+		if !old && lines[i].NewLine > 0 {
+			return lines[i].NewLine
+		}
+	}
+	return 0
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n"), "\n")
+}
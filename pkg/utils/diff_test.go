@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"knowledge-capsule/app/models"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []models.DiffLine
+	}{
+		{"both empty", "", "", []models.DiffLine{}},
+		{"identical", "a\nb\n", "a\nb", []models.DiffLine{
+			{Op: models.DiffEqual, Text: "a", OldLine: 1, NewLine: 1},
+			{Op: models.DiffEqual, Text: "b", OldLine: 2, NewLine: 2},
+		}},
+		{"insert into empty", "", "a", []models.DiffLine{
+			{Op: models.DiffInsert, Text: "a", NewLine: 1},
+		}},
+		{"delete all", "a\nb", "", []models.DiffLine{
+			{Op: models.DiffDelete, Text: "a", OldLine: 1},
+			{Op: models.DiffDelete, Text: "b", OldLine: 2},
+		}},
+		{"change in the middle", "a\nb\nc", "a\nx\nc", []models.DiffLine{
+			{Op: models.DiffEqual, Text: "a", OldLine: 1, NewLine: 1},
+			{Op: models.DiffDelete, Text: "b", OldLine: 2},
+			{Op: models.DiffInsert, Text: "x", NewLine: 2},
+			{Op: models.DiffEqual, Text: "c", OldLine: 3, NewLine: 3},
+		}},
+		{"insert shifts later lines", "a\nc", "a\nb\nc", []models.DiffLine{
+			{Op: models.DiffEqual, Text: "a", OldLine: 1, NewLine: 1},
+			{Op: models.DiffInsert, Text: "b", NewLine: 2},
+			{Op: models.DiffEqual, Text: "c", OldLine: 2, NewLine: 3},
+		}},
+		{"crlf line endings", "a\r\nb\r\n", "a\nb\n", []models.DiffLine{
+			{Op: models.DiffEqual, Text: "a", OldLine: 1, NewLine: 1},
+			{Op: models.DiffEqual, Text: "b", OldLine: 2, NewLine: 2},
+		}},
+		{"match inside changed region", "x\na\ny", "z\na\nw", []models.DiffLine{
+			{Op: models.DiffDelete, Text: "x", OldLine: 1},
+			{Op: models.DiffInsert, Text: "z", NewLine: 1},
+			{Op: models.DiffEqual, Text: "a", OldLine: 2, NewLine: 2},
+			{Op: models.DiffDelete, Text: "y", OldLine: 3},
+			{Op: models.DiffInsert, Text: "w", NewLine: 3},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffLines(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("DiffLines() error = %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("DiffLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesLimits(t *testing.T) {
+	numbered := func(prefix string, n int) string {
+		lines := make([]string, n)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("%s%d", prefix, i)
+		}
+		return strings.Join(lines, "\n")
+	}
+	tests := []struct {
+		name    string
+		a, b    string
+		wantErr bool
+	}{
+		{"many edits", numbered("a", MaxDiffEdits), numbered("b", MaxDiffEdits), true},
+		{"too many lines", "", numbered("a", MaxDiffLines+1), true},
+		{"long shared prefix", numbered("a", MaxDiffLines) + "\nold", numbered("a", MaxDiffLines) + "\nnew", false},
+		{"within the edit limit", numbered("a", MaxDiffEdits/2), numbered("b", MaxDiffEdits/2), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DiffLines(tt.a, tt.b)
+			if got := errors.Is(err, ErrDiffTooLarge); got != tt.wantErr {
+				t.Errorf("DiffLines() error = %v, want ErrDiffTooLarge: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{"no changes", "a\nb", "a\nb", 3, "--- old\n+++ new\n"},
+		{"one change", "a\nb\nc", "a\nx\nc", 1, "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n"},
+		{"context trimmed", "a\nb\nc\nd\ne", "a\nb\nC\nd\ne", 1, "--- old\n+++ new\n@@ -2,3 +2,3 @@\n b\n-c\n+C\n d\n"},
+		{"insert into empty", "", "a", 3, "--- old\n+++ new\n@@ -0,0 +1,1 @@\n+a\n"},
+		{"separate hunks", "1\n2\n3\n4\n5\n6\n7", "x\n2\n3\n4\n5\n6\ny", 1,
+			"--- old\n+++ new\n@@ -1,2 +1,2 @@\n-1\n+x\n 2\n@@ -6,2 +6,2 @@\n 6\n-7\n+y\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := DiffLines(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("DiffLines() error = %v", err)
+			}
+			if got := UnifiedDiff(lines, "old", "new", tt.context); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package utils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"knowledge-capsule/app/models"
+)
+
+// BuildGraph builds the knowledge graph of capsules, listed most relevant first, with their
+// topics, tags and the wiki links between them. Without a root it keeps the first limit
+// capsules. With one, it keeps the capsules within depth hops of the root, nearest first,
+// where a hop is a link either way, a shared tag or a shared topic. Links to capsules outside
+// the graph are dropped.
+func BuildGraph(capsules []models.GraphCapsule, links []models.WikiLink, rootID string, depth, limit int) *models.Graph {
+	index := make(map[string]int, len(capsules))
+	for i, c := range capsules {
+		index[c.ID] = i
+	}
+	linked := make(map[int][]int)
+	for _, l := range links {
+		from, ok1 := index[l.SourceID]
+		to, ok2 := index[*l.TargetID]
+		if ok1 && ok2 && from != to {
+			linked[from] = append(linked[from], to)
+			linked[to] = append(linked[to], from)
+		}
+	}
+
+	var selected []int
+	truncated := false
+	if root, ok := index[rootID]; ok {
+		selected, truncated = graphNeighborhood(capsules, linked, root, depth, limit)
+	} else {
+		for i := range capsules {
+			if i == limit {
+				truncated = true
+				break
+			}
+			selected = append(selected, i)
+		}
+	}
+	return graphOf(capsules, links, index, selected, truncated)
+}
+
+// graphNeighborhood walks outward from root breadth first and returns the capsules reached
+// within depth hops, and whether the walk stopped at limit.
+func graphNeighborhood(capsules []models.GraphCapsule, linked map[int][]int, root, depth, limit int) ([]int, bool) {
+	byTag := make(map[string][]int)
+	byTopic := make(map[string][]int)
+	for i, c := range capsules {
+		for _, tag := range c.Tags {
+			byTag[tag] = append(byTag[tag], i)
+		}
+		if c.TopicID != nil {
+			byTopic[*c.TopicID] = append(byTopic[*c.TopicID], i)
+		}
+	}
+
+	order := []int{root}
+	seen := map[int]bool{root: true}
+	// A tag or topic only needs expanding once: all of its capsules are then seen.
+	expandedTags := make(map[string]bool)
+	expandedTopics := make(map[string]bool)
+	frontier := []int{root}
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		var next []int
+		visit := func(j int) bool {
+			if seen[j] {
+				return true
+			}
+			if len(order) == limit {
+				return false
+			}
+			seen[j] = true
+			order = append(order, j)
+			next = append(next, j)
+			return true
+		}
+		for _, i := range frontier {
+			neighbors := append([]int(nil), linked[i]...)
+			if id := capsules[i].TopicID; id != nil && !expandedTopics[*id] {
+				expandedTopics[*id] = true
+				neighbors = append(neighbors, byTopic[*id]...)
+			}
+			for _, tag := range capsules[i].Tags {
+				if !expandedTags[tag] {
+					expandedTags[tag] = true
+					neighbors = append(neighbors, byTag[tag]...)
+				}
+			}
+			for _, j := range neighbors {
+				if !visit(j) {
+					return order, true
+				}
+			}
+		}
+		frontier = next
+	}
+	return order, false
+}
+
+// capsulePair is two capsules by their position in the graph, first < second.
+type capsulePair struct{ first, second int }
+
+// graphOf turns the selected capsules into graph nodes and edges.
+func graphOf(capsules []models.GraphCapsule, links []models.WikiLink, index map[string]int, selected []int, truncated bool) *models.Graph {
+	g := &models.Graph{Nodes: []models.GraphNode{}, Edges: []models.GraphEdge{}, Truncated: truncated}
+	position := make(map[int]int, len(selected))
+	for p, i := range selected {
+		position[i] = p
+		g.Nodes = append(g.Nodes, models.GraphNode{ID: "capsule:" + capsules[i].ID, Type: models.GraphNodeCapsule, Label: capsules[i].Title})
+	}
+
+	byTopic := make(map[string][]int)
+	byTag := make(map[string][]int)
+	var topicIDs, tags []string
+	for p, i := range selected {
+		c := capsules[i]
+		node := "capsule:" + c.ID
+		if c.TopicID != nil {
+			if _, ok := byTopic[*c.TopicID]; !ok {
+				topicIDs = append(topicIDs, *c.TopicID)
+				g.Nodes = append(g.Nodes, models.GraphNode{ID: "topic:" + *c.TopicID, Type: models.GraphNodeTopic, Label: c.Topic})
+			}
+			byTopic[*c.TopicID] = append(byTopic[*c.TopicID], p)
+			g.Edges = append(g.Edges, models.GraphEdge{Source: node, Target: "topic:" + *c.TopicID, Type: models.GraphEdgeTopic, Weight: 1})
+		}
+		for _, tag := range c.Tags {
+			if _, ok := byTag[tag]; !ok {
+				tags = append(tags, tag)
+				g.Nodes = append(g.Nodes, models.GraphNode{ID: "tag:" + tag, Type: models.GraphNodeTag, Label: tag})
+			}
+			byTag[tag] = append(byTag[tag], p)
+			g.Edges = append(g.Edges, models.GraphEdge{Source: node, Target: "tag:" + tag, Type: models.GraphEdgeTag, Weight: 1})
+		}
+	}
+
+	// Two link texts can name the same capsule; they add up to one weighted edge.
+	linkWeights := make(map[[2]int]int)
+	var linkKeys [][2]int
+	for _, l := range links {
+		source, ok1 := index[l.SourceID]
+		target, ok2 := index[*l.TargetID]
+		if !ok1 || !ok2 {
+			continue
+		}
+		from, ok1 := position[source]
+		to, ok2 := position[target]
+		if !ok1 || !ok2 || from == to {
+			continue
+		}
+		key := [2]int{from, to}
+		if linkWeights[key] == 0 {
+			linkKeys = append(linkKeys, key)
+		}
+		linkWeights[key]++
+	}
+	for _, key := range linkKeys {
+		g.Edges = append(g.Edges, graphEdge(capsules, selected, key[0], key[1], models.GraphEdgeLink, linkWeights[key]))
+	}
+
+	shared := make(map[capsulePair]int)
+	for _, tag := range tags {
+		forEachPair(byTag[tag], func(pair capsulePair) { shared[pair]++ })
+	}
+	for _, pair := range sortedPairs(shared) {
+		g.Edges = append(g.Edges, graphEdge(capsules, selected, pair.first, pair.second, models.GraphEdgeSharedTags, shared[pair]))
+	}
+	for _, id := range topicIDs {
+		forEachPair(byTopic[id], func(pair capsulePair) {
+			g.Edges = append(g.Edges, graphEdge(capsules, selected, pair.first, pair.second, models.GraphEdgeSameTopic, 1))
+		})
+	}
+	return g
+}
+
+// graphEdge returns an edge between the capsules at two graph positions.
+func graphEdge(capsules []models.GraphCapsule, selected []int, from, to int, kind string, weight int) models.GraphEdge {
+	return models.GraphEdge{
+		Source: "capsule:" + capsules[selected[from]].ID,
+		Target: "capsule:" + capsules[selected[to]].ID,
+		Type:   kind,
+		Weight: weight,
+	}
+}
+
+// forEachPair calls fn for every pair of the ascending positions in ps.
+func forEachPair(ps []int, fn func(capsulePair)) {
+	for a := 0; a < len(ps); a++ {
+		for b := a + 1; b < len(ps); b++ {
+			fn(capsulePair{ps[a], ps[b]})
+		}
+	}
+}
+
+// sortedPairs returns the keys of pairs in graph order.
+func sortedPairs(pairs map[capsulePair]int) []capsulePair {
+	keys := make([]capsulePair, 0, len(pairs))
+	for pair := range pairs {
+		keys = append(keys, pair)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].first != keys[j].first {
+			return keys[i].first < keys[j].first
+		}
+		return keys[i].second < keys[j].second
+	})
+	return keys
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID       string        `xml:"id,attr"`
+	Source   string        `xml:"source,attr"`
+	Target   string        `xml:"target,attr"`
+	Directed bool          `xml:"directed,attr"`
+	Data     []graphMLData `xml:"data"`
+}
+
+// WriteGraphML writes g as GraphML. Link edges are directed, the others undirected; node
+// type and label and edge type and weight are GraphML attributes.
+func WriteGraphML(w io.Writer, g *models.Graph) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "type", For: "node", Name: "type", Type: "string"},
+			{ID: "label", For: "node", Name: "label", Type: "string"},
+			{ID: "edge_type", For: "edge", Name: "type", Type: "string"},
+			{ID: "weight", For: "edge", Name: "weight", Type: "int"},
+		},
+	}
+	doc.Graph.ID = "knowledge"
+	doc.Graph.EdgeDefault = "undirected"
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: n.ID, Data: []graphMLData{
+			{Key: "type", Value: n.Type},
+			{Key: "label", Value: n.Label},
+		}})
+	}
+	for i, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:       fmt.Sprintf("e%d", i),
+			Source:   e.Source,
+			Target:   e.Target,
+			Directed: e.Type == models.GraphEdgeLink,
+			Data: []graphMLData{
+				{Key: "edge_type", Value: e.Type},
+				{Key: "weight", Value: fmt.Sprint(e.Weight)},
+			},
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// graphShapes are the Graphviz node shapes per node type.
+var graphShapes = map[string]string{
+	models.GraphNodeCapsule: "box",
+	models.GraphNodeTopic:   "folder",
+	models.GraphNodeTag:     "ellipse",
+}
+
+// WriteGraphDOT writes g as an undirected Graphviz graph; link edges carry an arrow.
+func WriteGraphDOT(w io.Writer, g *models.Graph) error {
+	var b strings.Builder
+	b.WriteString("graph knowledge {\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, type=%s, shape=%s];\n", dotQuote(n.ID), dotQuote(n.Label), dotQuote(n.Type), graphShapes[n.Type])
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -- %s [type=%s, weight=%d", dotQuote(e.Source), dotQuote(e.Target), dotQuote(e.Type), e.Weight)
+		if e.Type == models.GraphEdgeLink {
+			b.WriteString(", dir=forward")
+		}
+		b.WriteString("];\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote returns s as a DOT quoted string.
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+	return `"` + r.Replace(s) + `"`
+}
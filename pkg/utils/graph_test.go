@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"encoding/xml"
+	"slices"
+	"strings"
+	"testing"
+
+	"knowledge-capsule/app/models"
+)
+
+func ptr(s string) *string { return &s }
+
+func link(source, target string) models.WikiLink {
+	return models.WikiLink{SourceID: source, Target: target, TargetID: ptr(target)}
+}
+
+// testGraph is a → b by link, b and c share the tag go, c and d share the topic t1, and e
+// stands alone.
+func testGraph() ([]models.GraphCapsule, []models.WikiLink) {
+	capsules := []models.GraphCapsule{
+		{ID: "a", Title: "A"},
+		{ID: "b", Title: "B", Tags: models.Tags{"go"}},
+		{ID: "c", Title: "C", Tags: models.Tags{"go"}, TopicID: ptr("t1"), Topic: "Topic"},
+		{ID: "d", Title: "D", TopicID: ptr("t1"), Topic: "Topic"},
+		{ID: "e", Title: "E"},
+	}
+	return capsules, []models.WikiLink{link("a", "b")}
+}
+
+// capsuleIDs returns the capsule node IDs of g in order, without their prefix.
+func capsuleIDs(g *models.Graph) []string {
+	var ids []string
+	for _, n := range g.Nodes {
+		if n.Type == models.GraphNodeCapsule {
+			ids = append(ids, strings.TrimPrefix(n.ID, "capsule:"))
+		}
+	}
+	return ids
+}
+
+func TestBuildGraphNeighborhood(t *testing.T) {
+	capsules, links := testGraph()
+	tests := []struct {
+		name          string
+		root          string
+		depth, limit  int
+		want          []string
+		wantTruncated bool
+	}{
+		{"depth 0", "a", 0, 10, []string{"a"}, false},
+		{"link", "a", 1, 10, []string{"a", "b"}, false},
+		{"link back", "b", 1, 10, []string{"b", "a", "c"}, false},
+		{"shared tag", "a", 2, 10, []string{"a", "b", "c"}, false},
+		{"same topic", "a", 3, 10, []string{"a", "b", "c", "d"}, false},
+		{"unconnected never reached", "a", 10, 10, []string{"a", "b", "c", "d"}, false},
+		{"limit", "a", 3, 2, []string{"a", "b"}, true},
+		{"limit exactly reached", "a", 3, 4, []string{"a", "b", "c", "d"}, false},
+		{"unknown root keeps the first capsules", "missing", 1, 3, []string{"a", "b", "c"}, true},
+		{"no root", "", 1, 10, []string{"a", "b", "c", "d", "e"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := BuildGraph(capsules, links, tt.root, tt.depth, tt.limit)
+			if got := capsuleIDs(g); !slices.Equal(got, tt.want) {
+				t.Errorf("capsules = %v, want %v", got, tt.want)
+			}
+			if g.Truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", g.Truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestBuildGraphEdges(t *testing.T) {
+	capsules := []models.GraphCapsule{
+		{ID: "a", Title: "A", Tags: models.Tags{"go", "web"}},
+		{ID: "b", Title: "B", Tags: models.Tags{"go", "web"}},
+		{ID: "c", Title: "C"},
+	}
+	links := []models.WikiLink{
+		link("a", "b"),
+		{SourceID: "a", Target: "B again", TargetID: ptr("b")},
+		link("a", "a"),
+		link("a", "outside"),
+		link("c", "a"),
+	}
+	g := BuildGraph(capsules, links, "", 0, 2)
+
+	count := map[string]int{}
+	for _, e := range g.Edges {
+		count[e.Type]++
+		switch e.Type {
+		case models.GraphEdgeLink:
+			if e.Source != "capsule:a" || e.Target != "capsule:b" || e.Weight != 2 {
+				t.Errorf("link edge = %+v, want a → b with the two links to b counted once", e)
+			}
+		case models.GraphEdgeSharedTags:
+			if e.Weight != 2 {
+				t.Errorf("shared tags edge = %+v, want weight 2", e)
+			}
+		}
+	}
+	// Self links, links out of the graph and links from capsules left out are dropped.
+	want := map[string]int{models.GraphEdgeLink: 1, models.GraphEdgeSharedTags: 1, models.GraphEdgeTag: 4}
+	for kind, n := range want {
+		if count[kind] != n {
+			t.Errorf("%s edges = %d, want %d (%+v)", kind, count[kind], n, g.Edges)
+		}
+	}
+	tagNodes := 0
+	for _, n := range g.Nodes {
+		if n.Type == models.GraphNodeTag {
+			tagNodes++
+		}
+	}
+	if tagNodes != 2 {
+		t.Errorf("tag nodes = %d, want one per tag", tagNodes)
+	}
+}
+
+// hostileGraph has labels and IDs that would break out of DOT strings or XML.
+func hostileGraph() *models.Graph {
+	return &models.Graph{
+		Nodes: []models.GraphNode{
+			{ID: `capsule:1`, Type: models.GraphNodeCapsule, Label: "Quote \" back\\slash \\\"\nnew line\r"},
+			{ID: `tag:a"];evil[x="`, Type: models.GraphNodeTag, Label: `</data><script>&amp;`},
+		},
+		Edges: []models.GraphEdge{{Source: "capsule:1", Target: `tag:a"];evil[x="`, Type: models.GraphEdgeTag, Weight: 1}},
+	}
+}
+
+func TestWriteGraphDOTEscapes(t *testing.T) {
+	var b strings.Builder
+	if err := WriteGraphDOT(&b, hostileGraph()); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		`"capsule:1" [label="Quote \" back\\slash \\\"\nnew line", type="capsule", shape=box];`,
+		`"tag:a\"];evil[x=\"" [label="</data><script>&amp;", type="tag", shape=ellipse];`,
+		`"capsule:1" -- "tag:a\"];evil[x=\"" [type="tag", weight=1];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("DOT lacks %s:\n%s", want, out)
+		}
+	}
+	// Every statement stays on its own line, and every quote outside an escape closes a string.
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 5 {
+		t.Errorf("DOT has %d lines, want 5:\n%s", len(lines), out)
+	}
+	for _, line := range lines[1 : len(lines)-1] {
+		unescaped := strings.Count(strings.ReplaceAll(strings.ReplaceAll(line, `\\`, ""), `\"`, ""), `"`)
+		if unescaped%2 != 0 {
+			t.Errorf("unbalanced quotes in %s", line)
+		}
+	}
+}
+
+func TestWriteGraphMLEscapes(t *testing.T) {
+	var b strings.Builder
+	g := hostileGraph()
+	if err := WriteGraphML(&b, g); err != nil {
+		t.Fatal(err)
+	}
+	var doc graphML
+	if err := xml.Unmarshal([]byte(b.String()), &doc); err != nil {
+		t.Fatalf("GraphML does not parse: %v\n%s", err, b.String())
+	}
+	if len(doc.Graph.Nodes) != 2 || len(doc.Graph.Edges) != 1 {
+		t.Fatalf("GraphML has %d nodes and %d edges, want 2 and 1", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+	for i, n := range doc.Graph.Nodes {
+		if n.ID != g.Nodes[i].ID || n.Data[1].Value != g.Nodes[i].Label {
+			t.Errorf("node %d = %+v, want %+v", i, n, g.Nodes[i])
+		}
+	}
+	if e := doc.Graph.Edges[0]; e.Target != g.Edges[0].Target || e.Directed {
+		t.Errorf("edge = %+v", e)
+	}
+}
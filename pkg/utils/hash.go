@@ -3,32 +3,125 @@ package utils
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	saltLen   = 16
+	argon2Key = 32
 )
 
-const saltLen = 16
+// PasswordParams are the argon2id cost parameters embedded in every new hash.
+type PasswordParams struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+}
 
+// DefaultPasswordParams follow the OWASP argon2id baseline.
+var DefaultPasswordParams = PasswordParams{Memory: 64 * 1024, Iterations: 3, Parallelism: 2}
+
+var passwordParams = DefaultPasswordParams
+
+// InitPasswordParams sets the argon2id cost used for new hashes. Existing hashes keep
+// verifying with the parameters encoded in them and are upgraded via NeedsRehash.
+func InitPasswordParams(p PasswordParams) {
+	passwordParams = p
+}
+
+// HashPassword returns an encoded argon2id hash:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
 func HashPassword(password string) (string, error) {
 	salt := make([]byte, saltLen)
 	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
-	h := sha256.Sum256(append(salt, []byte(password)...))
-	return hex.EncodeToString(salt) + ":" + hex.EncodeToString(h[:]), nil
+	p := passwordParams
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, argon2Key)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
 }
 
+// CheckPassword verifies password against an argon2id hash, or a legacy salted
+// ("salt:hash") or unsalted SHA-256 hash. Comparisons are constant-time.
 func CheckPassword(password, stored string) bool {
+	if strings.HasPrefix(stored, "$argon2id$") {
+		p, salt, key, err := decodeArgon2(stored)
+		if err != nil {
+			return false
+		}
+		other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(key, other) == 1
+	}
+
 	parts := strings.SplitN(stored, ":", 2)
 	if len(parts) != 2 {
 		// Legacy format: plain SHA-256 without salt (for backwards compatibility)
 		hash := sha256.Sum256([]byte(password))
-		return hex.EncodeToString(hash[:]) == stored
+		return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(hash[:])), []byte(stored)) == 1
 	}
 	salt, err := hex.DecodeString(parts[0])
 	if err != nil || len(salt) != saltLen {
 		return false
 	}
 	h := sha256.Sum256(append(salt, []byte(password)...))
-	return hex.EncodeToString(h[:]) == parts[1]
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(h[:])), []byte(parts[1])) == 1
+}
+
+// NeedsRehash reports whether stored is a legacy SHA-256 hash or an argon2id hash
+// with parameters different from the current ones.
+func NeedsRehash(stored string) bool {
+	if !strings.HasPrefix(stored, "$argon2id$") {
+		return true
+	}
+	p, _, _, err := decodeArgon2(stored)
+	if err != nil {
+		return true
+	}
+	return p != passwordParams
+}
+
+// decodeArgon2 parses an encoded argon2id hash into its parameters, salt and key, rejecting
+// values argon2.IDKey cannot be called with.
+func decodeArgon2(encoded string) (PasswordParams, []byte, []byte, error) {
+	var p PasswordParams
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, errors.New("invalid argon2id hash format")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, err
+	}
+	if version != argon2.Version {
+		return p, nil, nil, errors.New("unsupported argon2 version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, err
+	}
+	// argon2.IDKey panics on zero iterations or parallelism.
+	if p.Iterations == 0 || p.Parallelism == 0 {
+		return p, nil, nil, errors.New("invalid argon2id parameters")
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, err
+	}
+	if len(salt) == 0 || len(key) == 0 {
+		return p, nil, nil, errors.New("invalid argon2id hash: empty salt or key")
+	}
+	return p, salt, key, nil
 }
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// cheapParams keep the tests fast; the format is the same at any cost.
+var cheapParams = PasswordParams{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestCheckPassword(t *testing.T) {
+	InitPasswordParams(cheapParams)
+	t.Cleanup(func() { InitPasswordParams(DefaultPasswordParams) })
+
+	argon, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	plain := sha256.Sum256([]byte("correct horse"))
+	salt := strings.Repeat("ab", saltLen)
+	saltBytes, _ := hex.DecodeString(salt)
+	salted := sha256.Sum256(append(saltBytes, []byte("correct horse")...))
+
+	tests := []struct {
+		name     string
+		password string
+		stored   string
+		want     bool
+	}{
+		{"argon2id", "correct horse", argon, true},
+		{"argon2id wrong password", "wrong horse", argon, false},
+		{"legacy unsalted", "correct horse", hex.EncodeToString(plain[:]), true},
+		{"legacy unsalted wrong password", "wrong horse", hex.EncodeToString(plain[:]), false},
+		{"legacy salted", "correct horse", salt + ":" + hex.EncodeToString(salted[:]), true},
+		{"legacy salted wrong password", "wrong horse", salt + ":" + hex.EncodeToString(salted[:]), false},
+		{"legacy short salt", "correct horse", "abcd:" + hex.EncodeToString(salted[:]), false},
+		{"zero iterations", "x", "$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHQ$a2V5a2V5", false},
+		{"zero parallelism", "x", "$argon2id$v=19$m=64,t=1,p=0$c2FsdHNhbHQ$a2V5a2V5", false},
+		{"empty salt", "x", "$argon2id$v=19$m=64,t=1,p=1$$a2V5a2V5", false},
+		{"empty key", "x", "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$", false},
+		{"wrong version", "x", "$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5", false},
+		{"missing fields", "x", "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ", false},
+		{"bad base64", "x", "$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5a2V5", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckPassword(tt.password, tt.stored); got != tt.want {
+				t.Errorf("CheckPassword() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	InitPasswordParams(cheapParams)
+	t.Cleanup(func() { InitPasswordParams(DefaultPasswordParams) })
+
+	current, err := HashPassword("secret")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	tests := []struct {
+		name   string
+		stored string
+		want   bool
+	}{
+		{"current parameters", current, false},
+		{"other parameters", "$argon2id$v=19$m=128,t=2,p=1$c2FsdHNhbHQ$a2V5a2V5", true},
+		{"legacy sha256", strings.Repeat("0", 64), true},
+		{"malformed", "$argon2id$broken", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeedsRehash(tt.stored); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,64 +1,183 @@
 package utils
 
 import (
+	"crypto"
+	"crypto/ed25519"
 	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"knowledge-capsule/app/models"
 )
 
-var secretKey []byte
+// Supported JWT signing algorithms.
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgEdDSA = "EdDSA"
+)
+
+// JWTSettings configures access token signing and verification.
+type JWTSettings struct {
+	Algorithm       string   // HS256 (default), RS256 or EdDSA
+	Secret          string   // HS256 signing secret; always used for signed cookies
+	PreviousSecrets []string // retired HS256 secrets still accepted for verification
+	PrivateKeyFile  string   // PEM private key for RS256/EdDSA
+	VerifyKeyFiles  []string // retired PEM public (or private) keys still accepted
+	Issuer          string
+	Audience        string
+}
 
-func InitJWTSecret(secret string) {
-	secretKey = []byte(secret)
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
 }
 
-// GenerateJWT creates a simple JWT token manually.
-func GenerateJWT(userID, email, role string, expiry time.Duration) (string, error) {
-	header := base64.URLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
-	if role == "" {
-		role = models.RoleUser
+// jwtKey is a signing or verification key. Asymmetric keys are identified by their
+// RFC 7638 thumbprint, HMAC keys by a hash of the secret.
+type jwtKey struct {
+	id      string
+	alg     string
+	secret  []byte
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+var (
+	secretKey   []byte
+	signingKey  *jwtKey
+	verifyKeys  = map[string]*jwtKey{}
+	jwtIssuer   string
+	jwtAudience string
+)
+
+// InitJWT loads the signing key and every key accepted for verification. To rotate, move
+// the current key to PreviousSecrets / VerifyKeyFiles and configure a new one; tokens
+// signed with the old key stay valid until they expire.
+func InitJWT(s JWTSettings) error {
+	secretKey = []byte(s.Secret)
+	jwtIssuer = s.Issuer
+	jwtAudience = s.Audience
+	verifyKeys = map[string]*jwtKey{}
+
+	switch s.Algorithm {
+	case "", JWTAlgHS256:
+		signingKey = hmacKey([]byte(s.Secret))
+	case JWTAlgRS256, JWTAlgEdDSA:
+		key, err := loadKeyFile(s.PrivateKeyFile)
+		if err != nil {
+			return err
+		}
+		if key.private == nil {
+			return fmt.Errorf("%s: a private key is required for signing", s.PrivateKeyFile)
+		}
+		if key.alg != s.Algorithm {
+			return fmt.Errorf("%s: key type does not match algorithm %s", s.PrivateKeyFile, s.Algorithm)
+		}
+		signingKey = key
+	default:
+		return fmt.Errorf("unsupported JWT algorithm %q", s.Algorithm)
 	}
+	verifyKeys[signingKey.id] = signingKey
 
-	claims := models.TokenClaims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
-		Iat:    time.Now().Unix(),
-		Exp:    time.Now().Add(expiry).Unix(),
+	for _, secret := range s.PreviousSecrets {
+		key := hmacKey([]byte(secret))
+		verifyKeys[key.id] = key
+	}
+	for _, file := range s.VerifyKeyFiles {
+		key, err := loadKeyFile(file)
+		if err != nil {
+			return err
+		}
+		key.private = nil
+		verifyKeys[key.id] = key
+	}
+	return nil
+}
+
+// GenerateJWT signs an access token with the current key. claims.SessionID ties the token
+// to a server-side session so it can be revoked before it expires; Iat, Nbf, Exp, Iss and
+// Aud are set here.
+func GenerateJWT(claims models.TokenClaims, expiry time.Duration) (string, error) {
+	if signingKey == nil {
+		return "", errors.New("jwt signing key not initialized")
+	}
+	headerBytes, err := json.Marshal(map[string]string{"alg": signingKey.alg, "typ": "JWT", "kid": signingKey.id})
+	if err != nil {
+		return "", err
+	}
+	if claims.Role == "" {
+		claims.Role = models.RoleUser
 	}
+	now := time.Now()
+	claims.Iat = now.Unix()
+	claims.Nbf = now.Unix()
+	claims.Exp = now.Add(expiry).Unix()
+	claims.Iss = jwtIssuer
+	claims.Aud = jwtAudience
 
 	payloadBytes, err := json.Marshal(claims)
 	if err != nil {
 		return "", err
 	}
-	payload := base64.URLEncoding.EncodeToString(payloadBytes)
 
-	unsignedToken := header + "." + payload
-	signature := signToken(unsignedToken)
-	return unsignedToken + "." + signature, nil
+	unsignedToken := base64.RawURLEncoding.EncodeToString(headerBytes) + "." + base64.RawURLEncoding.EncodeToString(payloadBytes)
+	signature, err := signingKey.sign(unsignedToken)
+	if err != nil {
+		return "", err
+	}
+	return unsignedToken + "." + base64.RawURLEncoding.EncodeToString(signature), nil
 }
 
-// VerifyJWT validates and parses a JWT token.
+// VerifyJWT validates the signature against the key named by kid, then exp, nbf, iss and aud.
 func VerifyJWT(token string) (*models.TokenClaims, error) {
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
 		return nil, errors.New("invalid token format")
 	}
 
-	unsignedToken := parts[0] + "." + parts[1]
-	expectedSig := signToken(unsignedToken)
-
-	if !hmac.Equal([]byte(expectedSig), []byte(parts[2])) {
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("invalid token header")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, errors.New("invalid token header")
+	}
+	key, ok := verifyKeys[header.Kid]
+	// The algorithm is fixed by the key, never chosen by the token.
+	if !ok || header.Alg != key.alg {
+		return nil, errors.New("invalid signature")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !key.verify(parts[0]+"."+parts[1], signature) {
 		return nil, errors.New("invalid signature")
 	}
 
-	payloadBytes, err := base64.URLEncoding.DecodeString(parts[1])
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
 		return nil, err
 	}
@@ -68,16 +187,155 @@ func VerifyJWT(token string) (*models.TokenClaims, error) {
 		return nil, err
 	}
 
-	if time.Now().Unix() > claims.Exp {
+	now := time.Now().Unix()
+	if now > claims.Exp {
 		return nil, errors.New("token expired")
 	}
+	if claims.Nbf != 0 && now < claims.Nbf {
+		return nil, errors.New("token not yet valid")
+	}
+	if claims.Iss != jwtIssuer || claims.Aud != jwtAudience {
+		return nil, errors.New("invalid token issuer or audience")
+	}
 
 	return &claims, nil
 }
 
-// signToken creates HMAC signature for JWT.
-func signToken(unsignedToken string) string {
-	h := hmac.New(sha256.New, secretKey)
-	h.Write([]byte(unsignedToken))
-	return base64.URLEncoding.EncodeToString(h.Sum(nil))
+// PublicJWKS returns the public keys accepted for verification, for /.well-known/jwks.json.
+// HMAC keys are secret and never published.
+func PublicJWKS() []JWK {
+	keys := []JWK{}
+	if signingKey == nil {
+		return keys
+	}
+	if signingKey.public != nil {
+		keys = append(keys, signingKey.jwk())
+	}
+	ids := make([]string, 0, len(verifyKeys))
+	for id, key := range verifyKeys {
+		if key.public != nil && id != signingKey.id {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		keys = append(keys, verifyKeys[id].jwk())
+	}
+	return keys
+}
+
+func hmacKey(secret []byte) *jwtKey {
+	sum := sha256.Sum256(secret)
+	return &jwtKey{id: "hs-" + hex.EncodeToString(sum[:8]), alg: JWTAlgHS256, secret: secret}
+}
+
+// loadKeyFile reads a PEM key: a PKCS#8 or PKCS#1 private key, or a PKIX public key.
+func loadKeyFile(path string) (*jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read JWT key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+
+	key := &jwtKey{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%s: unsupported private key", path)
+		}
+		key.private, key.public = signer, signer.Public()
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		key.private, key.public = parsed, parsed.Public()
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		key.public = parsed
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+
+	switch pub := key.public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("%s: RSA keys must be at least 2048 bits", path)
+		}
+		key.alg = JWTAlgRS256
+	case ed25519.PublicKey:
+		key.alg = JWTAlgEdDSA
+	default:
+		return nil, fmt.Errorf("%s: only RSA and Ed25519 keys are supported", path)
+	}
+	key.id = key.thumbprint()
+	return key, nil
+}
+
+func (k *jwtKey) sign(signingInput string) ([]byte, error) {
+	switch k.alg {
+	case JWTAlgHS256:
+		h := hmac.New(sha256.New, k.secret)
+		h.Write([]byte(signingInput))
+		return h.Sum(nil), nil
+	case JWTAlgRS256:
+		digest := sha256.Sum256([]byte(signingInput))
+		return k.private.Sign(rand.Reader, digest[:], crypto.SHA256)
+	default:
+		return k.private.Sign(rand.Reader, []byte(signingInput), crypto.Hash(0))
+	}
+}
+
+func (k *jwtKey) verify(signingInput string, signature []byte) bool {
+	switch k.alg {
+	case JWTAlgHS256:
+		expected, _ := k.sign(signingInput)
+		return hmac.Equal(expected, signature)
+	case JWTAlgRS256:
+		digest := sha256.Sum256([]byte(signingInput))
+		return rsa.VerifyPKCS1v15(k.public.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	default:
+		return ed25519.Verify(k.public.(ed25519.PublicKey), []byte(signingInput), signature)
+	}
+}
+
+func (k *jwtKey) jwk() JWK {
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA", Kid: k.id, Use: "sig", Alg: k.alg,
+			N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	default:
+		return JWK{
+			Kty: "OKP", Kid: k.id, Use: "sig", Alg: k.alg, Crv: "Ed25519",
+			X: base64.RawURLEncoding.EncodeToString(k.public.(ed25519.PublicKey)),
+		}
+	}
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint: SHA-256 over the required members in
+// lexicographic order.
+func (k *jwtKey) thumbprint() string {
+	j := k.jwk()
+	var canonical string
+	if j.Kty == "RSA" {
+		canonical = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, j.E, j.N)
+	} else {
+		canonical = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, j.X)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
 }
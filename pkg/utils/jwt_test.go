@@ -0,0 +1,253 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"knowledge-capsule/app/models"
+)
+
+// writePEM stores a PEM block in a temporary file and returns its path.
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// ed25519KeyFiles generates an Ed25519 key and returns its private and public PEM files.
+func ed25519KeyFiles(t *testing.T) (private, public string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, "PRIVATE KEY", privDER), writePEM(t, "PUBLIC KEY", pubDER)
+}
+
+func initJWT(t *testing.T, s JWTSettings) {
+	t.Helper()
+	if err := InitJWT(s); err != nil {
+		t.Fatalf("InitJWT() error = %v", err)
+	}
+	t.Cleanup(func() { InitJWT(JWTSettings{}) })
+}
+
+func testClaims() models.TokenClaims {
+	return models.TokenClaims{UserID: "u1", Email: "ada@example.com", Role: models.RoleUser, SessionID: "s1"}
+}
+
+// signToken signs a token with key exactly as given, bypassing GenerateJWT's claim defaults.
+func signToken(t *testing.T, key *jwtKey, header map[string]string, claims models.TokenClaims) string {
+	t.Helper()
+	h, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	sig, err := key.sign(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	oldPriv, oldPub := ed25519KeyFiles(t)
+	newPriv, _ := ed25519KeyFiles(t)
+
+	initJWT(t, JWTSettings{Algorithm: JWTAlgEdDSA, PrivateKeyFile: oldPriv})
+	oldToken, err := GenerateJWT(testClaims(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initJWT(t, JWTSettings{Algorithm: JWTAlgEdDSA, PrivateKeyFile: newPriv, VerifyKeyFiles: []string{oldPub}})
+	newToken, err := GenerateJWT(testClaims(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, token := range map[string]string{"rotated-out key": oldToken, "current key": newToken} {
+		if claims, err := VerifyJWT(token); err != nil || claims.UserID != "u1" {
+			t.Errorf("VerifyJWT(%s) = %+v, %v", name, claims, err)
+		}
+	}
+	if keys := PublicJWKS(); len(keys) != 2 || keys[0].Kid == keys[1].Kid {
+		t.Errorf("PublicJWKS() = %+v, want the current and the rotated-out key", keys)
+	}
+
+	initJWT(t, JWTSettings{Algorithm: JWTAlgEdDSA, PrivateKeyFile: newPriv})
+	if _, err := VerifyJWT(oldToken); err == nil {
+		t.Error("VerifyJWT() accepted a token signed by a dropped key")
+	}
+}
+
+func TestJWTSecretRotation(t *testing.T) {
+	initJWT(t, JWTSettings{Secret: "old-secret"})
+	oldToken, err := GenerateJWT(testClaims(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initJWT(t, JWTSettings{Secret: "new-secret", PreviousSecrets: []string{"old-secret"}})
+	if _, err := VerifyJWT(oldToken); err != nil {
+		t.Errorf("VerifyJWT(previous secret) error = %v", err)
+	}
+	if keys := PublicJWKS(); len(keys) != 0 {
+		t.Errorf("PublicJWKS() published HMAC keys: %+v", keys)
+	}
+
+	initJWT(t, JWTSettings{Secret: "new-secret"})
+	if _, err := VerifyJWT(oldToken); err == nil {
+		t.Error("VerifyJWT() accepted a token signed by a dropped secret")
+	}
+}
+
+func TestVerifyJWTRejects(t *testing.T) {
+	priv, pub := ed25519KeyFiles(t)
+	initJWT(t, JWTSettings{Algorithm: JWTAlgEdDSA, PrivateKeyFile: priv, Issuer: "kc", Audience: "kc-api"})
+	kid := signingKey.id
+	pubPEM, err := os.ReadFile(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := testClaims()
+	valid.Iss, valid.Aud = "kc", "kc-api"
+	valid.Iat, valid.Exp = time.Now().Unix(), time.Now().Add(time.Hour).Unix()
+	with := func(change func(*models.TokenClaims)) models.TokenClaims {
+		c := valid
+		change(&c)
+		return c
+	}
+	header := map[string]string{"alg": JWTAlgEdDSA, "typ": "JWT", "kid": kid}
+
+	if _, err := VerifyJWT(signToken(t, signingKey, header, valid)); err != nil {
+		t.Fatalf("VerifyJWT(valid) error = %v", err)
+	}
+
+	// An attacker who knows the public key signs with it as an HMAC secret.
+	confused := hmacKey(pubPEM)
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"unknown kid", signToken(t, signingKey, map[string]string{"alg": JWTAlgEdDSA, "kid": "other"}, valid)},
+		{"no kid", signToken(t, signingKey, map[string]string{"alg": JWTAlgEdDSA}, valid)},
+		{"HS256 with EdDSA kid", signToken(t, confused, map[string]string{"alg": JWTAlgHS256, "kid": kid}, valid)},
+		{"alg none", unsigned(t, map[string]string{"alg": "none", "kid": kid}, valid)},
+		{"wrong issuer", signToken(t, signingKey, header, with(func(c *models.TokenClaims) { c.Iss = "evil" }))},
+		{"wrong audience", signToken(t, signingKey, header, with(func(c *models.TokenClaims) { c.Aud = "other-api" }))},
+		{"no audience", signToken(t, signingKey, header, with(func(c *models.TokenClaims) { c.Aud = "" }))},
+		{"expired", signToken(t, signingKey, header, with(func(c *models.TokenClaims) { c.Exp = time.Now().Add(-time.Minute).Unix() }))},
+		{"not yet valid", signToken(t, signingKey, header, with(func(c *models.TokenClaims) { c.Nbf = time.Now().Add(time.Hour).Unix() }))},
+		{"tampered", tamperJWT(t, signToken(t, signingKey, header, valid), with(func(c *models.TokenClaims) { c.Role = models.RoleAdmin }))},
+		{"malformed", "not.a-jwt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if claims, err := VerifyJWT(tt.token); err == nil {
+				t.Errorf("VerifyJWT() = %+v, want error", claims)
+			}
+		})
+	}
+}
+
+func TestVerifyJWTRejectsHS256WithRSAKid(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initJWT(t, JWTSettings{Algorithm: JWTAlgRS256, PrivateKeyFile: writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))})
+
+	claims := testClaims()
+	claims.Exp = time.Now().Add(time.Hour).Unix()
+	for name, secret := range map[string][]byte{
+		"PEM":  pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}),
+		"DER":  pubDER,
+		"kid":  []byte(signingKey.id),
+		"none": nil,
+	} {
+		token := signToken(t, hmacKey(secret), map[string]string{"alg": JWTAlgHS256, "kid": signingKey.id}, claims)
+		if _, err := VerifyJWT(token); err == nil {
+			t.Errorf("VerifyJWT() accepted HS256 signed with the %s as secret", name)
+		}
+	}
+}
+
+func TestInitJWTKeyChecks(t *testing.T) {
+	priv, pub := ed25519KeyFiles(t)
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		s    JWTSettings
+	}{
+		{"key type mismatch", JWTSettings{Algorithm: JWTAlgRS256, PrivateKeyFile: priv}},
+		{"public key for signing", JWTSettings{Algorithm: JWTAlgEdDSA, PrivateKeyFile: pub}},
+		{"short RSA key", JWTSettings{Algorithm: JWTAlgRS256, PrivateKeyFile: writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(small))}},
+		{"missing file", JWTSettings{Algorithm: JWTAlgEdDSA, PrivateKeyFile: filepath.Join(t.TempDir(), "missing.pem")}},
+		{"unknown algorithm", JWTSettings{Algorithm: "ES512", Secret: "s"}},
+	}
+	t.Cleanup(func() { InitJWT(JWTSettings{}) })
+	for _, tt := range tests {
+		if err := InitJWT(tt.s); err == nil {
+			t.Errorf("InitJWT(%s) succeeded", tt.name)
+		}
+	}
+}
+
+// unsigned returns a token with an empty signature.
+func unsigned(t *testing.T, header map[string]string, claims models.TokenClaims) string {
+	t.Helper()
+	h, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c) + "."
+}
+
+// tamperJWT swaps a signed token's payload, keeping the original signature.
+func tamperJWT(t *testing.T, token string, claims models.TokenClaims) string {
+	t.Helper()
+	c, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	return parts[0] + "." + base64.RawURLEncoding.EncodeToString(c) + "." + parts[2]
+}
@@ -2,7 +2,11 @@ package utils
 
 import (
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+
+	"knowledge-capsule/app/models"
 )
 
 const (
@@ -33,6 +37,27 @@ func ParsePagination(r *http.Request) (page, limit int) {
 	return page, limit
 }
 
+// ParseListOptions extracts page, limit, sort, order and cursor from request query params.
+// sorts lists the sort keys the endpoint accepts.
+func ParseListOptions(r *http.Request, sorts ...string) (models.ListOptions, error) {
+	page, limit := ParsePagination(r)
+	q := r.URL.Query()
+	opts := models.ListOptions{
+		Page:   page,
+		Limit:  limit,
+		Sort:   q.Get("sort"),
+		Order:  strings.ToLower(q.Get("order")),
+		Cursor: q.Get("cursor"),
+	}
+	if opts.Sort != "" && !slices.Contains(sorts, opts.Sort) {
+		return opts, &ValidationError{Field: "sort", Message: "must be one of " + strings.Join(sorts, ", ")}
+	}
+	if opts.Order != "" && opts.Order != models.OrderAsc && opts.Order != models.OrderDesc {
+		return opts, &ValidationError{Field: "order", Message: "must be asc or desc"}
+	}
+	return opts, nil
+}
+
 // SlicePage returns the paginated slice of items.
 // Items must be a slice; returns (paginated slice, total count).
 func SlicePage[T any](items []T, page, limit int) ([]T, int) {
@@ -3,8 +3,11 @@ package utils
 import (
 	"encoding/json"
 	"errors"
+	"net"
 	"net/http"
+	"net/netip"
 	"reflect"
+	"strings"
 )
 
 func ParseAndValidateBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
@@ -49,3 +52,49 @@ type ValidationError struct {
 func (v *ValidationError) Error() string {
 	return v.Field + ": " + v.Message
 }
+
+// trustedProxies are the reverse proxies whose X-Forwarded-For header ClientIP believes.
+var trustedProxies []netip.Prefix
+
+// InitTrustedProxies sets the addresses of the reverse proxies in front of the server. With
+// none, X-Forwarded-For is ignored.
+func InitTrustedProxies(prefixes []netip.Prefix) {
+	trustedProxies = prefixes
+}
+
+// isTrustedProxy reports whether addr is one of the trusted proxies.
+func isTrustedProxy(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the caller's IP: the remote address of the connection, unless it is a
+// trusted proxy. Then X-Forwarded-For is read from the right, skipping the trusted proxies,
+// as entries left of the last one a trusted proxy added are set by the client.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isTrustedProxy(addr) {
+		return host
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+		if !isTrustedProxy(addr) {
+			break
+		}
+	}
+	return addr.String()
+}
@@ -30,6 +30,8 @@ type PaginatedResponse struct {
 	Page    int         `json:"page"`
 	Limit   int         `json:"limit"`
 	Total   int         `json:"total"`
+	// NextCursor continues after this page; empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // JSONPaginatedResponse writes a paginated JSON response.
@@ -49,6 +51,50 @@ func JSONPaginatedResponse(w http.ResponseWriter, status int, message string, da
 	json.NewEncoder(w).Encode(response)
 }
 
+// JSONListResponse writes a paginated JSON response for a page loaded with ListOptions.
+func JSONListResponse(w http.ResponseWriter, status int, message string, data interface{}, opts models.ListOptions, info models.PageInfo) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	response := PaginatedResponse{
+		Success:    true,
+		Message:    message,
+		Data:       data,
+		Page:       opts.Page,
+		Limit:      opts.Limit,
+		Total:      info.Total,
+		NextCursor: info.NextCursor,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// FacetedResponse is a paginated response with facet counts over the whole result set.
+type FacetedResponse struct {
+	PaginatedResponse
+	Facets interface{} `json:"facets"`
+}
+
+// JSONFacetedResponse writes a paginated JSON response with facets.
+func JSONFacetedResponse(w http.ResponseWriter, status int, message string, data, facets interface{}, page, limit, total int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	response := FacetedResponse{
+		PaginatedResponse: PaginatedResponse{
+			Success: true,
+			Message: message,
+			Data:    data,
+			Page:    page,
+			Limit:   limit,
+			Total:   total,
+		},
+		Facets: facets,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
 // ErrorResponse writes a JSON error response and logs the error. Pass nil for r when request is unavailable.
 func ErrorResponse(w http.ResponseWriter, r *http.Request, status int, err error) {
 	var errorMessage string
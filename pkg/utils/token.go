@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// GenerateSecureToken returns a URL-safe random token with n bytes of entropy.
+func GenerateSecureToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 hex digest used to store opaque tokens.
+// Tokens are high-entropy random values, so a fast hash is sufficient.
+func HashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// SignValue appends an HMAC of value, keyed with the JWT secret, for tamper-proof cookies.
+func SignValue(value string) string {
+	h := hmac.New(sha256.New, secretKey)
+	h.Write([]byte(value))
+	return value + "." + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// VerifySignedValue returns the value from SignValue output if its signature is valid.
+func VerifySignedValue(signed string) (string, bool) {
+	i := strings.LastIndex(signed, ".")
+	if i < 0 {
+		return "", false
+	}
+	value := signed[:i]
+	if !hmac.Equal([]byte(SignValue(value)), []byte(signed)) {
+		return "", false
+	}
+	return value, true
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the RFC 6238 SHA-1 test key "12345678901234567890" in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B vectors, truncated to 6 digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d) error = %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+	code := func(t time.Time) string {
+		c, _ := TOTPCode(rfcSecret, t)
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfcSecret, code(now), step, true},
+		{"previous step", rfcSecret, code(now.Add(-totpPeriod * time.Second)), step - 1, true},
+		{"next step", rfcSecret, code(now.Add(totpPeriod * time.Second)), step + 1, true},
+		{"outside the skew", rfcSecret, code(now.Add(-2 * totpPeriod * time.Second)), 0, false},
+		{"surrounding spaces", rfcSecret, " " + code(now) + " ", step, true},
+		{"lowercase secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code(now), step, true},
+		{"wrong code", rfcSecret, "000000", 0, false},
+		{"too short", rfcSecret, code(now)[:5], 0, false},
+		{"too long", rfcSecret, code(now) + "1", 0, false},
+		{"invalid secret", "not base32!", code(now), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := ValidateTOTP(tt.secret, tt.code, now)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("ValidateTOTP() = (%d, %v), want (%d, %v)", gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	u, err := url.Parse(TOTPURI("Knowledge Capsule", "jane@example.com", rfcSecret))
+	if err != nil {
+		t.Fatalf("TOTPURI() is not a URL: %v", err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Knowledge Capsule:jane@example.com" {
+		t.Errorf("TOTPURI() = %s, want otpauth://totp/<issuer>:<account>", u)
+	}
+	q := u.Query()
+	for key, want := range map[string]string{"secret": rfcSecret, "issuer": "Knowledge Capsule", "algorithm": "SHA1", "digits": "6", "period": "30"} {
+		if got := q.Get(key); got != want {
+			t.Errorf("TOTPURI() %s = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+#!/bin/bash
+
+ALG=${1:-EdDSA}
+KEY_DIR="keys"
+ENV_FILE=".env"
+KEY_FILE="$KEY_DIR/jwt-$(date +%Y%m%d%H%M%S).pem"
+
+mkdir -p "$KEY_DIR"
+
+case "$ALG" in
+    EdDSA)
+        openssl genpkey -algorithm ed25519 -out "$KEY_FILE" || exit 1
+        ;;
+    RS256)
+        openssl genpkey -algorithm RSA -pkeyopt rsa_keygen_bits:3072 -out "$KEY_FILE" || exit 1
+        ;;
+    *)
+        echo "❌ Unsupported algorithm: $ALG (use EdDSA or RS256)"
+        exit 1
+        ;;
+esac
+chmod 600 "$KEY_FILE"
+
+echo "🔑 Generated $ALG signing key: $KEY_FILE"
+
+# Ensure .env exists
+if [ ! -f "$ENV_FILE" ]; then
+    touch "$ENV_FILE"
+fi
+
+# Add or update a KEY=value line in .env
+set_env() {
+    if grep -q "^$1=" "$ENV_FILE"; then
+        sed -i "s|^$1=.*|$1=$2|" "$ENV_FILE"
+    else
+        [ -s "$ENV_FILE" ] && [ -n "$(tail -c1 "$ENV_FILE")" ] && echo "" >> "$ENV_FILE"
+        echo "$1=$2" >> "$ENV_FILE"
+    fi
+}
+
+OLD_KEY=$(grep "^JWT_PRIVATE_KEY_FILE=" "$ENV_FILE" | cut -d= -f2-)
+
+set_env JWT_ALG "$ALG"
+set_env JWT_PRIVATE_KEY_FILE "$KEY_FILE"
+echo "✅ Updated JWT_ALG and JWT_PRIVATE_KEY_FILE in $ENV_FILE"
+
+if [ -n "$OLD_KEY" ]; then
+    echo "💡 Add $OLD_KEY to JWT_VERIFY_KEY_FILES until tokens signed with it have expired"
+fi